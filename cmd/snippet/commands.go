@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+)
+
+// login checks the token read from stdin against the server, and saves
+// both to the config file. The token is read from stdin rather than a flag
+// so that it doesn't end up in the shell's history, and so that it can be
+// piped in from a password manager.
+func (c *cli) login(args []string) error {
+	cfg, err := loadConfig(c.configPath)
+	if err != nil {
+		return err
+	}
+
+	fs := c.flagSet("snippet login")
+	fs.StringVar(&cfg.Server, "server", cfg.Server, "address of the snippetbox server, such as https://snippetbox.example.com")
+	fs.StringVar(&cfg.CACert, "ca", cfg.CACert, "PEM `file` of a certificate to trust, for servers with a self-signed certificate")
+
+	err = fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	cfg.Server, err = checkServer(cfg.Server)
+	if err != nil {
+		return err
+	}
+
+	if cfg.CACert != "" {
+		cfg.CACert, err = filepath.Abs(cfg.CACert)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Only prompt for the token if someone is there to read the prompt.
+	if f, ok := c.stdin.(*os.File); ok {
+		if info, err := f.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+			fmt.Fprintf(c.stderr, "Create a token on your account page at %s/account/view, then paste it here.\nAPI token: ", cfg.Server)
+		}
+	}
+
+	line, err := bufio.NewReader(c.stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	cfg.Token = strings.TrimSpace(line)
+	if cfg.Token == "" {
+		return errors.New("no token was given")
+	}
+
+	client, err := newClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	err = client.checkToken()
+	if err != nil {
+		return err
+	}
+
+	err = cfg.save(c.configPath)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.stderr, "Logged in to %s; saved the token to %s\n", cfg.Server, c.configPath)
+
+	return nil
+}
+
+// create makes a snippet from the files given as arguments, or from stdin
+// if there aren't any, and prints its address.
+func (c *cli) create(args []string) error {
+	var input snippetInput
+	var name, language string
+
+	fs := c.flagSet("snippet create")
+	fs.Usage = func() {
+		fmt.Fprint(c.stderr, "Usage: snippet create [flags] [file ...]\n\nReads the content from standard input if no files are given.\n\n")
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&input.Title, "t", "", "`title` of the snippet")
+	fs.StringVar(&input.Expires, "e", "7d", "`lifetime` of the snippet, such as 10m, 6h, 30d or never")
+	fs.StringVar(&input.Visibility, "v", "unlisted", "`visibility` of the snippet: public, unlisted or private")
+	fs.StringVar(&input.Tags, "tags", "", "comma-separated `tags`")
+	fs.BoolVar(&input.BurnAfterReading, "burn", false, "delete the snippet once it has been read")
+	fs.StringVar(&name, "n", "", "file `name` for the content read from standard input")
+	fs.StringVar(&language, "l", "", "`language` of the content read from standard input, if it can't be detected")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		content, err := io.ReadAll(c.stdin)
+		if err != nil {
+			return err
+		}
+		input.Files = []fileInput{{Name: name, Language: language, Content: string(content)}}
+	} else {
+		if name != "" || language != "" {
+			fmt.Fprintln(c.stderr, "snippet: -n and -l only apply to standard input; name files by their paths instead")
+			return errUsage
+		}
+
+		for _, path := range fs.Args() {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			input.Files = append(input.Files, fileInput{Name: filepath.Base(path), Content: string(content)})
+		}
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	snippet, err := client.create(input)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(c.stdout, client.snippetURL(snippet.ID))
+
+	return nil
+}
+
+// get prints the content of a snippet, exactly as it was saved so that it
+// can be piped into another command. Snippets with several files have each
+// one printed under a header (in the same way as head(1)), unless -f picks
+// one of them.
+func (c *cli) get(args []string) error {
+	var name string
+
+	fs := c.flagSet("snippet get")
+	fs.Usage = func() {
+		fmt.Fprint(c.stderr, "Usage: snippet get [-f name] <id or address>\n\n")
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&name, "f", "", "only print the file with this `name`")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errUsage
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	snippet, err := client.get(snippetID(fs.Arg(0)))
+	if err != nil {
+		return err
+	}
+
+	// The API leaves out the files of protected and burn-after-reading
+	// snippets which belong to someone else, as it can't unlock or confirm
+	// reading them.
+	if len(snippet.Files) == 0 {
+		return fmt.Errorf("this snippet can only be read in a browser, at %s", client.snippetURL(snippet.ID))
+	}
+
+	files := snippet.Files
+	if name != "" {
+		files = nil
+		for _, file := range snippet.Files {
+			if file.Name == name {
+				files = append(files, file)
+			}
+		}
+		if files == nil {
+			return fmt.Errorf("the snippet has no file called %q", name)
+		}
+	}
+
+	if len(files) == 1 {
+		_, err := io.WriteString(c.stdout, files[0].Content)
+		return err
+	}
+
+	for i, file := range files {
+		if i > 0 {
+			fmt.Fprintln(c.stdout)
+		}
+
+		fmt.Fprintf(c.stdout, "==> %s <==\n%s", file.Name, file.Content)
+		if !strings.HasSuffix(file.Content, "\n") {
+			fmt.Fprintln(c.stdout)
+		}
+	}
+
+	return nil
+}
+
+// ls prints a table of the newest public snippets.
+func (c *cli) ls(args []string) error {
+	var count int
+
+	fs := c.flagSet("snippet ls")
+	fs.IntVar(&count, "n", 20, "`number` of snippets to list")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	snippets, err := client.list(count)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(c.stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTITLE\tAUTHOR\tCREATED\tEXPIRES")
+
+	for _, snippet := range snippets {
+		expires := "never"
+		if snippet.Expires != nil {
+			expires = snippet.Expires.Local().Format("2006-01-02 15:04")
+		}
+
+		author := snippet.Author
+		if author == "" {
+			author = "-"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", snippet.ID, snippet.Title, author, snippet.Created.Local().Format("2006-01-02 15:04"), expires)
+	}
+
+	return tw.Flush()
+}
+
+// rm deletes a snippet. Like rm(1), it prints nothing if it succeeds.
+func (c *cli) rm(args []string) error {
+	fs := c.flagSet("snippet rm")
+	fs.Usage = func() {
+		fmt.Fprint(c.stderr, "Usage: snippet rm <id or address>\n")
+	}
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errUsage
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	return client.delete(snippetID(fs.Arg(0)))
+}
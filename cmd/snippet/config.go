@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// A config holds what `snippet login` saves: the server to talk to, the API
+// token to send it and, for servers with a self-signed certificate (such as
+// a development server using tls/cert.pem), the certificate to trust.
+type config struct {
+	Server string `json:"server"`
+	Token  string `json:"token"`
+	CACert string `json:"caCert,omitempty"`
+}
+
+// defaultConfigPath returns where the config is kept when -config isn't
+// given, such as ~/.config/snippetbox/config.json on Linux.
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "snippetbox.json"
+	}
+
+	return filepath.Join(dir, "snippetbox", "config.json")
+}
+
+// loadConfig reads the config at path. A missing file isn't an error, as
+// the server and token can also come from the environment. The
+// SNIPPETBOX_SERVER and SNIPPETBOX_TOKEN environment variables take
+// precedence over the file, so that scripts and CI jobs don't need one.
+func loadConfig(path string) (config, error) {
+	var cfg config
+
+	js, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return config{}, err
+	default:
+		err = json.Unmarshal(js, &cfg)
+		if err != nil {
+			return config{}, fmt.Errorf("reading %s: %w", path, err)
+		}
+	}
+
+	if server := os.Getenv("SNIPPETBOX_SERVER"); server != "" {
+		cfg.Server = server
+	}
+	if token := os.Getenv("SNIPPETBOX_TOKEN"); token != "" {
+		cfg.Token = token
+	}
+
+	return cfg, nil
+}
+
+// save writes the config to path. Only the current user can read it, as it
+// holds the token.
+func (cfg config) save(path string) error {
+	js, err := json.MarshalIndent(cfg, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0o700)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(js, '\n'), 0o600)
+}
+
+// checkServer makes sure the server is an https:// URL, so that the token
+// is never sent in the clear, and returns it without a trailing slash.
+func checkServer(server string) (string, error) {
+	if server == "" {
+		return "", errors.New("no server is set; run 'snippet login -server https://...' first")
+	}
+
+	u, err := url.Parse(server)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return "", fmt.Errorf("the server %q must be an https:// URL", server)
+	}
+
+	return u.Scheme + "://" + u.Host + strings.TrimSuffix(u.Path, "/"), nil
+}
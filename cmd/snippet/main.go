@@ -0,0 +1,170 @@
+// The snippet command creates, fetches, lists and deletes snippets on a
+// snippetbox server from the command line, using the JSON API. It's mostly
+// meant for piping the output of other commands into a new snippet:
+//
+//	make test 2>&1 | snippet create -t "Failing tests" -e 1d
+//
+// Run `snippet login` first to save the server and an API token.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+)
+
+const usage = `Usage: snippet [-config file] <command> [arguments]
+
+Commands:
+  login    save the server and API token to use
+  create   create a snippet from standard input or files
+  get      print the content of a snippet
+  ls       list the newest public snippets
+  rm       delete a snippet
+
+Run 'snippet <command> -h' for the arguments of a command.
+`
+
+// errUsage is returned by a command when it's run with the wrong
+// arguments, after the problem has been printed.
+var errUsage = errors.New("usage")
+
+// A cli holds the streams and config file used by the commands, so that
+// tests can run them without touching the real ones.
+type cli struct {
+	stdin      io.Reader
+	stdout     io.Writer
+	stderr     io.Writer
+	configPath string
+}
+
+func main() {
+	c := &cli{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}
+	os.Exit(c.run(os.Args[1:]))
+}
+
+// run runs the command in args, returning the exit status: 0 if it
+// succeeded, 2 if it was used wrongly and 1 for any other error.
+func (c *cli) run(args []string) int {
+	fs := c.flagSet("snippet")
+	fs.Usage = func() { fmt.Fprint(c.stderr, usage) }
+	fs.StringVar(&c.configPath, "config", defaultConfigPath(), "")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return 2
+	}
+
+	commands := map[string]func([]string) error{
+		"login":  c.login,
+		"create": c.create,
+		"get":    c.get,
+		"ls":     c.ls,
+		"rm":     c.rm,
+	}
+
+	command, ok := commands[fs.Arg(0)]
+	if !ok {
+		if fs.Arg(0) != "" {
+			fmt.Fprintf(c.stderr, "snippet: unknown command %q\n\n", fs.Arg(0))
+		}
+		fs.Usage()
+		return 2
+	}
+
+	err = command(fs.Args()[1:])
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, errUsage), errors.Is(err, flag.ErrHelp):
+		return 2
+	default:
+		c.printError(err)
+		return 1
+	}
+}
+
+// flagSet returns a flag set for a command which returns its errors rather
+// than exiting, and prints them to the cli's stderr.
+func (c *cli) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	return fs
+}
+
+// client returns a client for the server and token in the config file.
+func (c *cli) client() (*client, error) {
+	cfg, err := loadConfig(c.configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return newClient(cfg)
+}
+
+// fieldFlags are the flags of `snippet create` which set each field, so
+// that validation errors can say which flag to change.
+var fieldFlags = map[string]string{
+	"title":      "-t",
+	"expires":    "-e",
+	"visibility": "-v",
+	"tags":       "-tags",
+}
+
+// printError prints an error to stderr. Validation errors from the server
+// are printed one per line, in a predictable order, such as:
+//
+//	snippet: the request contains invalid fields
+//	  title (-t): This field cannot be blank
+//	  files[0].content: This field cannot be blank
+func (c *cli) printError(err error) {
+	fmt.Fprintf(c.stderr, "snippet: %s\n", err)
+
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) {
+		return
+	}
+
+	for _, message := range apiErr.NonFieldErrors {
+		fmt.Fprintf(c.stderr, "  %s\n", message)
+	}
+
+	fields := make([]string, 0, len(apiErr.FieldErrors))
+	for field := range apiErr.FieldErrors {
+		fields = append(fields, field)
+	}
+	slices.Sort(fields)
+
+	for _, field := range fields {
+		name := field
+		if f, ok := fieldFlags[field]; ok {
+			name = fmt.Sprintf("%s (%s)", field, f)
+		}
+		fmt.Fprintf(c.stderr, "  %s: %s\n", name, apiErr.FieldErrors[field])
+	}
+
+	if apiErr.Status == http.StatusUnauthorized {
+		fmt.Fprintln(c.stderr, "  run 'snippet login' to save a valid API token")
+	}
+}
+
+// snippetID accepts either a snippet's ID or the address of its page, such
+// as https://snippetbox.example.com/snippet/view/abc123, so that links can
+// be pasted straight in.
+func snippetID(arg string) string {
+	// Drop any query string or fragment, such as ?file=main.go.
+	arg, _, _ = strings.Cut(arg, "?")
+	arg, _, _ = strings.Cut(arg, "#")
+
+	arg = strings.TrimRight(arg, "/")
+	if i := strings.LastIndex(arg, "/"); i >= 0 {
+		arg = arg[i+1:]
+	}
+
+	return arg
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mixnblend/snippetbox/internal/assert"
+)
+
+const validToken = "snip_valid"
+
+// fakeAPI stands in for the snippetbox JSON API. It accepts validToken,
+// knows a snippet with one file ("abc123"), one with two ("multi") and a
+// protected one ("locked"), and records the last snippet created.
+type fakeAPI struct {
+	created snippetInput
+	deleted string
+}
+
+func (api *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	writeJSON := func(status int, data any) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(data)
+	}
+
+	writeError := func(status int, message string) {
+		writeJSON(status, map[string]any{"error": apiError{Status: status, Message: message}})
+	}
+
+	if r.Header.Get("Authorization") != "Bearer "+validToken {
+		writeError(http.StatusUnauthorized, "invalid or missing API token")
+		return
+	}
+
+	snippets := map[string]snippet{
+		"abc123": {ID: "abc123", Title: "An old silent pond", Author: "Alice", Files: []file{{Name: "pond.txt", Content: "An old silent pond...\n"}}},
+		"multi":  {ID: "multi", Title: "Two files", Files: []file{{Name: "a.go", Content: "package a\n"}, {Name: "b.go", Content: "package b"}}},
+		"locked": {ID: "locked", Title: "Protected", Protected: true},
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v1/snippets":
+		writeJSON(http.StatusOK, map[string]any{"snippets": []snippet{snippets["abc123"], snippets["multi"]}})
+	case r.Method == http.MethodPost && r.URL.Path == "/api/v1/snippets":
+		api.created = snippetInput{}
+		json.NewDecoder(r.Body).Decode(&api.created)
+
+		if api.created.Title == "" {
+			writeJSON(http.StatusUnprocessableEntity, map[string]any{"error": apiError{
+				Status:      http.StatusUnprocessableEntity,
+				Message:     "the request contains invalid fields",
+				FieldErrors: map[string]string{"title": "This field cannot be blank", "files[0].content": "This field cannot be blank"},
+			}})
+			return
+		}
+
+		writeJSON(http.StatusCreated, map[string]any{"snippet": snippet{ID: "new123"}})
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/api/v1/snippets/"):
+		s, ok := snippets[strings.TrimPrefix(r.URL.Path, "/api/v1/snippets/")]
+		if !ok {
+			writeError(http.StatusNotFound, "the requested resource could not be found")
+			return
+		}
+		writeJSON(http.StatusOK, map[string]any{"snippet": s})
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/api/v1/snippets/"):
+		api.deleted = strings.TrimPrefix(r.URL.Path, "/api/v1/snippets/")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(http.StatusNotFound, "the requested resource could not be found")
+	}
+}
+
+// A testCLI runs commands against a fakeAPI, with a config file in a
+// temporary directory.
+type testCLI struct {
+	api        *fakeAPI
+	server     *httptest.Server
+	configPath string
+	caCert     string
+}
+
+// newTestCLI starts a fakeAPI and, if token isn't empty, saves a config
+// file which points at it with that token. The server's self-signed
+// certificate is trusted in the same way as with `snippet login -ca`.
+func newTestCLI(t *testing.T, token string) *testCLI {
+	t.Helper()
+
+	// Make sure the environment can't change which server is used.
+	t.Setenv("SNIPPETBOX_SERVER", "")
+	t.Setenv("SNIPPETBOX_TOKEN", "")
+
+	api := &fakeAPI{}
+	ts := httptest.NewTLSServer(api)
+	t.Cleanup(ts.Close)
+
+	dir := t.TempDir()
+	tc := &testCLI{
+		api:        api,
+		server:     ts,
+		configPath: filepath.Join(dir, "snippetbox", "config.json"),
+		caCert:     filepath.Join(dir, "cert.pem"),
+	}
+
+	err := os.WriteFile(tc.caCert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw}), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if token != "" {
+		err = config{Server: ts.URL, Token: token, CACert: tc.caCert}.save(tc.configPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return tc
+}
+
+// run runs a command with the given stdin, and returns its exit status and
+// what it wrote to stdout and stderr.
+func (tc *testCLI) run(stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+
+	c := &cli{stdin: strings.NewReader(stdin), stdout: &stdout, stderr: &stderr}
+	status := c.run(append([]string{"-config", tc.configPath}, args...))
+
+	return status, stdout.String(), stderr.String()
+}
+
+func TestLogin(t *testing.T) {
+	// Given ... a server, and no config file yet
+	tc := newTestCLI(t, "")
+
+	t.Run("Invalid token", func(t *testing.T) {
+		// When ... we log in with a token the server doesn't know
+		status, _, stderr := tc.run("snip_wrong\n", "login", "-server", tc.server.URL, "-ca", tc.caCert)
+
+		// Then ... it should fail, and say how to fix it
+		assert.Equal(t, status, 1)
+		assert.StringContains(t, stderr, "snippet: invalid or missing API token")
+		assert.StringContains(t, stderr, "run 'snippet login'")
+
+		// And ... nothing should be saved
+		_, err := os.Stat(tc.configPath)
+		assert.Equal(t, os.IsNotExist(err), true)
+	})
+
+	t.Run("Plain HTTP", func(t *testing.T) {
+		// When ... we log in to a server without TLS
+		status, _, stderr := tc.run(validToken+"\n", "login", "-server", "http://snippetbox.example.com")
+
+		// Then ... it should be refused, so that the token isn't sent in the clear
+		assert.Equal(t, status, 1)
+		assert.StringContains(t, stderr, "must be an https:// URL")
+	})
+
+	t.Run("Valid token", func(t *testing.T) {
+		// When ... we log in with a valid token
+		status, _, _ := tc.run(validToken+"\n", "login", "-server", tc.server.URL+"/", "-ca", tc.caCert)
+
+		// Then ... it should succeed
+		assert.Equal(t, status, 0)
+
+		// And ... the server and token should be saved where only we can read them
+		info, err := os.Stat(tc.configPath)
+		assert.NilError(t, err)
+		assert.Equal(t, info.Mode().Perm(), os.FileMode(0o600))
+
+		cfg, err := loadConfig(tc.configPath)
+		assert.NilError(t, err)
+		assert.Equal(t, cfg.Server, tc.server.URL)
+		assert.Equal(t, cfg.Token, validToken)
+		assert.Equal(t, cfg.CACert, tc.caCert)
+	})
+}
+
+func TestCreate(t *testing.T) {
+	// Given ... we're logged in
+	tc := newTestCLI(t, validToken)
+
+	t.Run("From standard input", func(t *testing.T) {
+		// When ... we pipe some output into a new snippet
+		status, stdout, _ := tc.run("FAIL: TestCreate\n", "create", "-t", "Build log", "-tags", "ci")
+
+		// Then ... the address of the new snippet should be printed
+		assert.Equal(t, status, 0)
+		assert.Equal(t, stdout, tc.server.URL+"/snippet/view/new123\n")
+
+		// And ... the content should be sent with the defaults for piped output
+		assert.Equal(t, tc.api.created.Title, "Build log")
+		assert.Equal(t, tc.api.created.Expires, "7d")
+		assert.Equal(t, tc.api.created.Visibility, "unlisted")
+		assert.Equal(t, tc.api.created.Tags, "ci")
+		assert.Equal(t, len(tc.api.created.Files), 1)
+		assert.Equal(t, tc.api.created.Files[0].Content, "FAIL: TestCreate\n")
+	})
+
+	t.Run("From files", func(t *testing.T) {
+		// Given ... two files on disk
+		dir := t.TempDir()
+		os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0o600)
+		os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example\n"), 0o600)
+
+		// When ... we create a snippet from them
+		status, _, _ := tc.run("", "create", "-t", "Example", "-e", "1d", filepath.Join(dir, "main.go"), filepath.Join(dir, "go.mod"))
+
+		// Then ... each file should be sent, named after its path
+		assert.Equal(t, status, 0)
+		assert.Equal(t, tc.api.created.Expires, "1d")
+		assert.Equal(t, len(tc.api.created.Files), 2)
+		assert.Equal(t, tc.api.created.Files[0].Name, "main.go")
+		assert.Equal(t, tc.api.created.Files[1].Content, "module example\n")
+	})
+
+	t.Run("Validation errors", func(t *testing.T) {
+		// When ... we send nothing, without a title
+		status, stdout, stderr := tc.run("", "create")
+
+		// Then ... each of the server's errors should be printed in order,
+		// with the flag which sets the field
+		assert.Equal(t, status, 1)
+		assert.Equal(t, stdout, "")
+		assert.Equal(t, stderr, "snippet: the request contains invalid fields\n"+
+			"  files[0].content: This field cannot be blank\n"+
+			"  title (-t): This field cannot be blank\n")
+	})
+
+	t.Run("Not logged in", func(t *testing.T) {
+		// Given ... a config file without a token
+		notLoggedIn := newTestCLI(t, "")
+		config{Server: notLoggedIn.server.URL, CACert: notLoggedIn.caCert}.save(notLoggedIn.configPath)
+
+		// When ... we try to create a snippet
+		status, _, stderr := notLoggedIn.run("hello", "create", "-t", "Hello")
+
+		// Then ... it should say how to log in
+		assert.Equal(t, status, 1)
+		assert.StringContains(t, stderr, "run 'snippet login' to save a valid API token")
+	})
+}
+
+func TestGet(t *testing.T) {
+	// Given ... we're logged in
+	tc := newTestCLI(t, validToken)
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantStatus int
+		wantStdout string
+		wantStderr string
+	}{
+		{
+			name:       "Single file",
+			args:       []string{"abc123"},
+			wantStatus: 0,
+			wantStdout: "An old silent pond...\n",
+		},
+		{
+			name:       "Address of the snippet",
+			args:       []string{tc.server.URL + "/snippet/view/abc123?file=pond.txt"},
+			wantStatus: 0,
+			wantStdout: "An old silent pond...\n",
+		},
+		{
+			name:       "Several files",
+			args:       []string{"multi"},
+			wantStatus: 0,
+			wantStdout: "==> a.go <==\npackage a\n\n==> b.go <==\npackage b\n",
+		},
+		{
+			name:       "One of several files",
+			args:       []string{"-f", "b.go", "multi"},
+			wantStatus: 0,
+			wantStdout: "package b",
+		},
+		{
+			name:       "Missing file",
+			args:       []string{"-f", "c.go", "multi"},
+			wantStatus: 1,
+			wantStderr: "snippet: the snippet has no file called \"c.go\"\n",
+		},
+		{
+			name:       "Protected snippet",
+			args:       []string{"locked"},
+			wantStatus: 1,
+			wantStderr: "snippet: this snippet can only be read in a browser, at " + tc.server.URL + "/snippet/view/locked\n",
+		},
+		{
+			name:       "Non-existent snippet",
+			args:       []string{"nope"},
+			wantStatus: 1,
+			wantStderr: "snippet: the requested resource could not be found\n",
+		},
+		{
+			name:       "No ID",
+			args:       []string{},
+			wantStatus: 2,
+			wantStderr: "Usage: snippet get",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When ... we get the snippet
+			status, stdout, stderr := tc.run("", append([]string{"get"}, tt.args...)...)
+
+			// Then ... its content should be printed exactly as it was saved
+			assert.Equal(t, status, tt.wantStatus)
+			assert.Equal(t, stdout, tt.wantStdout)
+			assert.StringContains(t, stderr, tt.wantStderr)
+		})
+	}
+}
+
+func TestLs(t *testing.T) {
+	// Given ... we're logged in
+	tc := newTestCLI(t, validToken)
+
+	// When ... we list the snippets
+	status, stdout, _ := tc.run("", "ls")
+
+	// Then ... they should be printed as a table
+	assert.Equal(t, status, 0)
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	assert.Equal(t, len(lines), 3)
+	assert.StringContains(t, lines[0], "ID      TITLE")
+	assert.StringContains(t, lines[1], "abc123  An old silent pond  Alice")
+
+	// And ... snippets without an author or expiry should say so
+	assert.StringContains(t, lines[2], "multi   Two files           -")
+	assert.StringContains(t, lines[2], "never")
+}
+
+func TestRm(t *testing.T) {
+	// Given ... we're logged in
+	tc := newTestCLI(t, validToken)
+
+	// When ... we delete a snippet by its address
+	status, stdout, _ := tc.run("", "rm", tc.server.URL+"/snippet/view/abc123")
+
+	// Then ... it should be deleted, without printing anything
+	assert.Equal(t, status, 0)
+	assert.Equal(t, stdout, "")
+	assert.Equal(t, tc.api.deleted, "abc123")
+}
+
+func TestUnknownCommand(t *testing.T) {
+	// Given ... we're logged in
+	tc := newTestCLI(t, validToken)
+
+	// When ... we run a command which doesn't exist
+	status, _, stderr := tc.run("", "paste")
+
+	// Then ... the usage should be printed
+	assert.Equal(t, status, 2)
+	assert.StringContains(t, stderr, `snippet: unknown command "paste"`)
+	assert.StringContains(t, stderr, "Commands:")
+}
@@ -0,0 +1,535 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mixnblend/snippetbox/internal/models"
+	"github.com/mixnblend/snippetbox/internal/validator"
+)
+
+// The JSON API lives under /api/v1/, so that the format can change in a
+// later version without breaking existing scripts. It shares the models and
+// form validation with the HTML handlers, but has its own middleware: there
+// are no sessions or CSRF tokens, and every response (including errors) is
+// JSON.
+
+// maxAPIBodyBytes is the largest request body the API will read.
+const maxAPIBodyBytes = 1 << 20
+
+// An envelope wraps every JSON response body in an object with a key saying
+// what it holds, such as {"snippet": ...} or {"error": ...}.
+type envelope map[string]any
+
+// An apiError is the body of every error response, under the "error" key.
+// FieldErrors and NonFieldErrors are only set for validation errors, and
+// hold the same messages as the HTML forms, keyed by the same field names.
+type apiError struct {
+	Status         int               `json:"status"`
+	Message        string            `json:"message"`
+	FieldErrors    map[string]string `json:"fieldErrors,omitempty"`
+	NonFieldErrors []string          `json:"nonFieldErrors,omitempty"`
+}
+
+// An apiSnippet is how a snippet is represented in API responses. Expires
+// is null for snippets which never expire. Files are left out of listings,
+// and of snippets whose content can't be read through the API (see
+// apiCanReadContent()).
+type apiSnippet struct {
+	ID               string     `json:"id"`
+	Title            string     `json:"title"`
+	Author           string     `json:"author"`
+	Visibility       string     `json:"visibility"`
+	BurnAfterReading bool       `json:"burnAfterReading"`
+	Protected        bool       `json:"protected"`
+	Tags             []string   `json:"tags"`
+	Created          time.Time  `json:"created"`
+	Expires          *time.Time `json:"expires"`
+	ForkedFrom       string     `json:"forkedFrom,omitempty"`
+	Stars            int        `json:"stars"`
+	Files            []apiFile  `json:"files,omitempty"`
+}
+
+// apiLinks holds the URLs of the pages either side of a page of a listing.
+// Each is left out at the ends of the listing.
+type apiLinks struct {
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+}
+
+// An apiFile is one of the files of an apiSnippet.
+type apiFile struct {
+	Name     string `json:"name"`
+	Language string `json:"language"`
+	Content  string `json:"content"`
+}
+
+// newAPISnippet converts a snippet for an API response, including its files
+// if withFiles is true.
+func newAPISnippet(snippet models.Snippet, withFiles bool) apiSnippet {
+	s := apiSnippet{
+		ID:               snippet.ShortID,
+		Title:            snippet.Title,
+		Author:           snippet.UserName,
+		Visibility:       snippet.Visibility,
+		BurnAfterReading: snippet.BurnAfterReading,
+		Protected:        snippet.Protected,
+		Tags:             snippet.Tags,
+		Created:          snippet.Created.UTC(),
+		Stars:            snippet.Stars,
+	}
+
+	// Always send an array, rather than null, for snippets without tags.
+	if s.Tags == nil {
+		s.Tags = []string{}
+	}
+
+	if !snippet.Expires.IsZero() {
+		expires := snippet.Expires.UTC()
+		s.Expires = &expires
+	}
+
+	if snippet.Parent != nil && snippet.Parent.Visibility != models.VisibilityPrivate {
+		s.ForkedFrom = snippet.Parent.ShortID
+	}
+
+	if withFiles {
+		s.Files = make([]apiFile, len(snippet.Files))
+		for i, file := range snippet.Files {
+			s.Files[i] = apiFile{Name: file.Name, Language: file.Language, Content: file.Content}
+		}
+	}
+
+	return s
+}
+
+// The writeJSON helper sends data as the JSON body of a response with the
+// given status code.
+func (app *application) writeJSON(w http.ResponseWriter, r *http.Request, status int, data envelope) {
+	js, err := json.MarshalIndent(data, "", "\t")
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(js, '\n'))
+}
+
+// The apiErrorResponse helper sends an error response in the standard
+// envelope, in place of http.Error()'s plain text.
+func (app *application) apiErrorResponse(w http.ResponseWriter, r *http.Request, status int, message string) {
+	app.writeJSON(w, r, status, envelope{"error": apiError{Status: status, Message: message}})
+}
+
+// The apiServerError helper is the API's version of serverError(). The
+// details of the error are logged, but never sent to the client.
+func (app *application) apiServerError(w http.ResponseWriter, r *http.Request, err error) {
+	app.logger.Error(err.Error(), "method", r.Method, "uri", r.URL.RequestURI())
+
+	// Marshalling the error envelope can't fail, so this can't loop.
+	app.apiErrorResponse(w, r, http.StatusInternalServerError, "the server encountered a problem and could not process your request")
+}
+
+// The apiNotFound helper sends a 404 Not Found response.
+func (app *application) apiNotFound(w http.ResponseWriter, r *http.Request) {
+	app.apiErrorResponse(w, r, http.StatusNotFound, "the requested resource could not be found")
+}
+
+// The apiMethodNotAllowed helper returns a handler which sends a 405 Method
+// Not Allowed response, with an Allow header listing the given methods. It's
+// registered for each API path without a method, so that it takes the place
+// of the ServeMux's plain text response. As with the ServeMux, HEAD is
+// allowed wherever GET is.
+func (app *application) apiMethodNotAllowed(methods ...string) http.HandlerFunc {
+	if slices.Contains(methods, http.MethodGet) {
+		methods = append(methods, http.MethodHead)
+	}
+	allow := strings.Join(methods, ", ")
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", allow)
+		app.apiErrorResponse(w, r, http.StatusMethodNotAllowed, fmt.Sprintf("the %s method is not supported for this resource", r.Method))
+	}
+}
+
+// isAPIRequest reports whether the request is for the JSON API, whose errors
+// are sent as JSON rather than plain text or HTML.
+func isAPIRequest(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, "/api/")
+}
+
+// The apiValidationError helper sends a 422 Unprocessable Entity response
+// with the errors collected by a validator.
+func (app *application) apiValidationError(w http.ResponseWriter, r *http.Request, v validator.Validator) {
+	status := http.StatusUnprocessableEntity
+
+	app.writeJSON(w, r, status, envelope{"error": apiError{
+		Status:         status,
+		Message:        "the request contains invalid fields",
+		FieldErrors:    v.FieldErrors,
+		NonFieldErrors: v.NonFieldErrors,
+	}})
+}
+
+// errUnsupportedMediaType is returned by readJSON() if the request body
+// isn't declared to be JSON.
+var errUnsupportedMediaType = errors.New("the request body must be JSON, with a Content-Type of application/json")
+
+// The readJSON helper decodes the JSON request body into dst. The body must
+// hold a single JSON value, with no fields which dst doesn't have. Requiring
+// the application/json content type also means browsers won't send requests
+// from other sites without asking first (using CORS), which is what
+// protects the API from cross-site request forgery in place of noSurf.
+func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/json" {
+		return errUnsupportedMediaType
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxAPIBodyBytes)
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(dst)
+	if err != nil {
+		var (
+			syntaxError           *json.SyntaxError
+			typeError             *json.UnmarshalTypeError
+			maxBytesError         *http.MaxBytesError
+			invalidUnmarshalError *json.InvalidUnmarshalError
+		)
+
+		switch {
+		case errors.As(err, &syntaxError):
+			return fmt.Errorf("the body contains badly-formed JSON (at character %d)", syntaxError.Offset)
+		case errors.Is(err, io.ErrUnexpectedEOF):
+			return errors.New("the body contains badly-formed JSON")
+		case errors.As(err, &typeError):
+			if typeError.Field != "" {
+				return fmt.Errorf("the body contains the wrong type for the field %q", typeError.Field)
+			}
+			return fmt.Errorf("the body contains the wrong type (at character %d)", typeError.Offset)
+		case errors.Is(err, io.EOF):
+			return errors.New("the body must not be empty")
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			field := strings.TrimPrefix(err.Error(), "json: unknown field ")
+			return fmt.Errorf("the body contains the unknown field %s", field)
+		case errors.As(err, &maxBytesError):
+			return fmt.Errorf("the body must not be larger than %d bytes", maxBytesError.Limit)
+		case errors.As(err, &invalidUnmarshalError):
+			// As with decodePostForm(), this means a bug in our code.
+			panic(err)
+		default:
+			return err
+		}
+	}
+
+	if dec.More() {
+		return errors.New("the body must only contain a single JSON value")
+	}
+
+	return nil
+}
+
+// The apiBadRequest helper sends a 400 Bad Request response for an error
+// from readJSON(), or a 415 Unsupported Media Type response if the body
+// wasn't JSON at all.
+func (app *application) apiBadRequest(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, errUnsupportedMediaType) {
+		app.apiErrorResponse(w, r, http.StatusUnsupportedMediaType, err.Error())
+		return
+	}
+
+	app.apiErrorResponse(w, r, http.StatusBadRequest, err.Error())
+}
+
+// The authenticateAPI middleware is the API's version of authenticate.
+// Rather than a session cookie, each request carries a personal access token
+// in an "Authorization: Bearer" header. The token's user is recorded in the
+// request context in the same way as for sessions, along with the token
+// itself so that its scopes can be checked. Requests without a token carry
+// on anonymously, but bad tokens are refused outright so that mistakes in
+// scripts are noticed.
+func (app *application) authenticateAPI(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Authorization")
+
+		authorization := r.Header.Get("Authorization")
+		if authorization == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		scheme, plaintext, ok := strings.Cut(authorization, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") || plaintext == "" {
+			app.apiInvalidCredentials(w, r)
+			return
+		}
+
+		token, err := app.tokens.Authenticate(plaintext)
+		if err != nil {
+			if errors.Is(err, models.ErrInvalidCredentials) {
+				app.apiInvalidCredentials(w, r)
+			} else {
+				app.apiServerError(w, r, err)
+			}
+			return
+		}
+
+		user, err := app.users.Get(token.UserID)
+		if err != nil {
+			app.apiServerError(w, r, err)
+			return
+		}
+
+		r = withAuthenticatedUser(r, user)
+		r = r.WithContext(context.WithValue(r.Context(), apiTokenContextKey, token))
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// The apiInvalidCredentials helper sends a 401 Unauthorized response, with
+// a WWW-Authenticate header saying how to authenticate.
+func (app *application) apiInvalidCredentials(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="snippetbox"`)
+	app.apiErrorResponse(w, r, http.StatusUnauthorized, "invalid or missing API token")
+}
+
+// The requireAPIAuthentication middleware is the API's version of
+// requireAuthentication, sending a 401 Unauthorized response rather than
+// redirecting to the login page.
+func (app *application) requireAPIAuthentication(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !app.isAuthenticated(r) {
+			app.apiInvalidCredentials(w, r)
+			return
+		}
+
+		w.Header().Add("Cache-Control", "no-store")
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// The requireAPIScope middleware sends a 403 Forbidden response if the
+// request was made with a token which doesn't have the given scope.
+// Anonymous requests are let through, so it should come after
+// requireAPIAuthentication on routes which need a user.
+func (app *application) requireAPIScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := r.Context().Value(apiTokenContextKey).(models.Token)
+			if ok && !token.HasScope(scope) {
+				app.apiErrorResponse(w, r, http.StatusForbidden, fmt.Sprintf("this token does not have the %s scope", scope))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// The apiSnippetFromPath helper is the API's version of snippetFromPath,
+// sending JSON error responses. Old numeric IDs aren't supported.
+func (app *application) apiSnippetFromPath(w http.ResponseWriter, r *http.Request) (models.Snippet, bool) {
+	shortID := r.PathValue("id")
+	if !validator.Matches(shortID, validator.ShortIDRX) {
+		app.apiNotFound(w, r)
+		return models.Snippet{}, false
+	}
+
+	snippet, err := app.snippets.Get(shortID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.apiNotFound(w, r)
+		} else {
+			app.apiServerError(w, r, err)
+		}
+		return models.Snippet{}, false
+	}
+
+	if !snippet.VisibleTo(app.authenticatedUserID(r)) {
+		app.apiNotFound(w, r)
+		return models.Snippet{}, false
+	}
+
+	return snippet, true
+}
+
+// The apiOwnedSnippet helper is the API's version of ownedSnippet.
+func (app *application) apiOwnedSnippet(w http.ResponseWriter, r *http.Request) (models.Snippet, bool) {
+	snippet, ok := app.apiSnippetFromPath(w, r)
+	if !ok {
+		return models.Snippet{}, false
+	}
+
+	if !snippet.OwnedBy(app.authenticatedUserID(r)) {
+		app.apiErrorResponse(w, r, http.StatusForbidden, "only the owner of a snippet can change it")
+		return models.Snippet{}, false
+	}
+
+	return snippet, true
+}
+
+// The apiCanReadContent helper is the API's version of canReadContent. The
+// API has no way to unlock a protected snippet or confirm reading a
+// burn-after-reading one, so the content of those is only sent to the
+// owner.
+func (app *application) apiCanReadContent(r *http.Request, snippet models.Snippet) bool {
+	if snippet.OwnedBy(app.authenticatedUserID(r)) {
+		return true
+	}
+
+	return !snippet.Protected && !snippet.BurnAfterReading
+}
+
+func (app *application) apiSnippetList(w http.ResponseWriter, r *http.Request) {
+	page, err := parseSnippetPage(r.URL.Query())
+	if err != nil {
+		app.apiErrorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// As on the HTML listing, only public snippets are listed.
+	list, err := app.snippets.List(models.SnippetFilter{Visibility: models.VisibilityPublic}, page)
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+
+	snippets := make([]apiSnippet, len(list.Snippets))
+	for i, snippet := range list.Snippets {
+		snippets[i] = newAPISnippet(snippet, false)
+	}
+
+	// The links to the pages either side are the same as for the HTML
+	// listing, but pointing back at the API.
+	p := newPagination("/api/v1/snippets", page, list)
+	links := apiLinks{Next: p.NextURL, Prev: p.PrevURL}
+
+	app.writeJSON(w, r, http.StatusOK, envelope{"snippets": snippets, "links": links})
+}
+
+func (app *application) apiSnippetGet(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.apiSnippetFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	app.writeJSON(w, r, http.StatusOK, envelope{"snippet": newAPISnippet(snippet, app.apiCanReadContent(r, snippet))})
+}
+
+func (app *application) apiSnippetCreate(w http.ResponseWriter, r *http.Request) {
+	var form snippetCreateForm
+
+	err := app.readJSON(w, r, &form)
+	if err != nil {
+		app.apiBadRequest(w, r, err)
+		return
+	}
+
+	form.validate(app.expiryPolicy(r))
+
+	if !form.Valid() {
+		app.apiValidationError(w, r, form.Validator)
+		return
+	}
+
+	shortID, err := app.snippets.Insert(form.input(), app.authenticatedUserID(r))
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+
+	// Read the new snippet back, so that the response holds exactly what
+	// was saved (such as the generated file names).
+	snippet, err := app.snippets.Get(shortID)
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+
+	app.queueWebhookEvent(app.newSnippetEvent(models.EventSnippetCreated, snippet))
+
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/snippets/%s", shortID))
+
+	app.writeJSON(w, r, http.StatusCreated, envelope{"snippet": newAPISnippet(snippet, true)})
+}
+
+// apiSnippetUpdate replaces a snippet with the one in the request body, in
+// the same way as the edit form. The expiry has to be given again, and an
+// empty passphrase keeps the current one unless removePassphrase is set.
+func (app *application) apiSnippetUpdate(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.apiOwnedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	var form snippetCreateForm
+
+	err := app.readJSON(w, r, &form)
+	if err != nil {
+		app.apiBadRequest(w, r, err)
+		return
+	}
+
+	form.validate(app.expiryPolicy(r))
+
+	if !form.Valid() {
+		app.apiValidationError(w, r, form.Validator)
+		return
+	}
+
+	err = app.snippets.Update(snippet.ID, form.input())
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.apiNotFound(w, r)
+		} else {
+			app.apiServerError(w, r, err)
+		}
+		return
+	}
+
+	snippet, err = app.snippets.Get(snippet.ShortID)
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+
+	app.queueWebhookEvent(app.newSnippetEvent(models.EventSnippetUpdated, snippet))
+
+	app.writeJSON(w, r, http.StatusOK, envelope{"snippet": newAPISnippet(snippet, true)})
+}
+
+func (app *application) apiSnippetDelete(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.apiOwnedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.snippets.Delete(snippet.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.apiNotFound(w, r)
+		} else {
+			app.apiServerError(w, r, err)
+		}
+		return
+	}
+
+	app.queueWebhookEvent(app.newSnippetEvent(models.EventSnippetDeleted, snippet))
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"github.com/mixnblend/snippetbox/internal/models"
+	"github.com/mixnblend/snippetbox/internal/validator"
+)
+
+// The collectionForm is used to create a collection and to rename it or
+// change its visibility later.
+type collectionForm struct {
+	Title               string `form:"title"`
+	Visibility          string `form:"visibility"`
+	validator.Validator `form:"-"`
+}
+
+func (form *collectionForm) validate() {
+	form.CheckField(validator.NotBlank(form.Title), "title", "This field cannot be blank")
+	form.CheckField(validator.MaxChars(form.Title, 100), "title", "This field cannot be more than 100 characters long")
+	form.CheckField(validator.PermittedValue(form.Visibility, models.VisibilityPublic, models.VisibilityUnlisted, models.VisibilityPrivate), "visibility", "This field must equal public, unlisted or private")
+}
+
+// input converts the validated form into the fields expected by the
+// collection model.
+func (form *collectionForm) input() models.CollectionInput {
+	return models.CollectionInput{
+		Title:      form.Title,
+		Visibility: form.Visibility,
+	}
+}
+
+// The collectionSnippetForm picks a snippet in a collection by its short ID,
+// for the owner to remove it or move it up or down.
+type collectionSnippetForm struct {
+	Snippet string `form:"snippet"`
+}
+
+// moveOffsets maps the posted direction in which a snippet is moved in a
+// collection to the offset passed to CollectionModel.MoveSnippet().
+var moveOffsets = map[string]int{
+	"up":   -1,
+	"down": 1,
+}
+
+// The snippetCollectForm picks one of the user's collections by its short
+// ID, to add a snippet to it.
+type snippetCollectForm struct {
+	Collection string `form:"collection"`
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mixnblend/snippetbox/internal/models"
+	"github.com/mixnblend/snippetbox/internal/validator"
+)
+
+// maxCommentLength is the longest comment which can be posted, in
+// characters.
+const maxCommentLength = 2000
+
+// maxCommentDepth is the deepest that replies are indented. Replies to
+// comments at this depth are shown at the same depth as their parent.
+const maxCommentDepth = 4
+
+// The commentForm is used both to post a new comment (or a reply, when
+// ParentID is set) and to edit an existing one.
+type commentForm struct {
+	Content             string `form:"content"`
+	ParentID            int    `form:"parentID"`
+	validator.Validator `form:"-"`
+}
+
+func (form *commentForm) validate() {
+	form.CheckField(validator.NotBlank(form.Content), "content", "This field cannot be blank")
+	form.CheckField(validator.MaxChars(form.Content, maxCommentLength), "content", fmt.Sprintf("This field cannot be more than %d characters long", maxCommentLength))
+}
+
+// A commentPolicy decides what the current user may do with the comments on
+// a snippet. Authors can edit and delete their own comments for editWindow
+// after posting them; the owner of the snippet can delete any comment on it
+// at any time.
+type commentPolicy struct {
+	userID       int
+	snippetOwner int
+	editWindow   time.Duration
+	now          time.Time
+}
+
+// The commentPolicy() helper returns the policy for the current user and
+// the given snippet.
+func (app *application) commentPolicy(r *http.Request, snippet models.Snippet) commentPolicy {
+	return commentPolicy{
+		userID:       app.authenticatedUserID(r),
+		snippetOwner: snippet.UserID,
+		editWindow:   app.commentEditWindow,
+		now:          time.Now(),
+	}
+}
+
+// isAuthor reports whether the current user wrote the comment.
+func (p commentPolicy) isAuthor(comment models.Comment) bool {
+	return p.userID != 0 && comment.UserID == p.userID
+}
+
+// canEdit reports whether the current user may edit the comment. Deleted
+// comments can't be changed by anyone.
+func (p commentPolicy) canEdit(comment models.Comment) bool {
+	return !comment.Deleted && p.isAuthor(comment) && p.now.Before(comment.Created.Add(p.editWindow))
+}
+
+// canDelete reports whether the current user may delete the comment.
+func (p commentPolicy) canDelete(comment models.Comment) bool {
+	return !comment.Deleted && (p.canEdit(comment) || (p.userID != 0 && p.snippetOwner == p.userID))
+}
+
+// A commentView is a comment as shown under a snippet, with its depth in the
+// thread and what the current user may do with it.
+type commentView struct {
+	models.Comment
+	Depth     int
+	CanEdit   bool
+	CanDelete bool
+}
+
+// threadComments arranges the comments on a snippet into threads. Each
+// comment is followed by its replies (oldest first), so the threads can be
+// shown as a flat list indented by Depth.
+func threadComments(comments []models.Comment, policy commentPolicy) []commentView {
+	replies := map[int][]models.Comment{}
+	for _, c := range comments {
+		replies[c.ParentID] = append(replies[c.ParentID], c)
+	}
+
+	views := make([]commentView, 0, len(comments))
+
+	var walk func(parentID, depth int)
+	walk = func(parentID, depth int) {
+		for _, c := range replies[parentID] {
+			views = append(views, commentView{
+				Comment:   c,
+				Depth:     min(depth, maxCommentDepth),
+				CanEdit:   policy.canEdit(c),
+				CanDelete: policy.canDelete(c),
+			})
+			walk(c.ID, depth+1)
+		}
+	}
+	walk(0, 0)
+
+	return views
+}
+
+// commentPath returns the URL of a comment on its snippet's view page.
+func commentPath(comment models.Comment) string {
+	return fmt.Sprintf("/snippet/view/%s#comment-%d", comment.SnippetShortID, comment.ID)
+}
+
+// The commentWindowPassed() helper sends the author of a comment which can
+// no longer be changed back to it, with a message explaining why.
+func (app *application) commentWindowPassed(w http.ResponseWriter, r *http.Request, comment models.Comment) {
+	app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("Comments can only be changed for %s after they're posted.", formatLifetime(app.commentEditWindow)))
+	http.Redirect(w, r, commentPath(comment), http.StatusSeeOther)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mixnblend/snippetbox/internal/assert"
+	"github.com/mixnblend/snippetbox/internal/models"
+)
+
+func TestCommentPolicy(t *testing.T) {
+	now := time.Date(2024, 3, 17, 10, 0, 0, 0, time.UTC)
+	comment := models.Comment{ID: 1, UserID: 2, Created: now.Add(-10 * time.Minute)}
+
+	tests := []struct {
+		name       string
+		policy     commentPolicy
+		wantEdit   bool
+		wantDelete bool
+	}{
+		{
+			name:       "Author within the edit window",
+			policy:     commentPolicy{userID: 2, snippetOwner: 1, editWindow: 15 * time.Minute, now: now},
+			wantEdit:   true,
+			wantDelete: true,
+		},
+		{
+			name:   "Author after the edit window",
+			policy: commentPolicy{userID: 2, snippetOwner: 1, editWindow: 5 * time.Minute, now: now},
+		},
+		{
+			name:       "Snippet owner",
+			policy:     commentPolicy{userID: 1, snippetOwner: 1, editWindow: 15 * time.Minute, now: now},
+			wantDelete: true,
+		},
+		{
+			name:   "Someone else",
+			policy: commentPolicy{userID: 3, snippetOwner: 1, editWindow: 15 * time.Minute, now: now},
+		},
+		{
+			name:   "Anonymous",
+			policy: commentPolicy{snippetOwner: 1, editWindow: 15 * time.Minute, now: now},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When ... we check what the user may do with the comment
+			// Then ... they should only be allowed what the policy permits
+			assert.Equal(t, tt.policy.canEdit(comment), tt.wantEdit)
+			assert.Equal(t, tt.policy.canDelete(comment), tt.wantDelete)
+
+			// And ... nobody should be able to change it once it's deleted
+			deleted := comment
+			deleted.Deleted = true
+			assert.Equal(t, tt.policy.canEdit(deleted), false)
+			assert.Equal(t, tt.policy.canDelete(deleted), false)
+		})
+	}
+}
+
+func TestThreadComments(t *testing.T) {
+	// Given ... a comment with a reply, a reply to the reply and so on, and
+	// another top-level comment posted after the first reply
+	comments := []models.Comment{
+		{ID: 1},
+		{ID: 2, ParentID: 1},
+		{ID: 3},
+		{ID: 4, ParentID: 2},
+		{ID: 5, ParentID: 4},
+		{ID: 6, ParentID: 5},
+		{ID: 7, ParentID: 6},
+	}
+
+	// When ... we arrange them into threads
+	views := threadComments(comments, commentPolicy{})
+
+	// Then ... each comment should be followed by its replies, with the
+	// depth capped at maxCommentDepth
+	var ids, depths []int
+	for _, v := range views {
+		ids = append(ids, v.ID)
+		depths = append(depths, v.Depth)
+	}
+	assert.Equal(t, fmt.Sprint(ids), "[1 2 4 5 6 7 3]")
+	assert.Equal(t, fmt.Sprint(depths), "[0 1 2 3 4 4 0]")
+}
@@ -2,4 +2,9 @@ package main
 
 type contextKey string
 
-const isAuthenticatedContextKey = contextKey("isAuthenticated")
+const (
+	isAuthenticatedContextKey     = contextKey("isAuthenticated")
+	authenticatedUserIDContextKey = contextKey("authenticatedUserID")
+	isVerifiedContextKey          = contextKey("isVerified")
+	apiTokenContextKey            = contextKey("apiToken")
+)
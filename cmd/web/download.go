@@ -0,0 +1,144 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/mixnblend/snippetbox/internal/highlight"
+	"github.com/mixnblend/snippetbox/internal/models"
+)
+
+// maxFilenameLength is the longest a generated file name can be, not
+// counting any suffix or extension.
+const maxFilenameLength = 64
+
+// slugify reduces a title to lowercase letters, digits, dots, dashes and
+// underscores, for use in a file name. It returns an empty string if there's
+// nothing usable in the title.
+func slugify(title string) string {
+	var b strings.Builder
+	dash := false
+
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '_'):
+			b.WriteRune(r)
+			dash = false
+		case !dash && b.Len() > 0:
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+
+	slug := strings.Trim(b.String(), "-.")
+	if len(slug) > maxFilenameLength {
+		slug = strings.TrimRight(slug[:maxFilenameLength], "-.")
+	}
+
+	return slug
+}
+
+// defaultFileName returns the name given to the i'th file of a snippet when
+// its author didn't name it: the snippet's title followed by the extension
+// for the file's language, numbered from the second file onwards. Titles
+// which already end in the extension (such as "main.go") don't get it twice.
+func defaultFileName(title string, i int, language highlight.Language) string {
+	name := slugify(title)
+	if name == "" {
+		name = "snippet"
+	}
+	if i > 0 {
+		name = strings.TrimSuffix(name, language.Extension) + fmt.Sprintf("-%d", i+1)
+	}
+
+	if !strings.HasSuffix(name, language.Extension) {
+		name += language.Extension
+	}
+
+	return name
+}
+
+// archiveFilename returns the file name used when downloading all the files
+// of a snippet as a zip archive.
+func archiveFilename(snippet models.Snippet) string {
+	name := slugify(snippet.Title)
+	if name == "" {
+		name = snippet.ShortID
+	}
+
+	return name + ".zip"
+}
+
+// snippetArchive returns a zip archive holding all the files of a snippet.
+// Every file is given the snippet's created time, so that the archive (and
+// therefore its ETag) only changes when the files do.
+func snippetArchive(snippet models.Snippet) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	for _, file := range snippet.Files {
+		w, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     file.Name,
+			Method:   zip.Deflate,
+			Modified: snippet.Created.UTC(),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = w.Write([]byte(file.Content))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := zw.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// contentETag returns a strong ETag for some content, so that clients can
+// make conditional requests for it.
+func contentETag(content []byte) string {
+	sum := sha256.Sum256(content)
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+}
+
+// The serveSnippetContent() helper writes some content from a snippet, such
+// as one of its files, with no template around it. It uses
+// http.ServeContent(), which deals with HEAD, Range and conditional
+// (If-None-Match) requests for us. Snippets can be edited without their
+// created time changing, so the ETag is used for conditional requests rather
+// than a modification time.
+func (app *application) serveSnippetContent(w http.ResponseWriter, r *http.Request, snippet models.Snippet, contentType string, content []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("ETag", contentETag(content))
+
+	// Ask caches to check back every time, so that edits, deletions and
+	// expiry are seen straight away. Anything that isn't public must not be
+	// kept in a shared cache at all.
+	if snippet.Visibility == models.VisibilityPublic && !snippet.Protected {
+		w.Header().Set("Cache-Control", "no-cache")
+	} else {
+		w.Header().Set("Cache-Control", "private, no-cache")
+	}
+
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+}
+
+// contentDisposition returns a Content-Disposition header value which asks
+// the browser to save the response under the given file name.
+func contentDisposition(filename string) string {
+	return mime.FormatMediaType("attachment", map[string]string{"filename": filename})
+}
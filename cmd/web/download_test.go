@@ -0,0 +1,111 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/mixnblend/snippetbox/internal/assert"
+	"github.com/mixnblend/snippetbox/internal/highlight"
+	"github.com/mixnblend/snippetbox/internal/models"
+)
+
+func TestDefaultFileName(t *testing.T) {
+	golang, _ := highlight.Lookup("go")
+	text, _ := highlight.Lookup(highlight.Text)
+
+	tests := []struct {
+		name     string
+		title    string
+		i        int
+		language highlight.Language
+		want     string
+	}{
+		{
+			name:     "Title and language",
+			title:    "Hello, World!",
+			language: golang,
+			want:     "hello-world.go",
+		},
+		{
+			name:     "Title with extension",
+			title:    "main.go",
+			language: golang,
+			want:     "main.go",
+		},
+		{
+			name:     "Second file",
+			title:    "main.go",
+			i:        1,
+			language: golang,
+			want:     "main-2.go",
+		},
+		{
+			name:     "Non-ASCII title",
+			title:    "古池や",
+			language: text,
+			want:     "snippet.txt",
+		},
+		{
+			name:     "Long title",
+			title:    "a very long title which goes on and on and on and on and on and on and on",
+			language: text,
+			want:     "a-very-long-title-which-goes-on-and-on-and-on-and-on-and-on-and.txt",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, defaultFileName(tt.title, tt.i, tt.language), tt.want)
+		})
+	}
+}
+
+func TestArchiveFilename(t *testing.T) {
+	assert.Equal(t, archiveFilename(models.Snippet{ShortID: "silentPond", Title: "Docker setup"}), "docker-setup.zip")
+	assert.Equal(t, archiveFilename(models.Snippet{ShortID: "silentPond", Title: "古池や"}), "silentPond.zip")
+}
+
+func TestSnippetArchive(t *testing.T) {
+	// Given a snippet with two files
+	snippet := models.Snippet{
+		Created: time.Date(2024, 3, 17, 10, 0, 0, 0, time.UTC),
+		Files: []models.SnippetFile{
+			{Name: "Dockerfile", Content: "FROM golang"},
+			{Name: "compose.yaml", Content: "services:"},
+		},
+	}
+
+	// When it is archived
+	archive, err := snippetArchive(snippet)
+	assert.NilError(t, err)
+
+	// Then the archive holds both files, in order
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	assert.NilError(t, err)
+	assert.Equal(t, len(zr.File), 2)
+
+	for i, file := range snippet.Files {
+		assert.Equal(t, zr.File[i].Name, file.Name)
+
+		rc, err := zr.File[i].Open()
+		assert.NilError(t, err)
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		assert.NilError(t, err)
+		assert.Equal(t, string(content), file.Content)
+	}
+
+	// And archiving it again gives exactly the same bytes, so the ETag is
+	// stable
+	again, err := snippetArchive(snippet)
+	assert.NilError(t, err)
+	assert.Equal(t, contentETag(again), contentETag(archive))
+}
+
+func TestContentDisposition(t *testing.T) {
+	assert.Equal(t, contentDisposition("main.go"), "attachment; filename=main.go")
+	assert.Equal(t, contentDisposition("my snippet.txt"), `attachment; filename="my snippet.txt"`)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// neverExpires is the value of the expires form field for snippets which
+// are kept until they are deleted.
+const neverExpires = "never"
+
+// expiresAtLayout is the format used by <input type='datetime-local'>. The
+// browser may or may not include the seconds, so both forms are accepted.
+const (
+	expiresAtLayout        = "2006-01-02T15:04"
+	expiresAtLayoutSeconds = "2006-01-02T15:04:05"
+)
+
+var errInvalidLifetime = errors.New("invalid lifetime")
+
+// lifetimeUnits maps the unit suffixes accepted by parseLifetime() to their
+// durations.
+var lifetimeUnits = map[byte]time.Duration{
+	'm': time.Minute,
+	'h': time.Hour,
+	'd': 24 * time.Hour,
+	'w': 7 * 24 * time.Hour,
+}
+
+// parseLifetime parses a lifetime such as "10m", "6h", "30d" or "2w" into a
+// time.Duration. Unlike time.ParseDuration() it understands days and weeks,
+// but only accepts a single positive whole number followed by a unit.
+func parseLifetime(s string) (time.Duration, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if len(s) < 2 {
+		return 0, errInvalidLifetime
+	}
+
+	unit, ok := lifetimeUnits[s[len(s)-1]]
+	if !ok {
+		return 0, errInvalidLifetime
+	}
+
+	n, err := strconv.ParseInt(s[:len(s)-1], 10, 64)
+	if err != nil || n < 1 || n > math.MaxInt64/int64(unit) {
+		return 0, errInvalidLifetime
+	}
+
+	return time.Duration(n) * unit, nil
+}
+
+// formatLifetime is the inverse of parseLifetime(). It uses the largest unit
+// which divides the duration exactly, falling back to minutes.
+func formatLifetime(d time.Duration) string {
+	for _, u := range []byte{'w', 'd', 'h'} {
+		if d%lifetimeUnits[u] == 0 {
+			return fmt.Sprintf("%d%c", d/lifetimeUnits[u], u)
+		}
+	}
+
+	return fmt.Sprintf("%dm", d/time.Minute)
+}
+
+// parseExpiresAt parses the value of a datetime-local input. Times are
+// interpreted as UTC, which is how they are displayed everywhere else.
+func parseExpiresAt(s string) (time.Time, error) {
+	t, err := time.ParseInLocation(expiresAtLayout, s, time.UTC)
+	if err != nil {
+		t, err = time.ParseInLocation(expiresAtLayoutSeconds, s, time.UTC)
+	}
+
+	return t, err
+}
+
+// An expiryPolicy limits how long a snippet may be kept for.
+type expiryPolicy struct {
+	// allowNever permits snippets which never expire.
+	allowNever bool
+	// maxLifetime is the longest a snippet may be kept for. Zero means
+	// there is no limit.
+	maxLifetime time.Duration
+}
+
+// The expiryPolicy helper returns the expiry policy for the current user.
+// Users whose accounts have been verified may keep snippets for as long as
+// they like, while accounts which haven't been verified yet are limited to
+// the configured maximum lifetime.
+func (app *application) expiryPolicy(r *http.Request) expiryPolicy {
+	if app.isVerified(r) {
+		return expiryPolicy{allowNever: true}
+	}
+
+	return expiryPolicy{maxLifetime: app.maxUnverifiedLifetime}
+}
+
+// defaultLifetime returns the lifetime the snippet form starts with: a year,
+// or the longest the policy allows if that's shorter.
+func (policy expiryPolicy) defaultLifetime() string {
+	if policy.maxLifetime > 0 && policy.maxLifetime < 365*24*time.Hour {
+		return formatLifetime(policy.maxLifetime)
+	}
+
+	return "365d"
+}
+
+// limit returns when a copied snippet which would otherwise expire at the
+// given time (the zero time meaning never) may be kept until under the
+// policy. Expiry times beyond the maximum lifetime are brought forward to it.
+func (policy expiryPolicy) limit(expires, now time.Time) time.Time {
+	if policy.maxLifetime <= 0 {
+		return expires
+	}
+
+	latest := now.Add(policy.maxLifetime)
+	if expires.IsZero() || expires.After(latest) {
+		return latest
+	}
+
+	return expires
+}
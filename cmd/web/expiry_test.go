@@ -0,0 +1,156 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mixnblend/snippetbox/internal/assert"
+)
+
+func TestParseLifetime(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "Minutes", value: "10m", want: 10 * time.Minute},
+		{name: "Hours", value: "6h", want: 6 * time.Hour},
+		{name: "Days", value: "30d", want: 30 * 24 * time.Hour},
+		{name: "Weeks", value: "2w", want: 14 * 24 * time.Hour},
+		{name: "Upper case and spaces", value: " 1D ", want: 24 * time.Hour},
+		{name: "Empty", value: "", wantErr: true},
+		{name: "No number", value: "d", wantErr: true},
+		{name: "No unit", value: "30", wantErr: true},
+		{name: "Unknown unit", value: "3y", wantErr: true},
+		{name: "Zero", value: "0h", wantErr: true},
+		{name: "Negative", value: "-1h", wantErr: true},
+		{name: "Fraction", value: "1.5h", wantErr: true},
+		{name: "Overflow", value: "99999999999999w", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When ... we parse the lifetime
+			got, err := parseLifetime(tt.value)
+
+			// Then ... we should get the expected duration or an error
+			if tt.wantErr {
+				assert.Equal(t, err, errInvalidLifetime)
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, got, tt.want)
+		})
+	}
+}
+
+func TestFormatLifetime(t *testing.T) {
+	tests := []struct {
+		lifetime time.Duration
+		want     string
+	}{
+		{lifetime: 14 * 24 * time.Hour, want: "2w"},
+		{lifetime: 3 * 24 * time.Hour, want: "3d"},
+		{lifetime: 36 * time.Hour, want: "36h"},
+		{lifetime: 90 * time.Minute, want: "90m"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			// When ... we format the lifetime
+			// Then ... it should use the largest whole unit
+			assert.Equal(t, formatLifetime(tt.lifetime), tt.want)
+		})
+	}
+}
+
+func TestSnippetCreateFormValidateExpiry(t *testing.T) {
+	now := time.Date(2024, 3, 17, 10, 15, 0, 0, time.UTC)
+
+	verified := expiryPolicy{allowNever: true}
+	unverified := expiryPolicy{maxLifetime: 7 * 24 * time.Hour}
+
+	tests := []struct {
+		name      string
+		form      snippetCreateForm
+		policy    expiryPolicy
+		want      time.Time
+		wantError string
+	}{
+		{
+			name:   "Lifetime",
+			form:   snippetCreateForm{Expires: "6h"},
+			policy: verified,
+			want:   now.Add(6 * time.Hour),
+		},
+		{
+			name:   "Never",
+			form:   snippetCreateForm{Expires: "never"},
+			policy: verified,
+		},
+		{
+			name:      "Never when unverified",
+			form:      snippetCreateForm{Expires: "never"},
+			policy:    unverified,
+			wantError: "expires",
+		},
+		{
+			name:   "Absolute time",
+			form:   snippetCreateForm{Expires: "365d", ExpiresAt: "2024-03-18T09:30"},
+			policy: verified,
+			want:   time.Date(2024, 3, 18, 9, 30, 0, 0, time.UTC),
+		},
+		{
+			name:      "Absolute time in the past",
+			form:      snippetCreateForm{ExpiresAt: "2024-03-17T10:00"},
+			policy:    verified,
+			wantError: "expiresAt",
+		},
+		{
+			name:      "Invalid absolute time",
+			form:      snippetCreateForm{ExpiresAt: "tomorrow"},
+			policy:    verified,
+			wantError: "expiresAt",
+		},
+		{
+			name:   "Within the maximum lifetime",
+			form:   snippetCreateForm{Expires: "1w"},
+			policy: unverified,
+			want:   now.Add(7 * 24 * time.Hour),
+		},
+		{
+			name:      "Beyond the maximum lifetime",
+			form:      snippetCreateForm{Expires: "8d"},
+			policy:    unverified,
+			wantError: "expires",
+		},
+		{
+			name:      "Absolute time beyond the maximum lifetime",
+			form:      snippetCreateForm{ExpiresAt: "2025-01-01T00:00"},
+			policy:    unverified,
+			wantError: "expiresAt",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given ... we have a snippet form
+			form := tt.form
+
+			// When ... we validate its expiry
+			form.validateExpiry(tt.policy, now)
+
+			// Then ... either the expected field should have an error
+			if tt.wantError != "" {
+				_, ok := form.FieldErrors[tt.wantError]
+				assert.Equal(t, ok, true)
+				return
+			}
+
+			// Or ... the expiry time should be worked out as expected
+			assert.Equal(t, form.Valid(), true)
+			assert.Equal(t, form.expiry, tt.want)
+		})
+	}
+}
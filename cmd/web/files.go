@@ -0,0 +1,240 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/mixnblend/snippetbox/internal/highlight"
+	"github.com/mixnblend/snippetbox/internal/markdown"
+	"github.com/mixnblend/snippetbox/internal/models"
+	"github.com/mixnblend/snippetbox/internal/validator"
+)
+
+// maxSnippetFiles is the most files a snippet can have.
+const maxSnippetFiles = 10
+
+// maxSnippetBytes is the most content, in bytes, a snippet's files can have
+// between them. The content columns are MEDIUMTEXT, which holds far more
+// than this, so the file names added when the files are joined together for
+// searching always fit too.
+const maxSnippetBytes = 512 * 1024
+
+// A snippetFileForm holds one of the files in the snippet form. The form
+// decoder fills a slice of these from indexed fields such as
+// files[0].name and files[1].content, and the API from a JSON array.
+type snippetFileForm struct {
+	Name     string `form:"name" json:"name"`
+	Language string `form:"language" json:"language"`
+	Content  string `form:"content" json:"content"`
+}
+
+// fileFieldKey returns the key used for validation errors on a field of the
+// i'th file, which matches the name of the form field.
+func fileFieldKey(i int, field string) string {
+	return fmt.Sprintf("files[%d].%s", i, field)
+}
+
+// The editFiles() method handles the "Add file" and "Remove file" buttons on
+// the snippet form. These submit the whole form, so that they work without
+// any JavaScript. It reports whether one of them was pressed, in which case
+// the form should be shown again rather than saved.
+func (form *snippetCreateForm) editFiles() bool {
+	switch {
+	case form.AddFile:
+		if len(form.Files) < maxSnippetFiles {
+			form.Files = append(form.Files, snippetFileForm{})
+		}
+		return true
+	case form.RemoveFile != nil:
+		i := *form.RemoveFile
+		if i >= 0 && i < len(form.Files) && len(form.Files) > 1 {
+			form.Files = slices.Delete(form.Files, i, i+1)
+		}
+		return true
+	}
+
+	return false
+}
+
+// validateFiles checks the files in the form. File names are optional, but
+// the names the files end up with (see fileNames()) must all be different.
+func (form *snippetCreateForm) validateFiles() {
+	form.CheckField(len(form.Files) > 0, "files", "A snippet must have at least one file")
+	form.CheckField(validator.MaxItems(form.Files, maxSnippetFiles), "files", fmt.Sprintf("A snippet cannot have more than %d files", maxSnippetFiles))
+
+	size := 0
+	for _, file := range form.Files {
+		size += len(file.Content)
+	}
+	form.CheckField(size <= maxSnippetBytes, "files", fmt.Sprintf("A snippet's files cannot be more than %dKB in total", maxSnippetBytes/1024))
+
+	names := form.fileNames()
+	seen := make(map[string]bool)
+
+	for i, file := range form.Files {
+		form.CheckField(validator.NotBlank(file.Content), fileFieldKey(i, "content"), "This field cannot be blank")
+
+		if name := strings.TrimSpace(file.Name); name != "" {
+			form.CheckField(validator.MaxChars(name, 255), fileFieldKey(i, "name"), "This field cannot be more than 255 characters long")
+			form.CheckField(validator.Matches(name, validator.FileNameRX), fileFieldKey(i, "name"), "File names cannot contain slashes or control characters, or be only dots")
+		}
+
+		// An empty language means "detect it for me".
+		_, supported := highlight.Lookup(file.Language)
+		form.CheckField(file.Language == "" || supported, fileFieldKey(i, "language"), "This field must be one of the listed languages")
+
+		// Names are compared case-insensitively, as the files may be
+		// unzipped onto a case-insensitive file system.
+		key := strings.ToLower(names[i])
+		form.CheckField(!seen[key], fileFieldKey(i, "name"), "Each file must have a different name")
+		seen[key] = true
+	}
+}
+
+// fileNames returns the name of each file in the form. Files which weren't
+// given a name are named after the snippet's title and their language.
+func (form *snippetCreateForm) fileNames() []string {
+	names := make([]string, len(form.Files))
+
+	for i, file := range form.Files {
+		names[i] = strings.TrimSpace(file.Name)
+		if names[i] != "" {
+			continue
+		}
+
+		// Detected Markdown is named as plain text, as a .md file would be
+		// formatted as prose (see isProse), which only its author should
+		// choose.
+		language := highlight.Resolve(file.Language, file.Content)
+		if file.Language == "" && language.Name == highlight.Markdown {
+			language, _ = highlight.Lookup(highlight.Text)
+		}
+
+		names[i] = defaultFileName(form.Title, i, language)
+	}
+
+	return names
+}
+
+// files returns the files in the form as they should be saved.
+func (form *snippetCreateForm) files() []models.SnippetFile {
+	names := form.fileNames()
+	files := make([]models.SnippetFile, len(form.Files))
+
+	for i, file := range form.Files {
+		files[i] = models.SnippetFile{Name: names[i], Language: file.Language, Content: file.Content}
+	}
+
+	return files
+}
+
+// newFileForms returns the files of a snippet, for pre-populating the edit
+// form.
+func newFileForms(files []models.SnippetFile) []snippetFileForm {
+	forms := make([]snippetFileForm, len(files))
+
+	for i, file := range files {
+		forms[i] = snippetFileForm{Name: file.Name, Language: file.Language, Content: file.Content}
+	}
+
+	return forms
+}
+
+// A renderedFile is a file of a snippet ready to be shown on the view page,
+// with its content highlighted, or formatted if Prose is true.
+type renderedFile struct {
+	Name     string
+	Language highlight.Language
+	HTML     template.HTML
+	Prose    bool
+}
+
+// linePrefix returns the prefix of the line IDs in the i'th file on the view
+// page. The first file uses plain #L10 style anchors, so that links to
+// single-file snippets stay short, and later files use #F2L10 and so on.
+func linePrefix(i int) string {
+	if i == 0 {
+		return highlight.LinePrefix
+	}
+
+	return fmt.Sprintf("F%d%s", i+1, highlight.LinePrefix)
+}
+
+// headingPrefix returns the prefix of the heading IDs in the i'th file on
+// the view page, when it's Markdown. As with linePrefix, later files get
+// their own prefix.
+func headingPrefix(i int) string {
+	if i == 0 {
+		return "md-"
+	}
+
+	return fmt.Sprintf("f%d-md-", i+1)
+}
+
+// isProse reports whether a file should be formatted as Markdown prose
+// rather than highlighted: only if its author chose Markdown, or gave it a
+// .md name without choosing another language. Detecting Markdown from the
+// content isn't enough, as prose has no line numbers for #L10 style links to
+// point at, and a misdetected file would lose them.
+func isProse(file models.SnippetFile) bool {
+	if file.Language == "" {
+		return strings.EqualFold(path.Ext(file.Name), ".md")
+	}
+
+	return file.Language == highlight.Markdown
+}
+
+// renderFiles highlights each of the files of a snippet in its language,
+// detecting the language of any file whose author didn't choose one.
+// Markdown files are formatted as prose instead (see isProse).
+func renderFiles(files []models.SnippetFile) ([]renderedFile, error) {
+	rendered := make([]renderedFile, len(files))
+
+	for i, file := range files {
+		language := highlight.Resolve(file.Language, file.Content)
+
+		if isProse(file) {
+			language, _ = highlight.Lookup(highlight.Markdown)
+
+			html, err := markdown.Render(file.Content, headingPrefix(i))
+			if err != nil {
+				return nil, err
+			}
+
+			rendered[i] = renderedFile{Name: file.Name, Language: language, HTML: html, Prose: true}
+			continue
+		}
+
+		html, err := highlight.Render(file.Content, language.Name, linePrefix(i))
+		if err != nil {
+			return nil, err
+		}
+
+		rendered[i] = renderedFile{Name: file.Name, Language: language, HTML: html}
+	}
+
+	return rendered, nil
+}
+
+// findFile returns the file of a snippet with the given name, or its first
+// file if the name is empty. The boolean is false if there's no such file.
+func findFile(snippet models.Snippet, name string) (models.SnippetFile, bool) {
+	if len(snippet.Files) == 0 {
+		return models.SnippetFile{}, false
+	}
+
+	if name == "" {
+		return snippet.Files[0], true
+	}
+
+	for _, file := range snippet.Files {
+		if file.Name == name {
+			return file, true
+		}
+	}
+
+	return models.SnippetFile{}, false
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mixnblend/snippetbox/internal/assert"
+	"github.com/mixnblend/snippetbox/internal/models"
+)
+
+func TestSnippetCreateFormEditFiles(t *testing.T) {
+	// Given a form with two files
+	newForm := func() snippetCreateForm {
+		return snippetCreateForm{Files: []snippetFileForm{{Name: "a.go"}, {Name: "b.go"}}}
+	}
+
+	t.Run("Add file", func(t *testing.T) {
+		// When the "Add file" button is pressed
+		form := newForm()
+		form.AddFile = true
+
+		// Then an empty file is added to the end
+		assert.Equal(t, form.editFiles(), true)
+		assert.Equal(t, len(form.Files), 3)
+		assert.Equal(t, form.Files[2], snippetFileForm{})
+	})
+
+	t.Run("Remove file", func(t *testing.T) {
+		// When the "Remove file" button of the first file is pressed
+		form := newForm()
+		i := 0
+		form.RemoveFile = &i
+
+		// Then only the second file is left
+		assert.Equal(t, form.editFiles(), true)
+		assert.Equal(t, len(form.Files), 1)
+		assert.Equal(t, form.Files[0].Name, "b.go")
+	})
+
+	t.Run("Remove last file", func(t *testing.T) {
+		// When the only file is removed
+		form := snippetCreateForm{Files: []snippetFileForm{{Name: "a.go"}}}
+		i := 0
+		form.RemoveFile = &i
+
+		// Then it is kept, as a snippet needs at least one file
+		assert.Equal(t, form.editFiles(), true)
+		assert.Equal(t, len(form.Files), 1)
+	})
+
+	t.Run("Save", func(t *testing.T) {
+		// When the form is submitted normally
+		form := newForm()
+
+		// Then the files are left alone
+		assert.Equal(t, form.editFiles(), false)
+		assert.Equal(t, len(form.Files), 2)
+	})
+}
+
+func TestSnippetCreateFormValidateFiles(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     []snippetFileForm
+		wantError string
+	}{
+		{
+			name:  "Valid",
+			files: []snippetFileForm{{Name: "Dockerfile", Content: "FROM golang"}, {Content: "services:"}},
+		},
+		{
+			name:      "No files",
+			wantError: "files",
+		},
+		{
+			name:      "Blank content",
+			files:     []snippetFileForm{{Content: "a"}, {Content: " "}},
+			wantError: "files[1].content",
+		},
+		{
+			name:      "Name with a slash",
+			files:     []snippetFileForm{{Name: "../etc/passwd", Content: "a"}},
+			wantError: "files[0].name",
+		},
+		{
+			name:      "Name of dots",
+			files:     []snippetFileForm{{Name: "..", Content: "a"}},
+			wantError: "files[0].name",
+		},
+		{
+			name:      "Duplicate names",
+			files:     []snippetFileForm{{Name: "main.go", Content: "a"}, {Name: "Main.go", Content: "b"}},
+			wantError: "files[1].name",
+		},
+		{
+			name:      "Unknown language",
+			files:     []snippetFileForm{{Language: "cobol", Content: "a"}},
+			wantError: "files[0].language",
+		},
+		{
+			name:  "As big as allowed",
+			files: []snippetFileForm{{Content: strings.Repeat("a", maxSnippetBytes/2)}, {Content: strings.Repeat("b", maxSnippetBytes/2)}},
+		},
+		{
+			name:      "Too big in total",
+			files:     []snippetFileForm{{Content: strings.Repeat("a", maxSnippetBytes/2)}, {Content: strings.Repeat("b", maxSnippetBytes/2+1)}},
+			wantError: "files",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := snippetCreateForm{Title: "Compose", Files: tt.files}
+			form.validateFiles()
+
+			if tt.wantError == "" {
+				assert.Equal(t, form.Valid(), true)
+				return
+			}
+			_, ok := form.FieldErrors[tt.wantError]
+			assert.Equal(t, ok, true)
+		})
+	}
+}
+
+func TestSnippetCreateFormFiles(t *testing.T) {
+	// Given a form with a named file and an unnamed one
+	form := snippetCreateForm{
+		Title: "Compose",
+		Files: []snippetFileForm{
+			{Name: " Dockerfile ", Language: "docker", Content: "FROM golang"},
+			{Language: "yaml", Content: "services:"},
+		},
+	}
+
+	// When its files are prepared for saving
+	files := form.files()
+
+	// Then names are trimmed, and the unnamed file is named after the title
+	assert.Equal(t, files[0].Name, "Dockerfile")
+	assert.Equal(t, files[1].Name, "compose-2.yaml")
+	assert.Equal(t, files[1].Language, "yaml")
+}
+
+func TestSnippetCreateFormFilesDetectedMarkdown(t *testing.T) {
+	// Given a form with an unnamed file which looks like Markdown, but whose
+	// author didn't choose a language
+	form := snippetCreateForm{
+		Title: "Notes",
+		Files: []snippetFileForm{{Content: "# Notes\n\n- one\n- two\n"}},
+	}
+
+	// When its files are prepared for saving
+	files := form.files()
+
+	// Then it isn't given a .md name, which would have it formatted as prose
+	assert.Equal(t, files[0].Name, "notes.txt")
+}
+
+func TestRenderFiles(t *testing.T) {
+	tests := []struct {
+		name      string
+		file      models.SnippetFile
+		wantProse bool
+		wantLabel string
+	}{
+		{
+			name:      "Chosen Markdown",
+			file:      models.SnippetFile{Name: "notes.txt", Language: "markdown", Content: "# Notes\n"},
+			wantProse: true,
+			wantLabel: "Markdown",
+		},
+		{
+			name:      "Markdown file name",
+			file:      models.SnippetFile{Name: "README.md", Content: "Read me.\n"},
+			wantProse: true,
+			wantLabel: "Markdown",
+		},
+		{
+			name:      "Detected Markdown",
+			file:      models.SnippetFile{Name: "notes.txt", Content: "# Notes\n\n- one\n- two\n"},
+			wantLabel: "Markdown",
+		},
+		{
+			name:      "Other language chosen for a Markdown file name",
+			file:      models.SnippetFile{Name: "config.md", Language: "yaml", Content: "# Config\nkey: value\n"},
+			wantLabel: "YAML",
+		},
+		{
+			name:      "Commented YAML",
+			file:      models.SnippetFile{Name: "config.yaml", Content: "# Config\nkey: value\n"},
+			wantLabel: "YAML",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When the file is rendered
+			rendered, err := renderFiles([]models.SnippetFile{tt.file})
+			assert.NilError(t, err)
+
+			// Then it's only formatted as prose if its author asked for
+			// Markdown, and otherwise highlighted with line numbers
+			assert.Equal(t, rendered[0].Prose, tt.wantProse)
+			assert.Equal(t, rendered[0].Language.Label, tt.wantLabel)
+			assert.Equal(t, strings.Contains(string(rendered[0].HTML), `id="L1"`), !tt.wantProse)
+		})
+	}
+}
+
+func TestLinePrefix(t *testing.T) {
+	assert.Equal(t, linePrefix(0), "L")
+	assert.Equal(t, linePrefix(1), "F2L")
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"time"
+
+	"github.com/mixnblend/snippetbox/internal/models"
+)
+
+// forkInput returns the input for a new snippet which is a copy of the given
+// one, recording it as the parent. The fork keeps the title, files, tags,
+// visibility and expiry of the original, although the expiry is brought
+// forward if the forking user's policy doesn't allow them to keep it that
+// long. A passphrase can't be copied (only its hash is stored), so forks of
+// protected snippets are made private rather than leaving their content open
+// to everyone.
+func forkInput(snippet models.Snippet, policy expiryPolicy, now time.Time) models.SnippetInput {
+	input := models.SnippetInput{
+		Title:            snippet.Title,
+		Files:            snippet.Files,
+		Expires:          policy.limit(snippet.Expires, now),
+		Visibility:       snippet.Visibility,
+		BurnAfterReading: snippet.BurnAfterReading,
+		Tags:             snippet.Tags,
+		ParentID:         snippet.ID,
+	}
+
+	if snippet.Protected {
+		input.Visibility = models.VisibilityPrivate
+	}
+
+	return input
+}
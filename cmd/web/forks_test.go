@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mixnblend/snippetbox/internal/assert"
+	"github.com/mixnblend/snippetbox/internal/models"
+)
+
+func TestForkInput(t *testing.T) {
+	snippet := models.Snippet{
+		ID:         7,
+		Title:      "Compose",
+		Expires:    time.Date(2024, 3, 17, 10, 0, 0, 0, time.UTC),
+		Visibility: models.VisibilityUnlisted,
+		Files:      []models.SnippetFile{{Name: "compose.yaml", Content: "services:"}},
+		Tags:       []string{"docker"},
+	}
+
+	// And ... the forking user's policy and the current time
+	verified := expiryPolicy{allowNever: true}
+	now := time.Date(2024, 3, 10, 10, 0, 0, 0, time.UTC)
+
+	t.Run("Copy", func(t *testing.T) {
+		// When ... we fork a snippet
+		input := forkInput(snippet, verified, now)
+
+		// Then ... the copy should keep its content and settings, and record
+		// where it came from
+		assert.Equal(t, input.Title, "Compose")
+		assert.Equal(t, input.Files[0], snippet.Files[0])
+		assert.Equal(t, input.Tags[0], "docker")
+		assert.Equal(t, input.Expires, snippet.Expires)
+		assert.Equal(t, input.Visibility, models.VisibilityUnlisted)
+		assert.Equal(t, input.ParentID, 7)
+	})
+
+	t.Run("Protected", func(t *testing.T) {
+		// When ... we fork a passphrase protected snippet
+		protected := snippet
+		protected.Protected = true
+		input := forkInput(protected, verified, now)
+
+		// Then ... the copy should be private, as it has no passphrase
+		assert.Equal(t, input.Visibility, models.VisibilityPrivate)
+		assert.Equal(t, input.Passphrase, "")
+	})
+
+	t.Run("Unverified", func(t *testing.T) {
+		unverified := expiryPolicy{maxLifetime: 24 * time.Hour}
+
+		// When ... an unverified user forks a snippet which never expires
+		forever := snippet
+		forever.Expires = time.Time{}
+		input := forkInput(forever, unverified, now)
+
+		// Then ... the copy should expire after the longest lifetime allowed
+		assert.Equal(t, input.Expires, now.Add(24*time.Hour))
+
+		// When ... they fork a snippet which expires after that
+		input = forkInput(snippet, unverified, now)
+
+		// Then ... the copy should also expire after the longest lifetime
+		assert.Equal(t, input.Expires, now.Add(24*time.Hour))
+
+		// When ... they fork a snippet which expires before that
+		soon := snippet
+		soon.Expires = now.Add(time.Hour)
+		input = forkInput(soon, unverified, now)
+
+		// Then ... the copy should keep its expiry
+		assert.Equal(t, input.Expires, soon.Expires)
+	})
+}
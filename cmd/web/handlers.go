@@ -5,20 +5,139 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/mixnblend/snippetbox/internal/diff"
+	"github.com/mixnblend/snippetbox/internal/highlight"
 	"github.com/mixnblend/snippetbox/internal/models"
+	"github.com/mixnblend/snippetbox/internal/search"
 	"github.com/mixnblend/snippetbox/internal/validator"
 )
 
+// The home page shows up to mostStarredLimit of the snippets starred most
+// often in the last mostStarredPeriod.
+const (
+	mostStarredLimit  = 5
+	mostStarredPeriod = 7 * 24 * time.Hour
+)
+
+// diffContextLines is the number of unchanged lines shown around each change
+// when comparing two revisions of a snippet.
+const diffContextLines = 3
+
+// The maximum number of search results shown, and the length of the excerpt
+// of each snippet's content shown with them.
+const (
+	searchResultLimit  = 50
+	searchExcerptWidth = 200
+)
+
 // Update our snippetCreateForm struct to include struct tags which tell the
 // decoder how to map HTML form values into the different struct fields. So, for
 // example, here we're telling the decoder to store the value from the HTML form
 // input with the name "title" in the Title field. The struct tag `form:"-"`
-// tells the decoder to completely ignore a field during decoding.
+// tells the decoder to completely ignore a field during decoding. The json
+// tags do the same for the JSON bodies of the API, which share the form and
+// its validation; the file buttons only make sense in the HTML form.
 type snippetCreateForm struct {
-	Title               string `form:"title"`
-	Content             string `form:"content"`
-	Expires             int    `form:"expires"`
+	Title               string            `form:"title" json:"title"`
+	Files               []snippetFileForm `form:"files" json:"files"`
+	Expires             string            `form:"expires" json:"expires"`
+	ExpiresAt           string            `form:"expiresAt" json:"expiresAt"`
+	Visibility          string            `form:"visibility" json:"visibility"`
+	BurnAfterReading    bool              `form:"burnAfterReading" json:"burnAfterReading"`
+	Passphrase          string            `form:"passphrase" json:"passphrase"`
+	RemovePassphrase    bool              `form:"removePassphrase" json:"removePassphrase"`
+	Tags                string            `form:"tags" json:"tags"`
+	AddFile             bool              `form:"addFile" json:"-"`
+	RemoveFile          *int              `form:"removeFile" json:"-"`
+	validator.Validator `form:"-" json:"-"`
+
+	// expiry is the time at which the snippet expires, as worked out by
+	// validate(). The zero time means the snippet never expires.
+	expiry time.Time
+}
+
+// validate runs the validation checks shared by the create and edit snippet
+// forms. Because the Validator struct is embedded by the snippetCreateForm
+// struct, we can call CheckField() directly on it to execute our validation
+// checks. CheckField() will add the provided key and error message to the
+// FieldErrors map if the check does not evaluate to true. For example, in the
+// first line here we "check that the form.Title field is not blank". In the
+// second, we "check that the form.Title field has a maximum character length
+// of 100" and so on. The expiry is checked against the given policy.
+func (form *snippetCreateForm) validate(policy expiryPolicy) {
+	form.CheckField(validator.NotBlank(form.Title), "title", "This field cannot be blank")
+	form.CheckField(validator.MaxChars(form.Title, 100), "title", "This field cannot be more than 100 characters long")
+	form.validateFiles()
+	form.validateExpiry(policy, time.Now())
+	form.CheckField(validator.PermittedValue(form.Visibility, models.VisibilityPublic, models.VisibilityUnlisted, models.VisibilityPrivate), "visibility", "This field must equal public, unlisted or private")
+	// bcrypt only uses the first 72 bytes of its input, so don't accept
+	// anything longer.
+	form.CheckField(len(form.Passphrase) <= 72, "passphrase", "This field cannot be more than 72 bytes long")
+	// Tags are normalised to lowercase before they're checked.
+	tags := validator.NormalizeTags(form.Tags)
+	form.CheckField(validator.MaxItems(tags, maxSnippetTags), "tags", fmt.Sprintf("A snippet cannot have more than %d tags", maxSnippetTags))
+	form.CheckField(validator.AllMatch(tags, validator.TagRX), "tags", "Tags can only contain letters, numbers and . + # -, and cannot be more than 32 characters long")
+}
+
+// validateExpiry works out when the snippet should expire. An absolute
+// expiresAt time takes precedence over the expires field, which holds either
+// a lifetime such as "6h" or "never".
+func (form *snippetCreateForm) validateExpiry(policy expiryPolicy, now time.Time) {
+	key := "expires"
+
+	switch {
+	case form.ExpiresAt != "":
+		key = "expiresAt"
+
+		expiry, err := parseExpiresAt(form.ExpiresAt)
+		if err != nil {
+			form.AddFieldError(key, "This field must be a valid date and time")
+			return
+		}
+		if !expiry.After(now) {
+			form.AddFieldError(key, "This field must be in the future")
+			return
+		}
+		form.expiry = expiry
+	case strings.EqualFold(strings.TrimSpace(form.Expires), neverExpires):
+		if !policy.allowNever {
+			form.AddFieldError(key, "Only verified accounts can create snippets which never expire")
+		}
+		return
+	default:
+		lifetime, err := parseLifetime(form.Expires)
+		if err != nil {
+			form.AddFieldError(key, "This field must be a lifetime such as 10m, 6h or 30d, or never")
+			return
+		}
+		form.expiry = now.Add(lifetime)
+	}
+
+	if policy.maxLifetime > 0 && form.expiry.After(now.Add(policy.maxLifetime)) {
+		form.AddFieldError(key, fmt.Sprintf("Snippets cannot be kept for more than %s", formatLifetime(policy.maxLifetime)))
+	}
+}
+
+// input converts the validated form into the fields expected by the snippet
+// model.
+func (form *snippetCreateForm) input() models.SnippetInput {
+	return models.SnippetInput{
+		Title:            form.Title,
+		Files:            form.files(),
+		Expires:          form.expiry,
+		Visibility:       form.Visibility,
+		BurnAfterReading: form.BurnAfterReading,
+		Passphrase:       form.Passphrase,
+		RemovePassphrase: form.RemovePassphrase,
+		Tags:             validator.NormalizeTags(form.Tags),
+	}
+}
+
+type snippetUnlockForm struct {
+	Passphrase          string `form:"passphrase"`
 	validator.Validator `form:"-"`
 }
 
@@ -29,146 +148,1166 @@ type userSignupForm struct {
 	validator.Validator `form:"-"`
 }
 
-type userLoginForm struct {
-	Email               string `form:"email"`
-	Password            string `form:"password"`
-	validator.Validator `form:"-"`
+type userLoginForm struct {
+	Email               string `form:"email"`
+	Password            string `form:"password"`
+	validator.Validator `form:"-"`
+}
+
+type accountPasswordUpdateForm struct {
+	CurrentPassword         string `form:"currentPassword"`
+	NewPassword             string `form:"newPassword"`
+	NewPasswordConfirmation string `form:"newPasswordConfirmation"`
+	validator.Validator     `form:"-"`
+}
+
+// define a home handler function which writes a byte slice containing
+// "Hello from Snippetbox" as the response body.
+func (app *application) home(w http.ResponseWriter, r *http.Request) {
+
+	snippets, err := app.snippets.Latest()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	tags, err := app.snippets.PopularTags(tagCloudLimit)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	mostStarred, err := app.stars.MostStarred(time.Now().Add(-mostStarredPeriod), mostStarredLimit)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippets = snippets
+	data.TagCloud = newTagCloud(tags)
+	data.MostStarred = mostStarred
+
+	app.render(w, r, http.StatusOK, "home.tmpl", data)
+}
+
+// The snippetList handler shows a page of public snippets, with a cursor
+// for the next and previous pages and a choice of sort order.
+func (app *application) snippetList(w http.ResponseWriter, r *http.Request) {
+	// Read the cursor, page size and sort order from the query string,
+	// sending a 400 Bad Request response if any of them are invalid.
+	page, err := parseSnippetPage(r.URL.Query())
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	// Only public snippets are listed, in the same way as on the home page.
+	list, err := app.snippets.List(models.SnippetFilter{Visibility: models.VisibilityPublic}, page)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippets = list.Snippets
+	data.Pagination = newPagination("/snippets", page, list)
+
+	app.render(w, r, http.StatusOK, "snippets.tmpl", data)
+}
+
+func (app *application) snippetListByTag(w http.ResponseWriter, r *http.Request) {
+	// Tags are always stored in lowercase, so anything else can't match.
+	tag := r.PathValue("name")
+	if !validator.Matches(tag, validator.TagRX) {
+		http.NotFound(w, r)
+		return
+	}
+
+	page, err := parseSnippetPage(r.URL.Query())
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	filter := models.SnippetFilter{Visibility: models.VisibilityPublic, Tag: tag}
+
+	list, err := app.snippets.List(filter, page)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Tag = tag
+	data.Snippets = list.Snippets
+	data.Pagination = newPagination(tagPath(tag), page, list)
+
+	app.render(w, r, http.StatusOK, "snippets.tmpl", data)
+}
+
+func (app *application) snippetSearch(w http.ResponseWriter, r *http.Request) {
+	// Parse the query using the search syntax. A blank query (or one with
+	// nothing but exclusions) just shows the search form.
+	q := r.URL.Query().Get("q")
+	query := search.Parse(q)
+
+	data := app.newTemplateData(r)
+	data.Query = q
+
+	if !query.IsEmpty() {
+		snippets, err := app.snippets.Search(query, app.authenticatedUserID(r), searchResultLimit)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		highlights := query.Highlights()
+		for _, snippet := range snippets {
+			data.SearchResults = append(data.SearchResults, searchResult{
+				Snippet: snippet,
+				Title:   search.Highlight(snippet.Title, highlights),
+				Excerpt: search.Excerpt(snippet.Content, highlights, searchExcerptWidth),
+			})
+		}
+	}
+
+	app.render(w, r, http.StatusOK, "search.tmpl", data)
+}
+
+// Add an about page for the application.
+func (app *application) about(w http.ResponseWriter, r *http.Request) {
+
+	data := app.newTemplateData(r)
+	app.render(w, r, http.StatusOK, "about.tmpl", data)
+}
+
+func (app *application) snippetView(w http.ResponseWriter, r *http.Request) {
+	// Use the snippetFromPath() helper to retrieve the data for the snippet
+	// identified in the URL. If the id is invalid or no matching record is
+	// found, it will already have sent a 404 Not Found response.
+	snippet, ok := app.snippetFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+
+	// Protected snippets ask for their passphrase before anything else.
+	if !app.isUnlocked(r, snippet) {
+		data.Form = snippetUnlockForm{}
+		app.render(w, r, http.StatusOK, "unlock.tmpl", data)
+		return
+	}
+
+	// Burn-after-reading snippets are deleted the first time someone other
+	// than the owner reads them. Rather than burning the snippet on a GET
+	// request (which link-preview bots and prefetchers make freely), we show a
+	// confirmation page which POSTs back to snippetViewPost.
+	if !app.canReadContent(r, snippet) {
+		app.render(w, r, http.StatusOK, "burn.tmpl", data)
+		return
+	}
+
+	app.renderSnippet(w, r, http.StatusOK, data)
+}
+
+func (app *application) snippetViewPost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.snippetFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	// Only burn-after-reading snippets are confirmed this way; for anything
+	// else (or for the owner, or if the snippet still needs to be unlocked)
+	// just send the user to the normal view page.
+	if app.canReadContent(r, snippet) || !app.isUnlocked(r, snippet) {
+		http.Redirect(w, r, fmt.Sprintf("/snippet/view/%s", snippet.ShortID), http.StatusSeeOther)
+		return
+	}
+
+	// Read and delete the snippet in a single transaction. If someone else got
+	// there first, the snippet no longer exists and we send a 404.
+	snippet, err := app.snippets.Burn(snippet.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			http.NotFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	// Reading the snippet deleted it, so its owner's webhooks are told, just
+	// as if they'd deleted it themselves.
+	app.queueWebhookEvent(app.newSnippetEvent(models.EventSnippetDeleted, snippet))
+
+	// Make sure the revealed content isn't kept in any cache.
+	w.Header().Set("Cache-Control", "no-store")
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+
+	app.renderSnippet(w, r, http.StatusOK, data)
+}
+
+// The renderSnippet() helper highlights each of the files of data.Snippet in
+// its language and renders the view page, along with the comments on the
+// snippet. The highlighting happens here on the server, so the page works
+// without any JavaScript.
+func (app *application) renderSnippet(w http.ResponseWriter, r *http.Request, status int, data templateData) {
+	files, err := renderFiles(data.Snippet.Files)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	data.Files = files
+
+	// Signed-in users get a button to star or unstar the snippet, and can
+	// add it to one of their collections.
+	if data.IsAuthenticated {
+		data.Starred, err = app.stars.Starred(data.AuthenticatedUserID, data.Snippet.ID)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		data.Collections, err = app.collections.ByUser(data.AuthenticatedUserID)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	comments, err := app.comments.ForSnippet(data.Snippet.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	data.Comments = threadComments(comments, app.commentPolicy(r, data.Snippet))
+
+	// The comment form is only set already if posting a comment failed.
+	if data.Form == nil {
+		data.Form = commentForm{}
+	}
+
+	app.render(w, r, status, "view.tmpl", data)
+}
+
+func (app *application) snippetRaw(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.snippetFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	// Snippets which still need unlocking, or which would be burnt by reading
+	// them, can only be read through the view page.
+	if !app.canReadContent(r, snippet) {
+		http.Redirect(w, r, fmt.Sprintf("/snippet/view/%s", snippet.ShortID), http.StatusSeeOther)
+		return
+	}
+
+	// The file to serve can be picked with ?file=name, otherwise the first
+	// one is served.
+	file, ok := findFile(snippet, r.URL.Query().Get("file"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	app.serveSnippetContent(w, r, snippet, "text/plain; charset=utf-8", []byte(file.Content))
+}
+
+func (app *application) snippetDownload(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.snippetFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if !app.canReadContent(r, snippet) {
+		http.Redirect(w, r, fmt.Sprintf("/snippet/view/%s", snippet.ShortID), http.StatusSeeOther)
+		return
+	}
+
+	file, ok := findFile(snippet, r.URL.Query().Get("file"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", contentDisposition(file.Name))
+
+	app.serveSnippetContent(w, r, snippet, "text/plain; charset=utf-8", []byte(file.Content))
+}
+
+func (app *application) snippetArchive(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.snippetFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if !app.canReadContent(r, snippet) {
+		http.Redirect(w, r, fmt.Sprintf("/snippet/view/%s", snippet.ShortID), http.StatusSeeOther)
+		return
+	}
+
+	archive, err := snippetArchive(snippet)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", contentDisposition(archiveFilename(snippet)))
+
+	app.serveSnippetContent(w, r, snippet, "application/zip", archive)
+}
+
+func (app *application) snippetUnlockPost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.snippetFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var form snippetUnlockForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form.CheckField(validator.NotBlank(form.Passphrase), "passphrase", "This field cannot be blank")
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Snippet = snippet
+		data.Form = form
+		app.render(w, r, http.StatusUnprocessableEntity, "unlock.tmpl", data)
+		return
+	}
+
+	// Check the passphrase. Wrong attempts are counted against the snippet,
+	// and once there have been too many the snippet refuses to unlock for a
+	// while regardless of the passphrase.
+	err = app.snippets.Unlock(snippet.ID, form.Passphrase)
+	if err != nil {
+		var status int
+
+		switch {
+		case errors.Is(err, models.ErrInvalidCredentials):
+			form.AddNonFieldError("Passphrase is incorrect")
+			status = http.StatusUnprocessableEntity
+		case errors.Is(err, models.ErrTooManyAttempts):
+			form.AddNonFieldError("Too many incorrect attempts. Please try again later.")
+			status = http.StatusTooManyRequests
+		case errors.Is(err, models.ErrNoRecord):
+			http.NotFound(w, r)
+			return
+		default:
+			app.serverError(w, r, err)
+			return
+		}
+
+		data := app.newTemplateData(r)
+		data.Snippet = snippet
+		data.Form = form
+		app.render(w, r, status, "unlock.tmpl", data)
+		return
+	}
+
+	// Remember that this session has unlocked the snippet, so the passphrase
+	// isn't asked for again until the session expires or the owner changes
+	// it.
+	app.sessionManager.Put(r.Context(), unlockedSnippetKey(snippet.ID), snippet.PassphraseVersion)
+
+	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%s", snippet.ShortID), http.StatusSeeOther)
+}
+
+func (app *application) snippetHistory(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.snippetFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	// The history exposes the content of the snippet, so send users who can't
+	// read it yet back to the view page.
+	if !app.canReadContent(r, snippet) {
+		http.Redirect(w, r, fmt.Sprintf("/snippet/view/%s", snippet.ShortID), http.StatusSeeOther)
+		return
+	}
+
+	revisions, err := app.snippets.Revisions(snippet.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.Revisions = revisions
+
+	app.render(w, r, http.StatusOK, "history.tmpl", data)
+}
+
+func (app *application) snippetDiff(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.snippetFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if !app.canReadContent(r, snippet) {
+		http.Redirect(w, r, fmt.Sprintf("/snippet/view/%s", snippet.ShortID), http.StatusSeeOther)
+		return
+	}
+
+	// The versions to compare are passed in the from and to query string
+	// parameters. If either is missing or isn't a positive integer, we send a
+	// 400 Bad Request response.
+	from, err := strconv.Atoi(r.URL.Query().Get("from"))
+	if err != nil || from < 1 {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	to, err := strconv.Atoi(r.URL.Query().Get("to"))
+	if err != nil || to < 1 {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	fromRevision, err := app.snippets.Revision(snippet.ID, from)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			http.NotFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	toRevision, err := app.snippets.Revision(snippet.ID, to)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			http.NotFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.DiffFrom = fromRevision
+	data.DiffTo = toRevision
+	data.DiffHunks = diff.Unified(fromRevision.Content, toRevision.Content, diffContextLines)
+
+	app.render(w, r, http.StatusOK, "diff.tmpl", data)
+}
+
+func (app *application) snippetCreate(w http.ResponseWriter, r *http.Request) {
+	data := app.newTemplateData(r)
+	// Initialize a new createSnippetForm instance and pass it to the template.
+	// Notice how this is also a great opportunity to set any default or
+	// 'initial' values for the form --- here we set the initial value for the
+	// snippet expiry to 365 days (or less, if the user's account hasn't been
+	// verified) and make the snippet public.
+	data.Form = snippetCreateForm{
+		Files:      []snippetFileForm{{}},
+		Expires:    app.expiryPolicy(r).defaultLifetime(),
+		Visibility: models.VisibilityPublic,
+	}
+
+	app.render(w, r, http.StatusOK, "create.tmpl", data)
+}
+
+func (app *application) snippetCreatePost(w http.ResponseWriter, r *http.Request) {
+	//First we call r.ParseForm() which adds any data in POST request bodies
+	// to the r.PostForm map. This also works in the same way for PUT and PATCH
+	// requests. If there are any errors, we use our app.ClientError() helper to
+	// send a 400 Bad Request response to the user.
+	// Declare a new empty instance of the snippetCreateForm struct.
+	var form snippetCreateForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	// Call the Decode() method of the form decoder, passing in the current
+	// request and *a pointer* to our snippetCreateForm struct. This will
+	// essentially fill our struct with the relevant values from the HTML form.
+	// If there is a problem, we return a 400 Bad Request response to the client.
+	err = app.formDecoder.Decode(&form, r.PostForm)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	// If the user pressed "Add file" or "Remove file", show the form again
+	// with the change made.
+	if form.editFiles() {
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, r, http.StatusOK, "create.tmpl", data)
+		return
+	}
+
+	// Run the validation checks shared with the edit snippet form.
+	form.validate(app.expiryPolicy(r))
+
+	// Use the Valid() method to see if any of the checks failed. If they did,
+	// then re-render the template passing in the form in the same way as
+	// before.
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, r, http.StatusUnprocessableEntity, "create.tmpl", data)
+		return
+	}
+
+	// Pass the data to the SnippetModel.Insert() method, along with the ID of
+	// the logged-in user who will own the snippet, receiving the short ID of
+	// the new record back.
+	shortID, err := app.snippets.Insert(form.input(), app.authenticatedUserID(r))
+
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// Tell the user's webhooks about the new snippet. This only queues the
+	// event, so it doesn't hold up the redirect.
+	app.queueWebhookEvent(app.newSnippetEvent(models.EventSnippetCreated, models.Snippet{ShortID: shortID, UserID: app.authenticatedUserID(r)}))
+
+	// Use the Put() method to add a string value ("Snippet successfully
+	// created!") and the corresponding key ("flash") to the session data.
+	app.sessionManager.Put(r.Context(), "flash", "Snippet successfully created!")
+
+	// Redirect the user to the relevant page for the snippet.
+	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%s", shortID), http.StatusSeeOther)
+}
+
+// snippetPreviewPost shows the create form again with the files rendered
+// as they would be on the view page, so that Markdown can be checked before
+// it's published. Nothing is validated or saved. The "tab" field says which
+// tab of the form was chosen. Previews of an existing snippet, which have its
+// ID in the path, come from its edit page and are shown on it again.
+func (app *application) snippetPreviewPost(w http.ResponseWriter, r *http.Request) {
+	page := "create.tmpl"
+
+	var snippet models.Snippet
+	if r.PathValue("id") != "" {
+		var ok bool
+		snippet, ok = app.ownedSnippet(w, r)
+		if !ok {
+			return
+		}
+		page = "edit.tmpl"
+	}
+
+	var form snippetCreateForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	files, err := renderFiles(form.files())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.Form = form
+	data.Files = files
+	data.Preview = r.PostForm.Get("tab") == "preview"
+
+	app.render(w, r, http.StatusOK, page, data)
+}
+
+func (app *application) snippetEdit(w http.ResponseWriter, r *http.Request) {
+	// Only the owner of a snippet is allowed to edit it.
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	// Pre-populate the form with the current snippet, including its current
+	// expiry time so that saving the form doesn't change when it expires.
+	form := snippetCreateForm{
+		Title:            snippet.Title,
+		Files:            newFileForms(snippet.Files),
+		Visibility:       snippet.Visibility,
+		BurnAfterReading: snippet.BurnAfterReading,
+		Tags:             strings.Join(snippet.Tags, ", "),
+	}
+	if snippet.Expires.IsZero() {
+		form.Expires = neverExpires
+	} else {
+		form.ExpiresAt = snippet.Expires.UTC().Format(expiresAtLayout)
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.Form = form
+
+	app.render(w, r, http.StatusOK, "edit.tmpl", data)
+}
+
+func (app *application) snippetEditPost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	var form snippetCreateForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	if form.editFiles() {
+		data := app.newTemplateData(r)
+		data.Snippet = snippet
+		data.Form = form
+		app.render(w, r, http.StatusOK, "edit.tmpl", data)
+		return
+	}
+
+	// Apply the same validation rules as when creating a snippet.
+	form.validate(app.expiryPolicy(r))
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Snippet = snippet
+		data.Form = form
+		app.render(w, r, http.StatusUnprocessableEntity, "edit.tmpl", data)
+		return
+	}
+
+	err = app.snippets.Update(snippet.ID, form.input())
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			http.NotFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	app.queueWebhookEvent(app.newSnippetEvent(models.EventSnippetUpdated, snippet))
+
+	app.sessionManager.Put(r.Context(), "flash", "Snippet successfully updated!")
+
+	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%s", snippet.ShortID), http.StatusSeeOther)
+}
+
+func (app *application) snippetForkPost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.snippetFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	// Forking copies the content, so it's only allowed for users who could
+	// read the content anyway.
+	if !app.canReadContent(r, snippet) {
+		http.Redirect(w, r, fmt.Sprintf("/snippet/view/%s", snippet.ShortID), http.StatusSeeOther)
+		return
+	}
+
+	shortID, err := app.snippets.Insert(forkInput(snippet, app.expiryPolicy(r), time.Now()), app.authenticatedUserID(r))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.queueWebhookEvent(app.newSnippetEvent(models.EventSnippetCreated, models.Snippet{ShortID: shortID, UserID: app.authenticatedUserID(r)}))
+
+	app.sessionManager.Put(r.Context(), "flash", "Snippet successfully forked!")
+
+	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%s", shortID), http.StatusSeeOther)
+}
+
+func (app *application) snippetStarPost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.snippetFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	// Only snippets which the user can read can be starred; anything else
+	// is sent to the view page to be unlocked (or burnt) first.
+	if app.canReadContent(r, snippet) {
+		err := app.stars.Star(app.authenticatedUserID(r), snippet.ID)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%s", snippet.ShortID), http.StatusSeeOther)
+}
+
+func (app *application) snippetUnstarPost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.snippetFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.stars.Unstar(app.authenticatedUserID(r), snippet.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%s", snippet.ShortID), http.StatusSeeOther)
+}
+
+func (app *application) snippetCommentPost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.snippetFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	// Only users who can read a snippet can comment on it.
+	if !app.canReadContent(r, snippet) {
+		http.Redirect(w, r, fmt.Sprintf("/snippet/view/%s", snippet.ShortID), http.StatusSeeOther)
+		return
+	}
+
+	var form commentForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form.validate()
+
+	// Replies must answer a comment on the same snippet. The comment may
+	// have been deleted while the reply was being written.
+	if form.ParentID != 0 {
+		parent, err := app.comments.Get(form.ParentID)
+		if err != nil && !errors.Is(err, models.ErrNoRecord) {
+			app.serverError(w, r, err)
+			return
+		}
+		if err != nil || parent.Deleted || parent.SnippetID != snippet.ID {
+			form.AddNonFieldError("The comment you replied to has been deleted")
+			form.ParentID = 0
+		}
+	}
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Snippet = snippet
+		data.Form = form
+		app.renderSnippet(w, r, http.StatusUnprocessableEntity, data)
+		return
+	}
+
+	id, err := app.comments.Insert(snippet.ID, app.authenticatedUserID(r), form.ParentID, form.Content)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Comment successfully posted!")
+
+	http.Redirect(w, r, commentPath(models.Comment{ID: id, SnippetShortID: snippet.ShortID}), http.StatusSeeOther)
+}
+
+func (app *application) commentEdit(w http.ResponseWriter, r *http.Request) {
+	comment, snippet, ok := app.editableComment(w, r)
+	if !ok {
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.Comment = comment
+	data.Form = commentForm{Content: comment.Content}
+
+	app.render(w, r, http.StatusOK, "comment_edit.tmpl", data)
+}
+
+func (app *application) commentEditPost(w http.ResponseWriter, r *http.Request) {
+	comment, snippet, ok := app.editableComment(w, r)
+	if !ok {
+		return
+	}
+
+	var form commentForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form.validate()
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Snippet = snippet
+		data.Comment = comment
+		data.Form = form
+		app.render(w, r, http.StatusUnprocessableEntity, "comment_edit.tmpl", data)
+		return
+	}
+
+	err = app.comments.Update(comment.ID, form.Content)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Comment successfully updated!")
+
+	http.Redirect(w, r, commentPath(comment), http.StatusSeeOther)
+}
+
+// The editableComment() helper works like commentFromPath, but only returns
+// comments which the current user may still edit. Anyone but the author gets
+// a 403 Forbidden response; the author is sent back to the comment with a
+// message once the edit window has passed.
+func (app *application) editableComment(w http.ResponseWriter, r *http.Request) (models.Comment, models.Snippet, bool) {
+	comment, snippet, ok := app.commentFromPath(w, r)
+	if !ok {
+		return models.Comment{}, models.Snippet{}, false
+	}
+
+	policy := app.commentPolicy(r, snippet)
+
+	if !policy.isAuthor(comment) {
+		app.clientError(w, http.StatusForbidden)
+		return models.Comment{}, models.Snippet{}, false
+	}
+
+	if !policy.canEdit(comment) {
+		app.commentWindowPassed(w, r, comment)
+		return models.Comment{}, models.Snippet{}, false
+	}
+
+	return comment, snippet, true
+}
+
+func (app *application) commentDeletePost(w http.ResponseWriter, r *http.Request) {
+	comment, snippet, ok := app.commentFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	// The snippet's owner can delete any comment; authors can only delete
+	// their own while they can still edit them.
+	policy := app.commentPolicy(r, snippet)
+
+	if !policy.canDelete(comment) {
+		if !policy.isAuthor(comment) {
+			app.clientError(w, http.StatusForbidden)
+			return
+		}
+		app.commentWindowPassed(w, r, comment)
+		return
+	}
+
+	err := app.comments.Delete(comment.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			http.NotFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Comment successfully deleted!")
+
+	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%s#comments", snippet.ShortID), http.StatusSeeOther)
+}
+
+func (app *application) snippetCollectPost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.snippetFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	// As with stars, only snippets which the user can read can be added to
+	// a collection.
+	if !app.canReadContent(r, snippet) {
+		http.Redirect(w, r, fmt.Sprintf("/snippet/view/%s", snippet.ShortID), http.StatusSeeOther)
+		return
+	}
+
+	var form snippetCollectForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil || !validator.Matches(form.Collection, validator.ShortIDRX) {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	// Snippets can only be added to the user's own collections.
+	collection, err := app.collections.Get(form.Collection)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.clientError(w, http.StatusBadRequest)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	if !collection.OwnedBy(app.authenticatedUserID(r)) {
+		app.clientError(w, http.StatusForbidden)
+		return
+	}
+
+	err = app.collections.AddSnippet(collection.ID, snippet.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("Snippet added to %s!", collection.Title))
+
+	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%s", snippet.ShortID), http.StatusSeeOther)
+}
+
+func (app *application) snippetDeletePost(w http.ResponseWriter, r *http.Request) {
+	// Only the owner of a snippet is allowed to delete it.
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.snippets.Delete(snippet.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			http.NotFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	app.queueWebhookEvent(app.newSnippetEvent(models.EventSnippetDeleted, snippet))
+
+	app.sessionManager.Put(r.Context(), "flash", "Snippet successfully deleted!")
+
+	http.Redirect(w, r, "/account/view", http.StatusSeeOther)
+}
+
+func (app *application) collectionView(w http.ResponseWriter, r *http.Request) {
+	collection, ok := app.collectionFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	// Private snippets in the collection are only listed for their owners.
+	snippets, err := app.collections.Snippets(collection.ID, app.authenticatedUserID(r))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Collection = collection
+	data.Snippets = snippets
+
+	app.render(w, r, http.StatusOK, "collection.tmpl", data)
+}
+
+func (app *application) collectionCreate(w http.ResponseWriter, r *http.Request) {
+	data := app.newTemplateData(r)
+	data.Form = collectionForm{
+		Visibility: models.VisibilityPublic,
+	}
+
+	app.render(w, r, http.StatusOK, "collection_create.tmpl", data)
+}
+
+func (app *application) collectionCreatePost(w http.ResponseWriter, r *http.Request) {
+	var form collectionForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form.validate()
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, r, http.StatusUnprocessableEntity, "collection_create.tmpl", data)
+		return
+	}
+
+	shortID, err := app.collections.Insert(form.input(), app.authenticatedUserID(r))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Collection successfully created!")
+
+	http.Redirect(w, r, fmt.Sprintf("/collection/%s", shortID), http.StatusSeeOther)
 }
 
-type accountPasswordUpdateForm struct {
-	CurrentPassword         string `form:"currentPassword"`
-	NewPassword             string `form:"newPassword"`
-	NewPasswordConfirmation string `form:"newPasswordConfirmation"`
-	validator.Validator     `form:"-"`
+func (app *application) collectionEdit(w http.ResponseWriter, r *http.Request) {
+	// Only the owner of a collection is allowed to change it.
+	collection, ok := app.ownedCollection(w, r)
+	if !ok {
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Collection = collection
+	data.Form = collectionForm{
+		Title:      collection.Title,
+		Visibility: collection.Visibility,
+	}
+
+	app.render(w, r, http.StatusOK, "collection_edit.tmpl", data)
 }
 
-// define a home handler function which writes a byte slice containing
-// "Hello from Snippetbox" as the response body.
-func (app *application) home(w http.ResponseWriter, r *http.Request) {
+func (app *application) collectionEditPost(w http.ResponseWriter, r *http.Request) {
+	collection, ok := app.ownedCollection(w, r)
+	if !ok {
+		return
+	}
 
-	snippets, err := app.snippets.Latest()
+	var form collectionForm
+
+	err := app.decodePostForm(r, &form)
 	if err != nil {
-		app.serverError(w, r, err)
+		app.clientError(w, http.StatusBadRequest)
 		return
 	}
 
-	data := app.newTemplateData(r)
-	data.Snippets = snippets
+	form.validate()
 
-	app.render(w, r, http.StatusOK, "home.tmpl", data)
-}
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Collection = collection
+		data.Form = form
+		app.render(w, r, http.StatusUnprocessableEntity, "collection_edit.tmpl", data)
+		return
+	}
 
-// add an about page the application
-func (app *application) about(w http.ResponseWriter, r *http.Request) {
+	err = app.collections.Update(collection.ID, form.input())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
 
-	data := app.newTemplateData(r)
-	app.render(w, r, http.StatusOK, "about.tmpl", data)
+	app.sessionManager.Put(r.Context(), "flash", "Collection successfully updated!")
+
+	http.Redirect(w, r, fmt.Sprintf("/collection/%s", collection.ShortID), http.StatusSeeOther)
 }
 
-func (app *application) snippetView(w http.ResponseWriter, r *http.Request) {
-	id, err := strconv.Atoi(r.PathValue("id"))
-	// Extract the value of the id wildcard from the request using r.PathValue()
-	// and try to convert it to an integer using the strconv.Atoi() function. If
-	// it can't be converted to an integer, or the value is less than 1, we
-	// return a 404 page not found response.
-	var invalid bool = (err != nil || id < 1)
-	if invalid {
-		http.NotFound(w, r)
+func (app *application) collectionDeletePost(w http.ResponseWriter, r *http.Request) {
+	collection, ok := app.ownedCollection(w, r)
+	if !ok {
 		return
 	}
 
-	// Use the SnippetModel's Get() method to retrieve the data for a
-	// specific record based on its ID. If no matching record is found,
-	// return a 404 Not Found response.
-	snippet, err := app.snippets.Get(id)
+	err := app.collections.Delete(collection.ID)
 	if err != nil {
 		if errors.Is(err, models.ErrNoRecord) {
 			http.NotFound(w, r)
 		} else {
 			app.serverError(w, r, err)
 		}
-
 		return
 	}
 
-	data := app.newTemplateData(r)
-	data.Snippet = snippet
+	app.sessionManager.Put(r.Context(), "flash", "Collection successfully deleted!")
 
-	app.render(w, r, http.StatusOK, "view.tmpl", data)
+	http.Redirect(w, r, "/account/view", http.StatusSeeOther)
 }
 
-func (app *application) snippetCreate(w http.ResponseWriter, r *http.Request) {
-	data := app.newTemplateData(r)
-	// Initialize a new createSnippetForm instance and pass it to the template.
-	// Notice how this is also a great opportunity to set any default or
-	// 'initial' values for the form --- here we set the initial value for the
-	// snippet expiry to 365 days.
-	data.Form = snippetCreateForm{
-		Expires: 365,
+func (app *application) collectionRemovePost(w http.ResponseWriter, r *http.Request) {
+	collection, snippet, ok := app.collectionSnippet(w, r)
+	if !ok {
+		return
 	}
 
-	app.render(w, r, http.StatusOK, "create.tmpl", data)
+	err := app.collections.RemoveSnippet(collection.ID, snippet.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Snippet removed from the collection.")
+
+	http.Redirect(w, r, fmt.Sprintf("/collection/%s", collection.ShortID), http.StatusSeeOther)
 }
 
-func (app *application) snippetCreatePost(w http.ResponseWriter, r *http.Request) {
-	//First we call r.ParseForm() which adds any data in POST request bodies
-	// to the r.PostForm map. This also works in the same way for PUT and PATCH
-	// requests. If there are any errors, we use our app.ClientError() helper to
-	// send a 400 Bad Request response to the user.
-	// Declare a new empty instance of the snippetCreateForm struct.
-	var form snippetCreateForm
+func (app *application) collectionMovePost(w http.ResponseWriter, r *http.Request) {
+	collection, snippet, ok := app.collectionSnippet(w, r)
+	if !ok {
+		return
+	}
 
-	err := app.decodePostForm(r, &form)
-	if err != nil {
+	// The form has already been parsed by collectionSnippet().
+	offset, ok := moveOffsets[r.PostForm.Get("direction")]
+	if !ok {
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
 
-	// Call the Decode() method of the form decoder, passing in the current
-	// request and *a pointer* to our snippetCreateForm struct. This will
-	// essentially fill our struct with the relevant values from the HTML form.
-	// If there is a problem, we return a 400 Bad Request response to the client.
-	err = app.formDecoder.Decode(&form, r.PostForm)
+	err := app.collections.MoveSnippet(collection.ID, snippet.ID, offset)
 	if err != nil {
-		app.clientError(w, http.StatusBadRequest)
+		if errors.Is(err, models.ErrNoRecord) {
+			http.NotFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
 		return
 	}
 
-	// Because the Validator struct is embedded by the snippetCreateForm struct,
-	// we can call CheckField() directly on it to execute our validation checks.
-	// CheckField() will add the provided key and error message to the
-	// FieldErrors map if the check does not evaluate to true. For example, in
-	// the first line here we "check that the form.Title field is not blank". In
-	// the second, we "check that the form.Title field has a maximum character
-	// length of 100" and so on.
-	form.CheckField(validator.NotBlank(form.Title), "title", "This field cannot be blank")
-	form.CheckField(validator.MaxChars(form.Title, 100), "title", "This field cannot be more than 100 characters long")
-	form.CheckField(validator.NotBlank(form.Content), "content", "This field cannot be blank")
-	form.CheckField(validator.PermittedValue(form.Expires, 1, 7, 365), "expires", "This field must equal 1, 7 or 365")
+	http.Redirect(w, r, fmt.Sprintf("/collection/%s", collection.ShortID), http.StatusSeeOther)
+}
 
-	// Use the Valid() method to see if any of the checks failed. If they did,
-	// then re-render the template passing in the form in the same way as
-	// before.
-	if !form.Valid() {
-		data := app.newTemplateData(r)
-		data.Form = form
-		app.render(w, r, http.StatusUnprocessableEntity, "create.tmpl", data)
+// The collectionSnippet() helper returns the collection identified in the
+// request path, which must be owned by the logged-in user, and the snippet
+// picked by the posted collectionSnippetForm. If either can't be found it
+// sends an error response and the returned bool is false.
+func (app *application) collectionSnippet(w http.ResponseWriter, r *http.Request) (models.Collection, models.Snippet, bool) {
+	collection, ok := app.ownedCollection(w, r)
+	if !ok {
+		return models.Collection{}, models.Snippet{}, false
 	}
 
-	// Pass the data to the SnippetModel.Insert() method, receiving the ID of the new record back.
-	id, err := app.snippets.Insert(form.Title, form.Content, form.Expires)
+	var form collectionSnippetForm
 
-	if err != nil {
-		app.serverError(w, r, err)
-		return
+	err := app.decodePostForm(r, &form)
+	if err != nil || !validator.Matches(form.Snippet, validator.ShortIDRX) {
+		app.clientError(w, http.StatusBadRequest)
+		return models.Collection{}, models.Snippet{}, false
 	}
 
-	// Use the Put() method to add a string value ("Snippet successfully
-	// created!") and the corresponding key ("flash") to the session data.
-	app.sessionManager.Put(r.Context(), "flash", "Snippet successfully created!")
+	snippet, err := app.snippets.Get(form.Snippet)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			http.NotFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return models.Collection{}, models.Snippet{}, false
+	}
 
-	// Redirect the user to the relevant page for the snippet.
-	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d", id), http.StatusSeeOther)
+	return collection, snippet, true
 }
 
 func (app *application) userSignup(w http.ResponseWriter, r *http.Request) {
@@ -318,8 +1457,23 @@ func ping(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("OK"))
 }
 
+// highlightCSS serves the stylesheet for highlighted snippets. It's generated
+// from the highlighting style rather than kept in ui/static, so that the two
+// can't drift apart.
+func (app *application) highlightCSS(w http.ResponseWriter, r *http.Request) {
+	css, err := highlight.CSS()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/css; charset=utf-8")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Write(css)
+}
+
 func (app *application) accountView(w http.ResponseWriter, r *http.Request) {
-	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	userId := app.authenticatedUserID(r)
 
 	user, err := app.users.Get(userId)
 
@@ -329,14 +1483,209 @@ func (app *application) accountView(w http.ResponseWriter, r *http.Request) {
 		} else {
 			app.serverError(w, r, err)
 		}
+		return
+	}
+
+	// Fetch the snippets owned by the user so they can be listed under "My
+	// snippets" on the account page.
+	snippets, err := app.snippets.ByUser(userId)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	collections, err := app.collections.ByUser(userId)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	tokens, err := app.tokens.ByUser(userId)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	webhooks, err := app.webhooks.ByUser(userId)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// Show the latest deliveries to any of the user's webhooks, so that they
+	// can see whether they're working.
+	deliveries, err := app.webhooks.Deliveries(userId, 20)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
 	}
 
 	data := app.newTemplateData(r)
 	data.User = user
+	data.Snippets = snippets
+	data.Collections = collections
+	data.Tokens = tokens
+	data.Webhooks = webhooks
+	data.Deliveries = deliveries
 
 	app.render(w, r, http.StatusOK, "account.tmpl", data)
 }
 
+func (app *application) accountStars(w http.ResponseWriter, r *http.Request) {
+	snippets, err := app.stars.ByUser(app.authenticatedUserID(r))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippets = snippets
+
+	app.render(w, r, http.StatusOK, "stars.tmpl", data)
+}
+
+func (app *application) accountTokenCreate(w http.ResponseWriter, r *http.Request) {
+	data := app.newTemplateData(r)
+	data.Form = tokenForm{
+		Scopes:  []string{models.ScopeSnippetsRead},
+		Expires: "30d",
+	}
+
+	app.render(w, r, http.StatusOK, "token_create.tmpl", data)
+}
+
+func (app *application) accountTokenCreatePost(w http.ResponseWriter, r *http.Request) {
+	var form tokenForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form.validate()
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, r, http.StatusUnprocessableEntity, "token_create.tmpl", data)
+		return
+	}
+
+	token, err := app.tokens.Insert(app.authenticatedUserID(r), form.input(time.Now()))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// Only a hash of the token is stored, so this is the one chance to show
+	// it to the user. It's shown straight away, rather than after a
+	// redirect, so that it never has to be kept in the session.
+	data := app.newTemplateData(r)
+	data.NewToken = token
+
+	app.render(w, r, http.StatusOK, "token_created.tmpl", data)
+}
+
+func (app *application) accountTokenRevokePost(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Users can only revoke their own tokens; anyone else's are reported as
+	// missing.
+	err = app.tokens.Delete(id, app.authenticatedUserID(r))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			http.NotFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Token successfully revoked!")
+
+	http.Redirect(w, r, "/account/view", http.StatusSeeOther)
+}
+
+func (app *application) accountWebhookCreate(w http.ResponseWriter, r *http.Request) {
+	data := app.newTemplateData(r)
+	data.Form = webhookForm{
+		Events: []string{models.EventSnippetCreated},
+	}
+
+	app.render(w, r, http.StatusOK, "webhook_create.tmpl", data)
+}
+
+func (app *application) accountWebhookCreatePost(w http.ResponseWriter, r *http.Request) {
+	var form webhookForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form.validate()
+
+	// Each webhook is sent every matching event, so limit how many a user
+	// can have.
+	webhooks, err := app.webhooks.ByUser(app.authenticatedUserID(r))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if len(webhooks) >= maxWebhooks {
+		form.AddNonFieldError(fmt.Sprintf("You can't have more than %d webhooks", maxWebhooks))
+	}
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, r, http.StatusUnprocessableEntity, "webhook_create.tmpl", data)
+		return
+	}
+
+	webhook, err := app.webhooks.Insert(app.authenticatedUserID(r), form.input())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// As with API tokens, the secret is only shown once, straight away.
+	data := app.newTemplateData(r)
+	data.NewWebhook = webhook
+
+	app.render(w, r, http.StatusOK, "webhook_created.tmpl", data)
+}
+
+func (app *application) accountWebhookDeletePost(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Users can only delete their own webhooks; anyone else's are reported
+	// as missing.
+	err = app.webhooks.Delete(id, app.authenticatedUserID(r))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			http.NotFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Webhook successfully deleted!")
+
+	http.Redirect(w, r, "/account/view", http.StatusSeeOther)
+}
+
 func (app *application) accountPasswordUpdate(w http.ResponseWriter, r *http.Request) {
 
 	data := app.newTemplateData(r)
@@ -368,7 +1717,7 @@ func (app *application) accountPasswordUpdatePost(w http.ResponseWriter, r *http
 		return
 	}
 
-	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	userId := app.authenticatedUserID(r)
 
 	err = app.users.PasswordUpdate(userId, form.CurrentPassword, form.NewPassword)
 	if err != nil {
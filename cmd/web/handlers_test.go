@@ -1,14 +1,19 @@
 package main
 
 import (
+	"archive/zip"
 	"bytes"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/mixnblend/snippetbox/internal/assert"
+	"github.com/mixnblend/snippetbox/internal/models"
+	"github.com/mixnblend/snippetbox/internal/models/mocks"
 )
 
 func TestPing(t *testing.T) {
@@ -76,13 +81,95 @@ func TestSnippetViewE2E(t *testing.T) {
 	}{
 		{
 			name:     "Valid ID",
-			urlPath:  "/snippet/view/1",
+			urlPath:  "/snippet/view/silentPond",
 			wantCode: http.StatusOK,
 			wantBody: "An old silent pond...",
 		},
+		{
+			name:     "Shows author",
+			urlPath:  "/snippet/view/silentPond",
+			wantCode: http.StatusOK,
+			wantBody: "by Alice",
+		},
+		{
+			name:     "Shows expiry countdown",
+			urlPath:  "/snippet/view/silentPond",
+			wantCode: http.StatusOK,
+			wantBody: "data-countdown",
+		},
+		{
+			name:     "Never expires",
+			urlPath:  "/snippet/view/wintryWood",
+			wantCode: http.StatusOK,
+			wantBody: "Never expires",
+		},
+		{
+			name:     "Highlights content",
+			urlPath:  "/snippet/view/silentPond",
+			wantCode: http.StatusOK,
+			wantBody: `<pre class="chroma">`,
+		},
+		{
+			name:     "Links line numbers",
+			urlPath:  "/snippet/view/silentPond",
+			wantCode: http.StatusOK,
+			wantBody: `<span class="ln" id="L1"><a class="lnlinks" href="#L1">1</a></span>`,
+		},
+		{
+			name:     "Shows detected language",
+			urlPath:  "/snippet/view/silentPond",
+			wantCode: http.StatusOK,
+			wantBody: "Plain text",
+		},
+		{
+			name:     "Shows file names",
+			urlPath:  "/snippet/view/wintryWood",
+			wantCode: http.StatusOK,
+			wantBody: "<strong>winds.md</strong>",
+		},
+		{
+			name:     "Shows chosen language of each file",
+			urlPath:  "/snippet/view/wintryWood",
+			wantCode: http.StatusOK,
+			wantBody: "Markdown",
+		},
+		{
+			name:     "Formats Markdown files",
+			urlPath:  "/snippet/view/wintryWood",
+			wantCode: http.StatusOK,
+			wantBody: `<div class='markdown'><h1 id="f2-md-winds">Winds</h1>`,
+		},
+		{
+			name:     "Shows parent of a fork",
+			urlPath:  "/snippet/view/wintryWood",
+			wantCode: http.StatusOK,
+			wantBody: "(forked from <a href='/snippet/view/silentPond'>#silentPond</a>)",
+		},
+		{
+			name:     "Shows fork count",
+			urlPath:  "/snippet/view/silentPond",
+			wantCode: http.StatusOK,
+			wantBody: "<span>2 forks</span>",
+		},
+		{
+			name:     "Offers a zip of several files",
+			urlPath:  "/snippet/view/wintryWood",
+			wantCode: http.StatusOK,
+			wantBody: "<a href='/snippet/zip/wintryWood'>Download ZIP</a>",
+		},
 		{
 			name:     "Non-existent ID",
-			urlPath:  "/snippet/view/2",
+			urlPath:  "/snippet/view/missingSnp",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Private snippet of another user",
+			urlPath:  "/snippet/view/autumnMorn",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Too short ID",
+			urlPath:  "/snippet/view/silent",
 			wantCode: http.StatusNotFound,
 		},
 		{
@@ -123,6 +210,86 @@ func TestSnippetViewE2E(t *testing.T) {
 	}
 }
 
+func TestHighlightCSSE2E(t *testing.T) {
+	endToEndTest(t)
+	// Given ... we have an application with a structured logger which discards everthing.
+	app := newTestApplication(t)
+
+	// And ... we have created a new test server
+	testServer := newTestServer(t, app.routes())
+	defer testServer.Close()
+
+	// When ... we ask for the highlighting stylesheet
+	code, headers, body := testServer.get(t, "/static/css/highlight.css")
+
+	// Then ... it should be served as CSS
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, headers.Get("Content-Type"), "text/css; charset=utf-8")
+	assert.StringContains(t, body, ".chroma")
+}
+
+func TestSnippetLegacyIDE2E(t *testing.T) {
+	endToEndTest(t)
+	// Given ... we have an application with a structured logger which discards everthing.
+	app := newTestApplication(t)
+
+	// And ... we have created a new test server
+	testServer := newTestServer(t, app.routes())
+	defer testServer.Close()
+
+	tests := []struct {
+		name         string
+		urlPath      string
+		wantCode     int
+		wantLocation string
+	}{
+		{
+			name:         "Numeric ID",
+			urlPath:      "/snippet/view/1",
+			wantCode:     http.StatusMovedPermanently,
+			wantLocation: "/snippet/view/silentPond",
+		},
+		{
+			name:         "Numeric ID with sub-path and query",
+			urlPath:      "/snippet/view/1/diff?from=1&to=2",
+			wantCode:     http.StatusMovedPermanently,
+			wantLocation: "/snippet/view/silentPond/diff?from=1&to=2",
+		},
+		{
+			name:     "Non-existent numeric ID",
+			urlPath:  "/snippet/view/2",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Snippet created since short IDs",
+			urlPath:  "/snippet/view/7",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Unlisted snippet",
+			urlPath:  "/snippet/view/5",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Zero ID",
+			urlPath:  "/snippet/view/0",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tableTest := range tests {
+		t.Run(tableTest.name, func(t *testing.T) {
+			// When ... we call an old-style numeric snippet URL
+			code, headers, _ := testServer.get(t, tableTest.urlPath)
+
+			// Then ... we should be permanently redirected to the short ID URL,
+			// if the snippet had a numeric URL and may be found by anyone
+			assert.Equal(t, code, tableTest.wantCode)
+			assert.Equal(t, headers.Get("Location"), tableTest.wantLocation)
+		})
+	}
+}
+
 func TestSnippetCreateE2E(t *testing.T) {
 	endToEndTest(t)
 	// Given ... we have an application with a structured logger which discards everthing.
@@ -168,6 +335,302 @@ func TestSnippetCreateE2E(t *testing.T) {
 		assert.Equal(t, code, http.StatusOK)
 		assert.StringContains(t, body, formTag)
 	})
+
+	// And ... we have extracted the csrf token from the create form
+	_, _, body := testServer.get(t, "/snippet/create")
+	validCSRFToken := extractCSRFToken(t, body)
+
+	postTests := []struct {
+		name      string
+		expires   string
+		expiresAt string
+		language  string
+		wantCode  int
+		wantBody  string
+	}{
+		{
+			name:     "Lifetime in minutes",
+			expires:  "10m",
+			wantCode: http.StatusSeeOther,
+		},
+		{
+			name:     "Lifetime in days",
+			expires:  "30d",
+			wantCode: http.StatusSeeOther,
+		},
+		{
+			name:     "Never expires",
+			expires:  "never",
+			wantCode: http.StatusSeeOther,
+		},
+		{
+			name:      "Absolute expiry time",
+			expiresAt: time.Now().UTC().Add(48 * time.Hour).Format("2006-01-02T15:04"),
+			wantCode:  http.StatusSeeOther,
+		},
+		{
+			name:     "Invalid lifetime",
+			expires:  "soon",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "This field must be a lifetime such as 10m, 6h or 30d, or never",
+		},
+		{
+			name:      "Expiry time in the past",
+			expiresAt: "2001-01-01T00:00",
+			wantCode:  http.StatusUnprocessableEntity,
+			wantBody:  "This field must be in the future",
+		},
+		{
+			name:     "Chosen language",
+			expires:  "7d",
+			language: "go",
+			wantCode: http.StatusSeeOther,
+		},
+		{
+			name:     "Unknown language",
+			expires:  "7d",
+			language: "cobol",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "This field must be one of the listed languages",
+		},
+	}
+
+	for _, tableTest := range postTests {
+		t.Run(tableTest.name, func(t *testing.T) {
+			// Given ... we have a snippet form with the expiry fields set
+			form := url.Values{}
+			form.Add("title", "O snail")
+			form.Add("files[0].content", "O snail\nClimb Mount Fuji,")
+			form.Add("expires", tableTest.expires)
+			form.Add("expiresAt", tableTest.expiresAt)
+			form.Add("files[0].language", tableTest.language)
+			form.Add("visibility", "public")
+			form.Add("csrf_token", validCSRFToken)
+
+			// When ... we post it to the create route
+			code, _, body := testServer.postForm(t, "/snippet/create", form)
+
+			// Then ... the HTTP status code should be returned as expected
+			assert.Equal(t, code, tableTest.wantCode)
+
+			// And ... any validation error should be shown
+			if tableTest.wantBody != "" {
+				assert.StringContains(t, body, tableTest.wantBody)
+			}
+		})
+	}
+
+	t.Run("Add file", func(t *testing.T) {
+		// Given ... we have a snippet form with one file
+		form := url.Values{}
+		form.Add("title", "Compose")
+		form.Add("files[0].name", "Dockerfile")
+		form.Add("files[0].content", "FROM golang")
+		form.Add("expires", "7d")
+		form.Add("visibility", "public")
+		form.Add("csrf_token", validCSRFToken)
+
+		// When ... we press the "Add file" button
+		form.Add("addFile", "true")
+		code, _, body := testServer.postForm(t, "/snippet/create", form)
+
+		// Then ... the form should be shown again with a second, empty file
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, body, "<input type='text' name='files[0].name' value='Dockerfile'")
+		assert.StringContains(t, body, "<textarea name='files[1].content'></textarea>")
+	})
+
+	t.Run("Several files", func(t *testing.T) {
+		// Given ... we have a snippet form with two files
+		form := url.Values{}
+		form.Add("title", "Compose")
+		form.Add("files[0].name", "Dockerfile")
+		form.Add("files[0].content", "FROM golang")
+		form.Add("files[1].name", "compose.yaml")
+		form.Add("files[1].content", "services:")
+		form.Add("expires", "7d")
+		form.Add("visibility", "public")
+		form.Add("csrf_token", validCSRFToken)
+
+		// When ... we post it to the create route
+		code, _, _ := testServer.postForm(t, "/snippet/create", form)
+
+		// Then ... the snippet should be created
+		assert.Equal(t, code, http.StatusSeeOther)
+	})
+}
+
+func TestSnippetCreateUnverifiedE2E(t *testing.T) {
+	endToEndTest(t)
+	// Given ... we have an application which limits snippets from unverified
+	// accounts to a week
+	app := newTestApplication(t)
+
+	// And ... we have created a new test server
+	testServer := newTestServer(t, app.routes())
+	defer testServer.Close()
+
+	// And ... we have logged in as a user whose account hasn't been verified
+	testServer.loginAs(t, mocks.UnverifiedUserCredentials)
+
+	// When ... we view the create form
+	code, _, body := testServer.get(t, "/snippet/create")
+	validCSRFToken := extractCSRFToken(t, body)
+
+	// Then ... the expiry should start at the longest lifetime allowed, and
+	// never expiring shouldn't be suggested
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, "name='expires' list='expires-suggestions' value='1w'")
+	assert.Equal(t, strings.Contains(body, "<option value='never'>"), false)
+
+	postTests := []struct {
+		name      string
+		expires   string
+		expiresAt string
+		wantCode  int
+		wantBody  string
+	}{
+		{
+			name:     "Within the limit",
+			expires:  "7d",
+			wantCode: http.StatusSeeOther,
+		},
+		{
+			name:     "Lifetime over the limit",
+			expires:  "30d",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "Snippets cannot be kept for more than 1w",
+		},
+		{
+			name:      "Expiry time over the limit",
+			expiresAt: time.Now().UTC().Add(30 * 24 * time.Hour).Format("2006-01-02T15:04"),
+			wantCode:  http.StatusUnprocessableEntity,
+			wantBody:  "Snippets cannot be kept for more than 1w",
+		},
+		{
+			name:     "Never expires",
+			expires:  "never",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "Only verified accounts can create snippets which never expire",
+		},
+	}
+
+	for _, tableTest := range postTests {
+		t.Run(tableTest.name, func(t *testing.T) {
+			// Given ... we have a snippet form with the expiry fields set
+			form := url.Values{}
+			form.Add("title", "O snail")
+			form.Add("files[0].content", "O snail\nClimb Mount Fuji,")
+			form.Add("expires", tableTest.expires)
+			form.Add("expiresAt", tableTest.expiresAt)
+			form.Add("visibility", "public")
+			form.Add("csrf_token", validCSRFToken)
+
+			// When ... we post it to the create route
+			code, _, body := testServer.postForm(t, "/snippet/create", form)
+
+			// Then ... snippets kept for longer than the limit should be refused
+			assert.Equal(t, code, tableTest.wantCode)
+			if tableTest.wantBody != "" {
+				assert.StringContains(t, body, tableTest.wantBody)
+			}
+		})
+	}
+}
+
+func TestSnippetPreviewE2E(t *testing.T) {
+	endToEndTest(t)
+	// Given ... we have an application with a structured logger which discards everthing.
+	app := newTestApplication(t)
+
+	// And ... we have created a new test server
+	testServer := newTestServer(t, app.routes())
+	defer testServer.Close()
+
+	// And ... we have extracted a csrf token
+	_, _, body := testServer.get(t, "/user/login")
+	validCSRFToken := extractCSRFToken(t, body)
+
+	// And ... we have a snippet form with a Markdown file and a Go file
+	newForm := func(tab string) url.Values {
+		form := url.Values{}
+		form.Add("title", "Runbook")
+		form.Add("files[0].name", "README.md")
+		form.Add("files[0].language", "markdown")
+		form.Add("files[0].content", "# Restarting\n\n| Step | Command |\n| --- | --- |\n| 1 | `make restart` |\n\n<script>alert(1)</script>")
+		form.Add("files[1].name", "main.go")
+		form.Add("files[1].content", "package main")
+		form.Add("expires", "7d")
+		form.Add("visibility", "public")
+		form.Add("tab", tab)
+		form.Add("csrf_token", validCSRFToken)
+		return form
+	}
+
+	t.Run("Unauthenticated", func(t *testing.T) {
+		// When ... we try to preview a snippet without logging in
+		code, headers, _ := testServer.postForm(t, "/snippet/preview", newForm("preview"))
+
+		// Then ... we should be sent to the login page
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, headers.Get("Location"), "/user/login")
+	})
+
+	// And ... we have logged the user in
+	testServer.login(t)
+	_, _, body = testServer.get(t, "/snippet/create")
+	validCSRFToken = extractCSRFToken(t, body)
+
+	t.Run("Preview tab", func(t *testing.T) {
+		// When ... we press the "Preview" tab
+		code, _, body := testServer.postForm(t, "/snippet/preview", newForm("preview"))
+
+		// Then ... the form should be shown again with the Markdown rendered
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, body, "<div class='markdown'><h1 id=\"md-restarting\">Restarting</h1>")
+		assert.StringContains(t, body, "<td><code>make restart</code></td>")
+
+		// And ... the other files should be highlighted as code
+		assert.StringContains(t, body, "<strong>main.go</strong>")
+
+		// And ... raw HTML in the Markdown should be left out
+		assert.Equal(t, strings.Contains(body, "<script>alert(1)</script>"), false)
+
+		// And ... the fields should be kept, but hidden
+		assert.StringContains(t, body, "<div hidden>")
+		assert.StringContains(t, body, "<input type='text' name='title' value='Runbook'>")
+	})
+
+	t.Run("Write tab", func(t *testing.T) {
+		// When ... we press the "Write" tab
+		code, _, body := testServer.postForm(t, "/snippet/preview", newForm("write"))
+
+		// Then ... the fields should be shown again, without a preview
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, body, "<input type='text' name='title' value='Runbook'>")
+		assert.Equal(t, strings.Contains(body, "<div hidden>"), false)
+		assert.Equal(t, strings.Contains(body, "<div class='markdown'>"), false)
+	})
+
+	t.Run("Preview tab on the edit page", func(t *testing.T) {
+		// When ... we press the "Preview" tab while editing one of our snippets
+		code, _, body := testServer.postForm(t, "/snippet/preview/silentPond", newForm("preview"))
+
+		// Then ... the edit form should be shown again with the Markdown rendered
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, body, "<form action='/snippet/edit/silentPond' method='POST'>")
+		assert.StringContains(t, body, "formaction='/snippet/preview/silentPond'")
+		assert.StringContains(t, body, "<div class='markdown'><h1 id=\"md-restarting\">Restarting</h1>")
+		assert.Equal(t, strings.Contains(body, "<form action='/snippet/create' method='POST'>"), false)
+	})
+
+	t.Run("Preview of someone else's snippet", func(t *testing.T) {
+		// When ... we try to preview an edit of a snippet we don't own
+		code, _, _ := testServer.postForm(t, "/snippet/preview/wintryWood", newForm("preview"))
+
+		// Then ... we should be forbidden
+		assert.Equal(t, code, http.StatusForbidden)
+	})
 }
 
 func TestUserSignupE2E(t *testing.T) {
@@ -274,3 +737,1988 @@ func TestUserSignupE2E(t *testing.T) {
 		})
 	}
 }
+
+func TestAccountViewE2E(t *testing.T) {
+	endToEndTest(t)
+	// Given ... we have an application with a structured logger which discards everthing.
+	app := newTestApplication(t)
+
+	// And ... we have created a new test server
+	testServer := newTestServer(t, app.routes())
+	defer testServer.Close()
+
+	t.Run("Unauthenticated", func(t *testing.T) {
+		// When ... we call our get /account/view route.
+		code, headers, _ := testServer.get(t, "/account/view")
+
+		// Then ... we should be redirected to the login page
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, headers.Get("Location"), "/user/login")
+	})
+
+	t.Run("Authenticated", func(t *testing.T) {
+		// And ... we have logged the user in
+		testServer.login(t)
+
+		// When ... we call our get /account/view route.
+		code, _, body := testServer.get(t, "/account/view")
+
+		// Then ... the 200 status code should be returned as expected
+		assert.Equal(t, code, http.StatusOK)
+
+		// And ... the user's own snippets should be listed
+		assert.StringContains(t, body, "My Snippets")
+		assert.StringContains(t, body, "<a href='/snippet/view/silentPond'>An old silent pond</a>")
+	})
+}
+
+func TestAccountTokensE2E(t *testing.T) {
+	endToEndTest(t)
+	// Given ... we have an application with a structured logger which discards everthing.
+	app := newTestApplication(t)
+
+	// And ... we have created a new test server
+	testServer := newTestServer(t, app.routes())
+	defer testServer.Close()
+
+	t.Run("Unauthenticated", func(t *testing.T) {
+		// When ... we try to create a token without logging in
+		code, headers, _ := testServer.get(t, "/account/tokens/create")
+
+		// Then ... we should be redirected to the login page
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, headers.Get("Location"), "/user/login")
+	})
+
+	// And ... we have logged the user in
+	testServer.login(t)
+	_, _, body := testServer.get(t, "/account/tokens/create")
+	validCSRFToken := extractCSRFToken(t, body)
+
+	t.Run("List", func(t *testing.T) {
+		// When ... we view the account page
+		code, _, body := testServer.get(t, "/account/view")
+
+		// Then ... the user's tokens should be listed with their scopes
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, body, "<td>CI</td>")
+		assert.StringContains(t, body, "<td>snippets:read, snippets:write</td>")
+		assert.StringContains(t, body, "<form action='/account/tokens/revoke/2' method='POST'>")
+	})
+
+	createTests := []struct {
+		name     string
+		tokName  string
+		scopes   []string
+		expires  string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "Create",
+			tokName:  "CI",
+			scopes:   []string{"snippets:read", "snippets:write"},
+			expires:  "90d",
+			wantCode: http.StatusOK,
+			wantBody: "<pre class='token'><code>" + mocks.NewToken + "</code></pre>",
+		},
+		{
+			name:     "No name",
+			scopes:   []string{"snippets:read"},
+			expires:  "90d",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "This field cannot be blank",
+		},
+		{
+			name:     "No scopes",
+			tokName:  "CI",
+			expires:  "90d",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "Choose at least one scope",
+		},
+		{
+			name:     "Unknown scope",
+			tokName:  "CI",
+			scopes:   []string{"users:admin"},
+			expires:  "90d",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "Choose from the listed scopes",
+		},
+		{
+			name:     "Never expires",
+			tokName:  "CI",
+			scopes:   []string{"snippets:read"},
+			expires:  "never",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "Choose one of the listed lifetimes",
+		},
+	}
+
+	for _, tableTest := range createTests {
+		t.Run(tableTest.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("name", tableTest.tokName)
+			for _, scope := range tableTest.scopes {
+				form.Add("scopes", scope)
+			}
+			form.Add("expires", tableTest.expires)
+			form.Add("csrf_token", validCSRFToken)
+
+			// When ... we post the new token form
+			code, headers, body := testServer.postForm(t, "/account/tokens/create", form)
+
+			// Then ... the token should be shown once, or the errors in the form
+			assert.Equal(t, code, tableTest.wantCode)
+			assert.StringContains(t, body, tableTest.wantBody)
+
+			// And ... the page should not be cached
+			assert.Equal(t, headers.Get("Cache-Control"), "no-store")
+		})
+	}
+
+	revokeTests := []struct {
+		name         string
+		urlPath      string
+		wantCode     int
+		wantLocation string
+	}{
+		{
+			name:         "Revoke",
+			urlPath:      "/account/tokens/revoke/1",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/account/view",
+		},
+		{
+			name:     "Someone else's token",
+			urlPath:  "/account/tokens/revoke/3",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Invalid ID",
+			urlPath:  "/account/tokens/revoke/abc",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tableTest := range revokeTests {
+		t.Run(tableTest.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("csrf_token", validCSRFToken)
+
+			// When ... we revoke a token
+			code, headers, _ := testServer.postForm(t, tableTest.urlPath, form)
+
+			// Then ... the status code and redirect should be as expected
+			assert.Equal(t, code, tableTest.wantCode)
+			assert.Equal(t, headers.Get("Location"), tableTest.wantLocation)
+		})
+	}
+}
+
+func TestAccountWebhooksE2E(t *testing.T) {
+	endToEndTest(t)
+	// Given ... we have an application with a structured logger which discards everthing.
+	app := newTestApplication(t)
+
+	// And ... we have created a new test server
+	testServer := newTestServer(t, app.routes())
+	defer testServer.Close()
+
+	t.Run("Unauthenticated", func(t *testing.T) {
+		// When ... we try to add a webhook without logging in
+		code, headers, _ := testServer.get(t, "/account/webhooks/create")
+
+		// Then ... we should be redirected to the login page
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, headers.Get("Location"), "/user/login")
+	})
+
+	// And ... we have logged the user in
+	testServer.login(t)
+	_, _, body := testServer.get(t, "/account/webhooks/create")
+	validCSRFToken := extractCSRFToken(t, body)
+
+	t.Run("List", func(t *testing.T) {
+		// When ... we view the account page
+		code, _, body := testServer.get(t, "/account/view")
+
+		// Then ... the user's webhooks should be listed with their events
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, body, "<td>https://chat.example.com/hooks/snippets</td>")
+		assert.StringContains(t, body, "<td>snippet.created, snippet.deleted</td>")
+		assert.StringContains(t, body, "<form action='/account/webhooks/delete/1' method='POST'>")
+
+		// And ... the failed delivery should be shown with its response status
+		assert.StringContains(t, body, "<td>failed</td>")
+		assert.StringContains(t, body, "<td>502</td>")
+	})
+
+	createTests := []struct {
+		name     string
+		hookURL  string
+		events   []string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "Create",
+			hookURL:  "https://example.com/hooks",
+			events:   []string{"snippet.created", "snippet.expired"},
+			wantCode: http.StatusOK,
+			wantBody: "<pre class='token'><code>" + mocks.NewWebhookSecret + "</code></pre>",
+		},
+		{
+			name:     "No URL",
+			events:   []string{"snippet.created"},
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "This field cannot be blank",
+		},
+		{
+			name:     "Not a web URL",
+			hookURL:  "ftp://example.com/hooks",
+			events:   []string{"snippet.created"},
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "This field must be an http:// or https:// URL",
+		},
+		{
+			name:     "No events",
+			hookURL:  "https://example.com/hooks",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "Choose at least one event",
+		},
+		{
+			name:     "Unknown event",
+			hookURL:  "https://example.com/hooks",
+			events:   []string{"user.created"},
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "Choose from the listed events",
+		},
+	}
+
+	for _, tableTest := range createTests {
+		t.Run(tableTest.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("url", tableTest.hookURL)
+			for _, event := range tableTest.events {
+				form.Add("events", event)
+			}
+			form.Add("csrf_token", validCSRFToken)
+
+			// When ... we post the new webhook form
+			code, headers, body := testServer.postForm(t, "/account/webhooks/create", form)
+
+			// Then ... the secret should be shown once, or the errors in the form
+			assert.Equal(t, code, tableTest.wantCode)
+			assert.StringContains(t, body, tableTest.wantBody)
+
+			// And ... the page should not be cached
+			assert.Equal(t, headers.Get("Cache-Control"), "no-store")
+		})
+	}
+
+	deleteTests := []struct {
+		name         string
+		urlPath      string
+		wantCode     int
+		wantLocation string
+	}{
+		{
+			name:         "Delete",
+			urlPath:      "/account/webhooks/delete/1",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/account/view",
+		},
+		{
+			name:     "Someone else's webhook",
+			urlPath:  "/account/webhooks/delete/3",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Invalid ID",
+			urlPath:  "/account/webhooks/delete/abc",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tableTest := range deleteTests {
+		t.Run(tableTest.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("csrf_token", validCSRFToken)
+
+			// When ... we delete a webhook
+			code, headers, _ := testServer.postForm(t, tableTest.urlPath, form)
+
+			// Then ... the status code and redirect should be as expected
+			assert.Equal(t, code, tableTest.wantCode)
+			assert.Equal(t, headers.Get("Location"), tableTest.wantLocation)
+		})
+	}
+}
+
+func TestSnippetEditE2E(t *testing.T) {
+	endToEndTest(t)
+	// Given ... we have an application with a structured logger which discards everthing.
+	app := newTestApplication(t)
+
+	// And ... we have created a new test server
+	testServer := newTestServer(t, app.routes())
+	defer testServer.Close()
+
+	t.Run("Unauthenticated", func(t *testing.T) {
+		// When ... we call our get /snippet/edit route.
+		code, headers, _ := testServer.get(t, "/snippet/edit/silentPond")
+
+		// Then ... we should be redirected to the login page
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, headers.Get("Location"), "/user/login")
+	})
+
+	// And ... we have logged the user in
+	testServer.login(t)
+
+	getTests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "Owner",
+			urlPath:  "/snippet/edit/silentPond",
+			wantCode: http.StatusOK,
+			wantBody: "<form action='/snippet/edit/silentPond' method='POST'>",
+		},
+		{
+			name:     "Not owner",
+			urlPath:  "/snippet/edit/wintryWood",
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "Non-existent ID",
+			urlPath:  "/snippet/edit/missingSnp",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tableTest := range getTests {
+		t.Run(tableTest.name, func(t *testing.T) {
+			// When ... we call our get /snippet/edit route.
+			code, _, body := testServer.get(t, tableTest.urlPath)
+
+			// Then ... the HTTP status code should be returned as expected
+			assert.Equal(t, code, tableTest.wantCode)
+
+			// And ... the body of the response should be returned as expected.
+			if tableTest.wantBody != "" {
+				assert.StringContains(t, body, tableTest.wantBody)
+			}
+		})
+	}
+
+	// And ... we have extracted the csrf token from the edit form
+	_, _, body := testServer.get(t, "/snippet/edit/silentPond")
+	validCSRFToken := extractCSRFToken(t, body)
+
+	postTests := []struct {
+		name     string
+		urlPath  string
+		title    string
+		wantCode int
+	}{
+		{
+			name:     "Valid submission",
+			urlPath:  "/snippet/edit/silentPond",
+			title:    "An old silent pond",
+			wantCode: http.StatusSeeOther,
+		},
+		{
+			name:     "Empty title",
+			urlPath:  "/snippet/edit/silentPond",
+			title:    "",
+			wantCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "Not owner",
+			urlPath:  "/snippet/edit/wintryWood",
+			title:    "Over the wintry forest",
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "Private snippet of another user",
+			urlPath:  "/snippet/edit/autumnMorn",
+			title:    "First autumn morning",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Non-existent ID",
+			urlPath:  "/snippet/edit/missingSnp",
+			title:    "Missing",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tableTest := range postTests {
+		t.Run(tableTest.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("title", tableTest.title)
+			form.Add("files[0].content", "A frog jumps into the pond")
+			form.Add("expires", "7d")
+			form.Add("visibility", "unlisted")
+			form.Add("csrf_token", validCSRFToken)
+
+			code, _, _ := testServer.postForm(t, tableTest.urlPath, form)
+
+			assert.Equal(t, code, tableTest.wantCode)
+		})
+	}
+}
+
+func TestSnippetDeleteE2E(t *testing.T) {
+	endToEndTest(t)
+	// Given ... we have an application with a structured logger which discards everthing.
+	app := newTestApplication(t)
+
+	// And ... we have created a new test server
+	testServer := newTestServer(t, app.routes())
+	defer testServer.Close()
+
+	// And ... we have logged the user in and extracted a csrf token
+	testServer.login(t)
+	_, _, body := testServer.get(t, "/snippet/create")
+	validCSRFToken := extractCSRFToken(t, body)
+
+	tests := []struct {
+		name         string
+		urlPath      string
+		wantCode     int
+		wantLocation string
+	}{
+		{
+			name:         "Owner",
+			urlPath:      "/snippet/delete/silentPond",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/account/view",
+		},
+		{
+			name:     "Not owner",
+			urlPath:  "/snippet/delete/wintryWood",
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "Non-existent ID",
+			urlPath:  "/snippet/delete/missingSnp",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tableTest := range tests {
+		t.Run(tableTest.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("csrf_token", validCSRFToken)
+
+			code, headers, _ := testServer.postForm(t, tableTest.urlPath, form)
+
+			assert.Equal(t, code, tableTest.wantCode)
+			assert.Equal(t, headers.Get("Location"), tableTest.wantLocation)
+		})
+	}
+}
+
+func TestSnippetRawE2E(t *testing.T) {
+	endToEndTest(t)
+	// Given ... we have an application with a structured logger which discards everthing.
+	app := newTestApplication(t)
+
+	// And ... we have created a new test server
+	testServer := newTestServer(t, app.routes())
+	defer testServer.Close()
+
+	tests := []struct {
+		name            string
+		urlPath         string
+		wantCode        int
+		wantBody        string
+		wantLocation    string
+		wantDisposition string
+	}{
+		{
+			name:     "Raw",
+			urlPath:  "/snippet/raw/silentPond",
+			wantCode: http.StatusOK,
+			wantBody: "An old silent pond...",
+		},
+		{
+			name:            "Download",
+			urlPath:         "/snippet/download/silentPond",
+			wantCode:        http.StatusOK,
+			wantBody:        "An old silent pond...",
+			wantDisposition: `attachment; filename=an-old-silent-pond.txt`,
+		},
+		{
+			name:     "Raw file by name",
+			urlPath:  "/snippet/raw/wintryWood?file=winds.md",
+			wantCode: http.StatusOK,
+			wantBody: "# Winds\n\nwinds howl in rage...",
+		},
+		{
+			name:            "Download file by name",
+			urlPath:         "/snippet/download/wintryWood?file=winds.md",
+			wantCode:        http.StatusOK,
+			wantBody:        "# Winds\n\nwinds howl in rage...",
+			wantDisposition: `attachment; filename=winds.md`,
+		},
+		{
+			name:     "Non-existent file",
+			urlPath:  "/snippet/raw/wintryWood?file=missing.txt",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Non-existent ID",
+			urlPath:  "/snippet/raw/missingSnp",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:         "Zip of locked snippet",
+			urlPath:      "/snippet/zip/contractor",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/snippet/view/contractor",
+		},
+		{
+			name:     "Private snippet of another user",
+			urlPath:  "/snippet/download/autumnMorn",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:         "Locked snippet",
+			urlPath:      "/snippet/raw/contractor",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/snippet/view/contractor",
+		},
+		{
+			name:         "Burn after reading snippet",
+			urlPath:      "/snippet/download/deployTokn",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/snippet/view/deployTokn",
+		},
+	}
+
+	for _, tableTest := range tests {
+		t.Run(tableTest.name, func(t *testing.T) {
+			// When ... we call our path
+			code, headers, body := testServer.get(t, tableTest.urlPath)
+
+			// Then ... the HTTP status code should be returned as expected
+			assert.Equal(t, code, tableTest.wantCode)
+
+			// And ... a successful response should be the bare content as
+			// plain text
+			if code == http.StatusOK {
+				assert.Equal(t, headers.Get("Content-Type"), "text/plain; charset=utf-8")
+				assert.Equal(t, body, tableTest.wantBody)
+				assert.Equal(t, headers.Get("Content-Disposition"), tableTest.wantDisposition)
+			}
+
+			if tableTest.wantLocation != "" {
+				assert.Equal(t, headers.Get("Location"), tableTest.wantLocation)
+			}
+		})
+	}
+
+	t.Run("Zip", func(t *testing.T) {
+		// When ... we download all the files of a snippet
+		code, headers, body := testServer.get(t, "/snippet/zip/wintryWood")
+
+		// Then ... we should get a zip archive named after the snippet
+		assert.Equal(t, code, http.StatusOK)
+		assert.Equal(t, headers.Get("Content-Type"), "application/zip")
+		assert.Equal(t, headers.Get("Content-Disposition"), "attachment; filename=over-the-wintry-forest.zip")
+
+		// And ... it should hold both files
+		zr, err := zip.NewReader(strings.NewReader(body), int64(len(body)))
+		assert.NilError(t, err)
+		assert.Equal(t, len(zr.File), 2)
+		assert.Equal(t, zr.File[1].Name, "winds.md")
+	})
+
+	t.Run("HEAD", func(t *testing.T) {
+		// When ... we make a HEAD request
+		code, headers, body := testServer.request(t, http.MethodHead, "/snippet/raw/silentPond", nil)
+
+		// Then ... the headers should be sent without the content
+		assert.Equal(t, code, http.StatusOK)
+		assert.Equal(t, headers.Get("Content-Length"), "21")
+		assert.Equal(t, body, "")
+	})
+
+	t.Run("Conditional request", func(t *testing.T) {
+		// Given ... we have the ETag of the content
+		_, headers, _ := testServer.get(t, "/snippet/raw/silentPond")
+		etag := headers.Get("ETag")
+
+		// When ... we ask for the content again if it has changed
+		code, _, body := testServer.request(t, http.MethodGet, "/snippet/raw/silentPond",
+			http.Header{"If-None-Match": {etag}})
+
+		// Then ... we should be told it hasn't
+		assert.Equal(t, code, http.StatusNotModified)
+		assert.Equal(t, body, "")
+
+		// And ... a stale ETag should get the content
+		code, _, _ = testServer.request(t, http.MethodGet, "/snippet/raw/silentPond",
+			http.Header{"If-None-Match": {`"stale"`}})
+		assert.Equal(t, code, http.StatusOK)
+	})
+}
+
+func TestSnippetForkE2E(t *testing.T) {
+	endToEndTest(t)
+	// Given ... we have an application with a structured logger which discards everthing.
+	app := newTestApplication(t)
+
+	// And ... we have created a new test server
+	testServer := newTestServer(t, app.routes())
+	defer testServer.Close()
+
+	// And ... we have extracted a csrf token
+	_, _, body := testServer.get(t, "/user/login")
+	validCSRFToken := extractCSRFToken(t, body)
+
+	t.Run("Unauthenticated", func(t *testing.T) {
+		form := url.Values{}
+		form.Add("csrf_token", validCSRFToken)
+
+		// When ... we try to fork a snippet without logging in
+		code, headers, _ := testServer.postForm(t, "/snippet/fork/wintryWood", form)
+
+		// Then ... we should be sent to the login page
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, headers.Get("Location"), "/user/login")
+	})
+
+	// And ... we have logged the user in
+	testServer.login(t)
+	_, _, body = testServer.get(t, "/snippet/create")
+	validCSRFToken = extractCSRFToken(t, body)
+
+	tests := []struct {
+		name         string
+		urlPath      string
+		wantCode     int
+		wantLocation string
+	}{
+		{
+			name:         "Another user's snippet",
+			urlPath:      "/snippet/fork/wintryWood",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/snippet/view/newSnippet",
+		},
+		{
+			name:         "Own snippet",
+			urlPath:      "/snippet/fork/silentPond",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/snippet/view/newSnippet",
+		},
+		{
+			name:     "Private snippet of another user",
+			urlPath:  "/snippet/fork/autumnMorn",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:         "Locked snippet",
+			urlPath:      "/snippet/fork/contractor",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/snippet/view/contractor",
+		},
+		{
+			name:     "Non-existent ID",
+			urlPath:  "/snippet/fork/missingSnp",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tableTest := range tests {
+		t.Run(tableTest.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("csrf_token", validCSRFToken)
+
+			// When ... we fork the snippet
+			code, headers, _ := testServer.postForm(t, tableTest.urlPath, form)
+
+			// Then ... we should be sent to the new snippet, or refused
+			assert.Equal(t, code, tableTest.wantCode)
+			assert.Equal(t, headers.Get("Location"), tableTest.wantLocation)
+		})
+	}
+}
+
+func TestSnippetForkUnverifiedE2E(t *testing.T) {
+	endToEndTest(t)
+	// Given ... we have an application which limits snippets from unverified
+	// accounts to a week, and records the snippets inserted
+	app := newTestApplication(t)
+	snippets := &insertRecorder{}
+	app.snippets = snippets
+
+	// And ... we have created a new test server
+	testServer := newTestServer(t, app.routes())
+	defer testServer.Close()
+
+	// And ... we have logged in as a user whose account hasn't been verified
+	testServer.loginAs(t, mocks.UnverifiedUserCredentials)
+	_, _, body := testServer.get(t, "/snippet/create")
+	form := url.Values{}
+	form.Add("csrf_token", extractCSRFToken(t, body))
+
+	// When ... we fork a snippet which never expires
+	start := time.Now()
+	code, headers, _ := testServer.postForm(t, "/snippet/fork/wintryWood", form)
+
+	// Then ... the fork should be created
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, headers.Get("Location"), "/snippet/view/newSnippet")
+
+	// And ... it should expire after the longest lifetime allowed
+	expires := snippets.input.Expires
+	if expires.Before(start.Add(app.maxUnverifiedLifetime)) || expires.After(time.Now().Add(app.maxUnverifiedLifetime)) {
+		t.Errorf("got expiry %v; want %v after the fork", expires, app.maxUnverifiedLifetime)
+	}
+}
+
+// insertRecorder is the mock snippet model, except that it remembers the
+// input of the last snippet inserted.
+type insertRecorder struct {
+	mocks.SnippetModel
+	input models.SnippetInput
+}
+
+func (m *insertRecorder) Insert(input models.SnippetInput, userID int) (string, error) {
+	m.input = input
+	return m.SnippetModel.Insert(input, userID)
+}
+
+func TestSnippetStarE2E(t *testing.T) {
+	endToEndTest(t)
+	// Given ... we have an application with a structured logger which discards everthing.
+	app := newTestApplication(t)
+
+	// And ... we have created a new test server
+	testServer := newTestServer(t, app.routes())
+	defer testServer.Close()
+
+	// And ... we have extracted a csrf token
+	_, _, body := testServer.get(t, "/user/login")
+	validCSRFToken := extractCSRFToken(t, body)
+
+	t.Run("Unauthenticated", func(t *testing.T) {
+		form := url.Values{}
+		form.Add("csrf_token", validCSRFToken)
+
+		// When ... we try to star a snippet without logging in
+		code, headers, _ := testServer.postForm(t, "/snippet/star/wintryWood", form)
+
+		// Then ... we should be sent to the login page
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, headers.Get("Location"), "/user/login")
+
+		// And ... the starred snippets page should also require a login
+		code, headers, _ = testServer.get(t, "/account/stars")
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, headers.Get("Location"), "/user/login")
+	})
+
+	// And ... we have logged the user in
+	testServer.login(t)
+	_, _, body = testServer.get(t, "/snippet/create")
+	validCSRFToken = extractCSRFToken(t, body)
+
+	tests := []struct {
+		name         string
+		urlPath      string
+		wantCode     int
+		wantLocation string
+	}{
+		{
+			name:         "Star",
+			urlPath:      "/snippet/star/wintryWood",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/snippet/view/wintryWood",
+		},
+		{
+			name:         "Unstar",
+			urlPath:      "/snippet/unstar/silentPond",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/snippet/view/silentPond",
+		},
+		{
+			name:         "Locked snippet",
+			urlPath:      "/snippet/star/contractor",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/snippet/view/contractor",
+		},
+		{
+			name:     "Private snippet of another user",
+			urlPath:  "/snippet/star/autumnMorn",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Non-existent ID",
+			urlPath:  "/snippet/star/missingSnp",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tableTest := range tests {
+		t.Run(tableTest.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("csrf_token", validCSRFToken)
+
+			// When ... we star or unstar the snippet
+			code, headers, _ := testServer.postForm(t, tableTest.urlPath, form)
+
+			// Then ... we should be sent back to the snippet, or refused
+			assert.Equal(t, code, tableTest.wantCode)
+			assert.Equal(t, headers.Get("Location"), tableTest.wantLocation)
+		})
+	}
+
+	t.Run("View", func(t *testing.T) {
+		// When ... we view a snippet the user has starred
+		code, _, body := testServer.get(t, "/snippet/view/silentPond")
+
+		// Then ... the star count and an unstar button should be shown
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, body, "<span>3 stars</span>")
+		assert.StringContains(t, body, "<form action='/snippet/unstar/silentPond' method='POST'>")
+	})
+
+	t.Run("Starred snippets", func(t *testing.T) {
+		// When ... we view the user's starred snippets
+		code, _, body := testServer.get(t, "/account/stars")
+
+		// Then ... the starred snippet should be listed
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, body, "<a href='/snippet/view/silentPond'>An old silent pond</a>")
+	})
+
+	t.Run("Most starred", func(t *testing.T) {
+		// When ... we view the home page
+		code, _, body := testServer.get(t, "/")
+
+		// Then ... the most starred snippets should be shown
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, body, "<h2>Most Starred This Week</h2>")
+	})
+}
+
+func TestSnippetCommentsE2E(t *testing.T) {
+	endToEndTest(t)
+	// Given ... we have an application with a structured logger which discards everthing.
+	app := newTestApplication(t)
+
+	// And ... we have created a new test server
+	testServer := newTestServer(t, app.routes())
+	defer testServer.Close()
+
+	// And ... we have extracted a csrf token
+	_, _, body := testServer.get(t, "/user/login")
+	validCSRFToken := extractCSRFToken(t, body)
+
+	t.Run("Unauthenticated", func(t *testing.T) {
+		// When ... we view a snippet without logging in
+		code, _, body := testServer.get(t, "/snippet/view/silentPond")
+
+		// Then ... the comments should be shown, but not the comment form
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, body, "What a lovely haiku")
+		assert.StringContains(t, body, "<a href='/user/login'>Login</a> to leave a comment.")
+
+		// And ... posting a comment should send us to the login page
+		form := url.Values{}
+		form.Add("csrf_token", validCSRFToken)
+		form.Add("content", "Hello")
+		code, headers, _ := testServer.postForm(t, "/snippet/comment/silentPond", form)
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, headers.Get("Location"), "/user/login")
+	})
+
+	// And ... we have logged the user in
+	testServer.login(t)
+	_, _, body = testServer.get(t, "/snippet/create")
+	validCSRFToken = extractCSRFToken(t, body)
+
+	t.Run("View", func(t *testing.T) {
+		// When ... the owner of a snippet views it
+		code, _, body := testServer.get(t, "/snippet/view/silentPond")
+
+		// Then ... they should be able to delete any comment, but only edit
+		// their own
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, body, "<div class='comment depth-1' id='comment-2'>")
+		assert.StringContains(t, body, "<form action='/comment/delete/1' method='POST'>")
+		assert.StringContains(t, body, "<a href='/comment/edit/2'>Edit</a>")
+		assert.Equal(t, strings.Contains(body, "<a href='/comment/edit/1'>Edit</a>"), false)
+		assert.StringContains(t, body, "<input type='submit' value='Post comment'>")
+	})
+
+	postTests := []struct {
+		name         string
+		urlPath      string
+		content      string
+		parentID     string
+		wantCode     int
+		wantLocation string
+		wantBody     string
+	}{
+		{
+			name:         "Comment",
+			urlPath:      "/snippet/comment/silentPond",
+			content:      "Hello",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/snippet/view/silentPond#comment-5",
+		},
+		{
+			name:         "Reply",
+			urlPath:      "/snippet/comment/silentPond",
+			content:      "Hello",
+			parentID:     "1",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/snippet/view/silentPond#comment-5",
+		},
+		{
+			name:     "Blank",
+			urlPath:  "/snippet/comment/silentPond",
+			content:  " ",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "This field cannot be blank",
+		},
+		{
+			name:     "Too long",
+			urlPath:  "/snippet/comment/silentPond",
+			content:  strings.Repeat("a", 2001),
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "This field cannot be more than 2000 characters long",
+		},
+		{
+			name:     "Reply to a comment on another snippet",
+			urlPath:  "/snippet/comment/silentPond",
+			content:  "Hello",
+			parentID: "3",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "The comment you replied to has been deleted",
+		},
+		{
+			name:         "Locked snippet",
+			urlPath:      "/snippet/comment/contractor",
+			content:      "Hello",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/snippet/view/contractor",
+		},
+		{
+			name:     "Private snippet of another user",
+			urlPath:  "/snippet/comment/autumnMorn",
+			content:  "Hello",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tableTest := range postTests {
+		t.Run(tableTest.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("csrf_token", validCSRFToken)
+			form.Add("content", tableTest.content)
+			form.Add("parentID", tableTest.parentID)
+
+			// When ... we post the comment
+			code, headers, body := testServer.postForm(t, tableTest.urlPath, form)
+
+			// Then ... we should be sent to the new comment, or shown what
+			// went wrong
+			assert.Equal(t, code, tableTest.wantCode)
+			assert.Equal(t, headers.Get("Location"), tableTest.wantLocation)
+			if tableTest.wantBody != "" {
+				assert.StringContains(t, body, tableTest.wantBody)
+			}
+		})
+	}
+
+	t.Run("Edit form", func(t *testing.T) {
+		// When ... the author opens the edit form for their recent comment
+		code, _, body := testServer.get(t, "/comment/edit/2")
+
+		// Then ... the form should hold the comment
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, body, "<textarea name='content'>Thank you, Bob!</textarea>")
+	})
+
+	changeTests := []struct {
+		name         string
+		urlPath      string
+		content      string
+		wantCode     int
+		wantLocation string
+	}{
+		{
+			name:         "Edit own comment",
+			urlPath:      "/comment/edit/2",
+			content:      "Thanks!",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/snippet/view/silentPond#comment-2",
+		},
+		{
+			name:     "Edit own comment with blank content",
+			urlPath:  "/comment/edit/2",
+			content:  "",
+			wantCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "Edit another user's comment",
+			urlPath:  "/comment/edit/1",
+			content:  "Thanks!",
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:         "Edit own comment after the edit window",
+			urlPath:      "/comment/edit/3",
+			content:      "Thanks!",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/snippet/view/wintryWood#comment-3",
+		},
+		{
+			name:         "Delete own comment",
+			urlPath:      "/comment/delete/2",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/snippet/view/silentPond#comments",
+		},
+		{
+			name:         "Delete a comment on own snippet",
+			urlPath:      "/comment/delete/1",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/snippet/view/silentPond#comments",
+		},
+		{
+			name:         "Delete own comment after the edit window",
+			urlPath:      "/comment/delete/3",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/snippet/view/wintryWood#comment-3",
+		},
+		{
+			name:     "Delete another user's comment",
+			urlPath:  "/comment/delete/4",
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "Non-existent ID",
+			urlPath:  "/comment/delete/99",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Invalid ID",
+			urlPath:  "/comment/edit/foo",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tableTest := range changeTests {
+		t.Run(tableTest.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("csrf_token", validCSRFToken)
+			form.Add("content", tableTest.content)
+
+			// When ... we edit or delete the comment
+			code, headers, _ := testServer.postForm(t, tableTest.urlPath, form)
+
+			// Then ... the change should only be allowed for the author, or
+			// for deletions by the snippet's owner
+			assert.Equal(t, code, tableTest.wantCode)
+			assert.Equal(t, headers.Get("Location"), tableTest.wantLocation)
+		})
+	}
+}
+
+func TestSnippetDeletedCommentE2E(t *testing.T) {
+	endToEndTest(t)
+	// Given ... we have an application where Bob's comment on "An old silent
+	// pond" has been deleted, but Alice's reply to it has not
+	app := newTestApplication(t)
+	app.comments = &deletedComments{}
+
+	// And ... we have created a new test server
+	testServer := newTestServer(t, app.routes())
+	defer testServer.Close()
+
+	// And ... we have logged the user in
+	testServer.login(t)
+	_, _, body := testServer.get(t, "/snippet/create")
+	validCSRFToken := extractCSRFToken(t, body)
+
+	t.Run("View", func(t *testing.T) {
+		// When ... we view the snippet
+		code, _, body := testServer.get(t, "/snippet/view/silentPond")
+
+		// Then ... the deleted comment should be a placeholder which can't
+		// be deleted or replied to, and the reply should still be shown
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, body, "<div class='comment-content'>[deleted]</div>")
+		assert.StringContains(t, body, "<div class='comment depth-1' id='comment-2'>")
+		assert.StringContains(t, body, "Thank you, Bob!")
+		assert.Equal(t, strings.Contains(body, "What a lovely haiku"), false)
+		assert.Equal(t, strings.Contains(body, "<form action='/comment/delete/1' method='POST'>"), false)
+		assert.Equal(t, strings.Contains(body, "<input type='hidden' name='parentID' value='1'>"), false)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		form := url.Values{}
+		form.Add("csrf_token", validCSRFToken)
+
+		// When ... we try to delete the comment again
+		code, _, _ := testServer.postForm(t, "/comment/delete/1", form)
+
+		// Then ... it should no longer be found
+		assert.Equal(t, code, http.StatusNotFound)
+	})
+
+	t.Run("Reply", func(t *testing.T) {
+		form := url.Values{}
+		form.Add("csrf_token", validCSRFToken)
+		form.Add("content", "Me too")
+		form.Add("parentID", "1")
+
+		// When ... we try to reply to it
+		code, _, body := testServer.postForm(t, "/snippet/comment/silentPond", form)
+
+		// Then ... we should be told that it has been deleted
+		assert.Equal(t, code, http.StatusUnprocessableEntity)
+		assert.StringContains(t, body, "The comment you replied to has been deleted")
+	})
+}
+
+// deletedComments is the mock comment model, except that the first comment
+// (which has a reply) has been deleted.
+type deletedComments struct {
+	mocks.CommentModel
+}
+
+func (m *deletedComments) Get(id int) (models.Comment, error) {
+	comment, err := m.CommentModel.Get(id)
+	return markDeleted(comment), err
+}
+
+func (m *deletedComments) ForSnippet(snippetID int) ([]models.Comment, error) {
+	comments, err := m.CommentModel.ForSnippet(snippetID)
+	for i := range comments {
+		comments[i] = markDeleted(comments[i])
+	}
+	return comments, err
+}
+
+func markDeleted(comment models.Comment) models.Comment {
+	if comment.ID == 1 {
+		comment.Deleted = true
+		comment.Content = ""
+	}
+	return comment
+}
+
+func TestCollectionsE2E(t *testing.T) {
+	endToEndTest(t)
+	// Given ... we have an application with a structured logger which discards everthing.
+	app := newTestApplication(t)
+
+	// And ... we have created a new test server
+	testServer := newTestServer(t, app.routes())
+	defer testServer.Close()
+
+	viewTests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "Public collection",
+			urlPath:  "/collection/sqlRecipes",
+			wantCode: http.StatusOK,
+			wantBody: "<td><a href='/snippet/view/wintryWood'>Over the wintry forest</a></td>",
+		},
+		{
+			name:     "Private collection of another user",
+			urlPath:  "/collection/bobsPrivat",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Non-existent ID",
+			urlPath:  "/collection/missingCol",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Invalid ID",
+			urlPath:  "/collection/foo",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tableTest := range viewTests {
+		t.Run(tableTest.name, func(t *testing.T) {
+			// When ... we view the collection without logging in
+			code, _, body := testServer.get(t, tableTest.urlPath)
+
+			// Then ... we should see its snippets, or a 404
+			assert.Equal(t, code, tableTest.wantCode)
+			if tableTest.wantBody != "" {
+				assert.StringContains(t, body, tableTest.wantBody)
+			}
+		})
+	}
+
+	t.Run("Unauthenticated", func(t *testing.T) {
+		// When ... we try to create a collection without logging in
+		code, headers, _ := testServer.get(t, "/collection/create")
+
+		// Then ... we should be sent to the login page
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, headers.Get("Location"), "/user/login")
+	})
+
+	// And ... we have logged the user in
+	testServer.login(t)
+	_, _, body := testServer.get(t, "/collection/create")
+	validCSRFToken := extractCSRFToken(t, body)
+
+	t.Run("Owner", func(t *testing.T) {
+		// When ... the owner views their collection
+		code, _, body := testServer.get(t, "/collection/sqlRecipes")
+
+		// Then ... they should be able to reorder it, and edit or delete it
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, body, "<form action='/collection/move/sqlRecipes' method='POST'>")
+		assert.StringContains(t, body, "<a href='/collection/edit/sqlRecipes'>Edit</a>")
+
+		// And ... nobody else's collection should offer the same
+		_, _, body = testServer.get(t, "/collection/bobsPublic")
+		assert.Equal(t, strings.Contains(body, "/collection/move/"), false)
+	})
+
+	t.Run("Snippet and account pages", func(t *testing.T) {
+		// When ... we view a snippet and the account page
+		_, _, snippetBody := testServer.get(t, "/snippet/view/wintryWood")
+		_, _, accountBody := testServer.get(t, "/account/view")
+
+		// Then ... the user's collections should be offered and listed
+		assert.StringContains(t, snippetBody, "<option value='sqlRecipes'>SQL recipes</option>")
+		assert.StringContains(t, accountBody, "<td><a href='/collection/sqlRecipes'>SQL recipes</a></td>")
+	})
+
+	tests := []struct {
+		name         string
+		urlPath      string
+		form         url.Values
+		wantCode     int
+		wantLocation string
+	}{
+		{
+			name:         "Create",
+			urlPath:      "/collection/create",
+			form:         url.Values{"title": {"Onboarding scripts"}, "visibility": {"unlisted"}},
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/collection/newCollect",
+		},
+		{
+			name:     "Create without a title",
+			urlPath:  "/collection/create",
+			form:     url.Values{"title": {""}, "visibility": {"public"}},
+			wantCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "Create with an invalid visibility",
+			urlPath:  "/collection/create",
+			form:     url.Values{"title": {"Drafts"}, "visibility": {"secret"}},
+			wantCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "Edit",
+			urlPath:      "/collection/edit/sqlRecipes",
+			form:         url.Values{"title": {"SQL"}, "visibility": {"private"}},
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/collection/sqlRecipes",
+		},
+		{
+			name:     "Edit another user's collection",
+			urlPath:  "/collection/edit/bobsPublic",
+			form:     url.Values{"title": {"Mine"}, "visibility": {"public"}},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:         "Add a snippet",
+			urlPath:      "/snippet/collect/wintryWood",
+			form:         url.Values{"collection": {"sqlRecipes"}},
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/snippet/view/wintryWood",
+		},
+		{
+			name:     "Add a snippet to another user's collection",
+			urlPath:  "/snippet/collect/wintryWood",
+			form:     url.Values{"collection": {"bobsPublic"}},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "Add a snippet to a missing collection",
+			urlPath:  "/snippet/collect/wintryWood",
+			form:     url.Values{"collection": {"missingCol"}},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Add a locked snippet",
+			urlPath:      "/snippet/collect/contractor",
+			form:         url.Values{"collection": {"sqlRecipes"}},
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/snippet/view/contractor",
+		},
+		{
+			name:         "Move a snippet",
+			urlPath:      "/collection/move/sqlRecipes",
+			form:         url.Values{"snippet": {"wintryWood"}, "direction": {"up"}},
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/collection/sqlRecipes",
+		},
+		{
+			name:     "Move a snippet sideways",
+			urlPath:  "/collection/move/sqlRecipes",
+			form:     url.Values{"snippet": {"wintryWood"}, "direction": {"left"}},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Move a snippet which isn't in the collection",
+			urlPath:  "/collection/move/sqlRecipes",
+			form:     url.Values{"snippet": {"deployTokn"}, "direction": {"down"}},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Move a snippet in another user's collection",
+			urlPath:  "/collection/move/bobsPublic",
+			form:     url.Values{"snippet": {"wintryWood"}, "direction": {"down"}},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:         "Remove a snippet",
+			urlPath:      "/collection/remove/sqlRecipes",
+			form:         url.Values{"snippet": {"silentPond"}},
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/collection/sqlRecipes",
+		},
+		{
+			name:         "Delete",
+			urlPath:      "/collection/delete/sqlRecipes",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/account/view",
+		},
+		{
+			name:     "Delete another user's collection",
+			urlPath:  "/collection/delete/bobsPublic",
+			wantCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tableTest := range tests {
+		t.Run(tableTest.name, func(t *testing.T) {
+			form := url.Values{}
+			for key, values := range tableTest.form {
+				form[key] = values
+			}
+			form.Add("csrf_token", validCSRFToken)
+
+			// When ... we post the form
+			code, headers, _ := testServer.postForm(t, tableTest.urlPath, form)
+
+			// Then ... the change should be made, or refused
+			assert.Equal(t, code, tableTest.wantCode)
+			assert.Equal(t, headers.Get("Location"), tableTest.wantLocation)
+		})
+	}
+}
+
+func TestSnippetHistoryE2E(t *testing.T) {
+	endToEndTest(t)
+	// Given ... we have an application with a structured logger which discards everthing.
+	app := newTestApplication(t)
+
+	// And ... we have created a new test server
+	testServer := newTestServer(t, app.routes())
+	defer testServer.Close()
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "Valid ID",
+			urlPath:  "/snippet/view/silentPond/history",
+			wantCode: http.StatusOK,
+			wantBody: "<input type='radio' name='to' value='2' checked>",
+		},
+		{
+			name:     "Non-existent ID",
+			urlPath:  "/snippet/view/missingSnp/history",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tableTest := range tests {
+		t.Run(tableTest.name, func(t *testing.T) {
+			// When ... we call our path
+			code, _, body := testServer.get(t, tableTest.urlPath)
+
+			// Then ... the HTTP status code should be returned as expected
+			assert.Equal(t, code, tableTest.wantCode)
+
+			// And ... the body of the response should be returned as expected.
+			if tableTest.wantBody != "" {
+				assert.StringContains(t, body, tableTest.wantBody)
+			}
+		})
+	}
+}
+
+func TestSnippetDiffE2E(t *testing.T) {
+	endToEndTest(t)
+	// Given ... we have an application with a structured logger which discards everthing.
+	app := newTestApplication(t)
+
+	// And ... we have created a new test server
+	testServer := newTestServer(t, app.routes())
+	defer testServer.Close()
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "Valid versions",
+			urlPath:  "/snippet/view/silentPond/diff?from=1&to=2",
+			wantCode: http.StatusOK,
+			wantBody: "<tr class='diff-added'>",
+		},
+		{
+			name:     "Hunk header",
+			urlPath:  "/snippet/view/silentPond/diff?from=1&to=2",
+			wantCode: http.StatusOK,
+			wantBody: "@@ -1,1 &#43;1,2 @@",
+		},
+		{
+			name:     "Missing versions",
+			urlPath:  "/snippet/view/silentPond/diff",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Invalid version",
+			urlPath:  "/snippet/view/silentPond/diff?from=foo&to=2",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Non-existent version",
+			urlPath:  "/snippet/view/silentPond/diff?from=1&to=3",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Non-existent ID",
+			urlPath:  "/snippet/view/missingSnp/diff?from=1&to=2",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tableTest := range tests {
+		t.Run(tableTest.name, func(t *testing.T) {
+			// When ... we call our path
+			code, _, body := testServer.get(t, tableTest.urlPath)
+
+			// Then ... the HTTP status code should be returned as expected
+			assert.Equal(t, code, tableTest.wantCode)
+
+			// And ... the body of the response should be returned as expected.
+			if tableTest.wantBody != "" {
+				assert.StringContains(t, body, tableTest.wantBody)
+			}
+		})
+	}
+}
+
+func TestSnippetBurnAfterReadingE2E(t *testing.T) {
+	endToEndTest(t)
+	// Given ... we have an application with a structured logger which discards everthing.
+	app := newTestApplication(t)
+
+	// And ... we have created a new test server
+	testServer := newTestServer(t, app.routes())
+	defer testServer.Close()
+
+	t.Run("View shows confirmation", func(t *testing.T) {
+		// When ... we view a burn-after-reading snippet
+		code, _, body := testServer.get(t, "/snippet/view/deployTokn")
+
+		// Then ... a confirmation page should be shown instead of the content
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, body, "<form action='/snippet/view/deployTokn' method='POST' class='reveal'>")
+
+		if strings.Contains(body, "t0k3n-s3cr3t") {
+			t.Errorf("confirmation page should not contain the snippet content")
+		}
+	})
+
+	t.Run("History redirects to confirmation", func(t *testing.T) {
+		// When ... we try to read the history of a burn-after-reading snippet
+		code, headers, _ := testServer.get(t, "/snippet/view/deployTokn/history")
+
+		// Then ... we should be sent back to the view page
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, headers.Get("Location"), "/snippet/view/deployTokn")
+	})
+
+	t.Run("Invalid CSRF token", func(t *testing.T) {
+		form := url.Values{}
+		form.Add("csrf_token", "wrongToken")
+
+		// When ... we confirm without a valid CSRF token
+		code, _, _ := testServer.postForm(t, "/snippet/view/deployTokn", form)
+
+		// Then ... the request should be rejected
+		assert.Equal(t, code, http.StatusBadRequest)
+	})
+
+	t.Run("Confirm reveals content", func(t *testing.T) {
+		_, _, body := testServer.get(t, "/snippet/view/deployTokn")
+		form := url.Values{}
+		form.Add("csrf_token", extractCSRFToken(t, body))
+
+		// When ... we confirm that we want to view the snippet
+		code, headers, body := testServer.postForm(t, "/snippet/view/deployTokn", form)
+
+		// Then ... the content should be shown and not cached
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, body, "t0k3n-s3cr3t")
+		assert.Equal(t, headers.Get("Cache-Control"), "no-store")
+
+		// And ... a deleted event should be queued for the owner's webhooks
+		select {
+		case e := <-app.webhookEvents:
+			assert.Equal(t, e.Type, models.EventSnippetDeleted)
+			assert.Equal(t, e.Snippet.ShortID, "deployTokn")
+			assert.Equal(t, e.Snippet.UserID, 2)
+		default:
+			t.Fatal("no webhook event was queued")
+		}
+	})
+
+	t.Run("Confirm non-burn snippet", func(t *testing.T) {
+		_, _, body := testServer.get(t, "/user/login")
+		form := url.Values{}
+		form.Add("csrf_token", extractCSRFToken(t, body))
+
+		// When ... we POST to a normal snippet
+		code, headers, _ := testServer.postForm(t, "/snippet/view/silentPond", form)
+
+		// Then ... we should be redirected to the normal view page
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, headers.Get("Location"), "/snippet/view/silentPond")
+	})
+}
+
+func TestSnippetUnlockE2E(t *testing.T) {
+	endToEndTest(t)
+	// Given ... we have an application with a structured logger which discards everthing.
+	app := newTestApplication(t)
+
+	// And ... we have created a new test server
+	testServer := newTestServer(t, app.routes())
+	defer testServer.Close()
+
+	// When ... we view a protected snippet
+	code, _, body := testServer.get(t, "/snippet/view/contractor")
+
+	// Then ... an unlock form should be shown instead of the content
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, "<form action='/snippet/unlock/contractor' method='POST' class='unlock' novalidate>")
+	if strings.Contains(body, "vpn.example.com") {
+		t.Errorf("unlock page should not contain the snippet content")
+	}
+
+	validCSRFToken := extractCSRFToken(t, body)
+
+	tests := []struct {
+		name         string
+		passphrase   string
+		wantCode     int
+		wantBody     string
+		wantLocation string
+	}{
+		{
+			name:       "Empty passphrase",
+			passphrase: "",
+			wantCode:   http.StatusUnprocessableEntity,
+			wantBody:   "This field cannot be blank",
+		},
+		{
+			name:       "Wrong passphrase",
+			passphrase: "wrong",
+			wantCode:   http.StatusUnprocessableEntity,
+			wantBody:   "Passphrase is incorrect",
+		},
+		{
+			name:       "Too many attempts",
+			passphrase: mocks.ThrottledPassphrase,
+			wantCode:   http.StatusTooManyRequests,
+			wantBody:   "Too many incorrect attempts",
+		},
+		{
+			name:         "Valid passphrase",
+			passphrase:   mocks.ValidPassphrase,
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/snippet/view/contractor",
+		},
+	}
+
+	for _, tableTest := range tests {
+		t.Run(tableTest.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("passphrase", tableTest.passphrase)
+			form.Add("csrf_token", validCSRFToken)
+
+			code, headers, body := testServer.postForm(t, "/snippet/unlock/contractor", form)
+
+			assert.Equal(t, code, tableTest.wantCode)
+			assert.Equal(t, headers.Get("Location"), tableTest.wantLocation)
+
+			if tableTest.wantBody != "" {
+				assert.StringContains(t, body, tableTest.wantBody)
+			}
+		})
+	}
+
+	t.Run("Unlocked for the session", func(t *testing.T) {
+		// When ... we view the snippet again after unlocking it
+		code, _, body := testServer.get(t, "/snippet/view/contractor")
+
+		// Then ... the content should be shown
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, body, "vpn.example.com")
+	})
+
+	t.Run("Locked again after the passphrase changes", func(t *testing.T) {
+		// Given ... the owner has since changed the passphrase
+		app.snippets = &passphraseChangedSnippets{}
+		defer func() { app.snippets = &mocks.SnippetModel{} }()
+
+		// When ... we view the snippet again
+		code, _, body := testServer.get(t, "/snippet/view/contractor")
+
+		// Then ... the new passphrase should be asked for
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, body, "<form action='/snippet/unlock/contractor' method='POST' class='unlock' novalidate>")
+		if strings.Contains(body, "vpn.example.com") {
+			t.Errorf("unlock page should not contain the snippet content")
+		}
+	})
+}
+
+// passphraseChangedSnippets is the mock snippet model, except that the
+// passphrase of each snippet has been changed once since it was created.
+type passphraseChangedSnippets struct {
+	mocks.SnippetModel
+}
+
+func (m *passphraseChangedSnippets) Get(shortID string) (models.Snippet, error) {
+	snippet, err := m.SnippetModel.Get(shortID)
+	snippet.PassphraseVersion++
+	return snippet, err
+}
+
+func TestSnippetListE2E(t *testing.T) {
+	endToEndTest(t)
+	// Given ... we have an application with a structured logger which discards everthing.
+	app := newTestApplication(t)
+
+	// And ... we have created a new test server
+	testServer := newTestServer(t, app.routes())
+	defer testServer.Close()
+
+	tests := []struct {
+		name         string
+		urlPath      string
+		wantCode     int
+		wantBody     []string
+		dontWantBody []string
+	}{
+		{
+			name:         "First page",
+			urlPath:      "/snippets",
+			wantCode:     http.StatusOK,
+			wantBody:     []string{"Over the wintry forest", "An old silent pond"},
+			dontWantBody: []string{"Next &rarr;", "&larr; Previous"},
+		},
+		{
+			name:         "Excludes private snippets",
+			urlPath:      "/snippets",
+			wantCode:     http.StatusOK,
+			dontWantBody: []string{"First autumn morning"},
+		},
+		{
+			name:         "One per page",
+			urlPath:      "/snippets?per_page=1",
+			wantCode:     http.StatusOK,
+			wantBody:     []string{"Over the wintry forest", "Next &rarr;"},
+			dontWantBody: []string{"An old silent pond", "&larr; Previous"},
+		},
+		{
+			name:         "Oldest first",
+			urlPath:      "/snippets?per_page=1&sort=oldest",
+			wantCode:     http.StatusOK,
+			wantBody:     []string{"An old silent pond", "<strong>Oldest first</strong>"},
+			dontWantBody: []string{"Over the wintry forest"},
+		},
+		{
+			name:     "Invalid sort",
+			urlPath:  "/snippets?sort=sideways",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Invalid page size",
+			urlPath:  "/snippets?per_page=1000",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Invalid cursor",
+			urlPath:  "/snippets?page=garbage",
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tableTest := range tests {
+		t.Run(tableTest.name, func(t *testing.T) {
+			// When ... we call our path
+			code, _, body := testServer.get(t, tableTest.urlPath)
+
+			// Then ... the HTTP status code should be returned as expected
+			assert.Equal(t, code, tableTest.wantCode)
+
+			// And ... the body of the response should be returned as expected.
+			for _, want := range tableTest.wantBody {
+				assert.StringContains(t, body, want)
+			}
+			for _, dontWant := range tableTest.dontWantBody {
+				if strings.Contains(body, dontWant) {
+					t.Errorf("body unexpectedly contains %q", dontWant)
+				}
+			}
+		})
+	}
+
+	t.Run("Following the page links", func(t *testing.T) {
+		// Given ... we are on the first page of one snippet per page
+		_, _, body := testServer.get(t, "/snippets?per_page=1")
+
+		// When ... we follow the next link
+		next := extractLink(t, body, "next")
+		code, _, body := testServer.get(t, next)
+
+		// Then ... we should see the second snippet, with a link back
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, body, "An old silent pond")
+
+		// And ... following the previous link should take us back to the
+		// first snippet
+		prev := extractLink(t, body, "prev")
+		code, _, body = testServer.get(t, prev)
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, body, "Over the wintry forest")
+	})
+}
+
+func TestSnippetSearchE2E(t *testing.T) {
+	endToEndTest(t)
+	// Given ... we have an application with a structured logger which discards everthing.
+	app := newTestApplication(t)
+
+	// And ... we have created a new test server
+	testServer := newTestServer(t, app.routes())
+	defer testServer.Close()
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantBody string
+	}{
+		{
+			name:     "Empty query",
+			urlPath:  "/search",
+			wantBody: "<form action='/search' method='GET' class='search-page'>",
+		},
+		{
+			name:     "Highlights matches",
+			urlPath:  "/search?q=pond",
+			wantBody: "<a href='/snippet/view/silentPond'>An old silent <mark>pond</mark></a>",
+		},
+		{
+			name:     "Highlights excerpts",
+			urlPath:  "/search?q=%22silent+pond%22",
+			wantBody: "<p>An old <mark>silent pond</mark>...</p>",
+		},
+		{
+			name:     "Keeps the query",
+			urlPath:  "/search?q=pond+-frog",
+			wantBody: "value='pond -frog'",
+		},
+		{
+			name:     "No results",
+			urlPath:  "/search?q=winter",
+			wantBody: "No snippets matched your search.",
+		},
+		{
+			name:     "Only exclusions",
+			urlPath:  "/search?q=-pond",
+			wantBody: "<input type='text' name='q' value='-pond'>",
+		},
+	}
+
+	for _, tableTest := range tests {
+		t.Run(tableTest.name, func(t *testing.T) {
+			// When ... we call our path
+			code, _, body := testServer.get(t, tableTest.urlPath)
+
+			// Then ... the search page should be shown
+			assert.Equal(t, code, http.StatusOK)
+
+			// And ... the body of the response should be returned as expected.
+			assert.StringContains(t, body, tableTest.wantBody)
+		})
+	}
+}
+
+func TestSnippetTagsE2E(t *testing.T) {
+	endToEndTest(t)
+	// Given ... we have an application with a structured logger which discards everthing.
+	app := newTestApplication(t)
+
+	// And ... we have created a new test server
+	testServer := newTestServer(t, app.routes())
+	defer testServer.Close()
+
+	getTests := []struct {
+		name         string
+		urlPath      string
+		wantCode     int
+		wantBody     string
+		dontWantBody string
+	}{
+		{
+			name:     "Tag cloud on the home page",
+			urlPath:  "/",
+			wantCode: http.StatusOK,
+			wantBody: "<a href='/tag/haiku' class='tag-size-5' title='3 snippets'>haiku</a>",
+		},
+		{
+			name:     "Tags on the view page",
+			urlPath:  "/snippet/view/silentPond",
+			wantCode: http.StatusOK,
+			wantBody: "<a href='/tag/nature' class='tag'>nature</a>",
+		},
+		{
+			name:         "Snippets with a tag",
+			urlPath:      "/tag/haiku",
+			wantCode:     http.StatusOK,
+			wantBody:     "An old silent pond",
+			dontWantBody: "Over the wintry forest",
+		},
+		{
+			name:     "Unused tag",
+			urlPath:  "/tag/cobol",
+			wantCode: http.StatusOK,
+			wantBody: "There's nothing to see here... yet!",
+		},
+		{
+			name:     "Invalid tag",
+			urlPath:  "/tag/Haiku",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tableTest := range getTests {
+		t.Run(tableTest.name, func(t *testing.T) {
+			// When ... we call our path
+			code, _, body := testServer.get(t, tableTest.urlPath)
+
+			// Then ... the HTTP status code should be returned as expected
+			assert.Equal(t, code, tableTest.wantCode)
+
+			// And ... the body of the response should be returned as expected.
+			if tableTest.wantBody != "" {
+				assert.StringContains(t, body, tableTest.wantBody)
+			}
+			if tableTest.dontWantBody != "" && strings.Contains(body, tableTest.dontWantBody) {
+				t.Errorf("body unexpectedly contains %q", tableTest.dontWantBody)
+			}
+		})
+	}
+
+	// And ... we have logged the user in
+	testServer.login(t)
+
+	t.Run("Edit form shows tags", func(t *testing.T) {
+		_, _, body := testServer.get(t, "/snippet/edit/silentPond")
+		assert.StringContains(t, body, "<input type='text' name='tags' value='haiku, nature'")
+	})
+
+	// And ... we have extracted the csrf token from the create form
+	_, _, body := testServer.get(t, "/snippet/create")
+	validCSRFToken := extractCSRFToken(t, body)
+
+	postTests := []struct {
+		name     string
+		tags     string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "Valid tags",
+			tags:     "Go, SQL k8s",
+			wantCode: http.StatusSeeOther,
+		},
+		{
+			name:     "Invalid characters",
+			tags:     "go, snake_case",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "Tags can only contain letters, numbers",
+		},
+		{
+			name:     "Too many tags",
+			tags:     "a b c d e f g h i j k",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "A snippet cannot have more than 10 tags",
+		},
+	}
+
+	for _, tableTest := range postTests {
+		t.Run(tableTest.name, func(t *testing.T) {
+			// Given ... we have a snippet form with some tags
+			form := url.Values{}
+			form.Add("title", "O snail")
+			form.Add("files[0].content", "O snail")
+			form.Add("expires", "7d")
+			form.Add("visibility", "public")
+			form.Add("tags", tableTest.tags)
+			form.Add("csrf_token", validCSRFToken)
+
+			// When ... we post it to the create route
+			code, _, body := testServer.postForm(t, "/snippet/create", form)
+
+			// Then ... the HTTP status code should be returned as expected
+			assert.Equal(t, code, tableTest.wantCode)
+
+			// And ... any validation error should be shown
+			if tableTest.wantBody != "" {
+				assert.StringContains(t, body, tableTest.wantBody)
+			}
+		})
+	}
+}
@@ -6,10 +6,14 @@ import (
 	"fmt"
 	"net/http"
 	"runtime/debug"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-playground/form/v4"
 	"github.com/justinas/nosurf"
+	"github.com/mixnblend/snippetbox/internal/models"
+	"github.com/mixnblend/snippetbox/internal/validator"
 )
 
 // The serverError helper writes a log entry at Error level (including the request
@@ -79,10 +83,12 @@ func (app *application) render(w http.ResponseWriter, r *http.Request, status in
 // *http.Request parameter here at the moment, but we will do later in the book.
 func (app *application) newTemplateData(r *http.Request) templateData {
 	return templateData{
-		CurrentYear:     time.Now().Year(),
-		Flash:           app.sessionManager.PopString(r.Context(), "flash"),
-		IsAuthenticated: app.isAuthenticated(r),
-		CSRFToken:       nosurf.Token(r),
+		CurrentYear:         time.Now().Year(),
+		Flash:               app.sessionManager.PopString(r.Context(), "flash"),
+		IsAuthenticated:     app.isAuthenticated(r),
+		IsVerified:          app.isVerified(r),
+		AuthenticatedUserID: app.authenticatedUserID(r),
+		CSRFToken:           nosurf.Token(r),
 	}
 }
 
@@ -125,3 +131,245 @@ func (app *application) isAuthenticated(r *http.Request) bool {
 
 	return isAuthenticated
 }
+
+// The isVerified helper reports whether the request was made by a user whose
+// account has been verified. It's false for anonymous requests.
+func (app *application) isVerified(r *http.Request) bool {
+	isVerified, ok := r.Context().Value(isVerifiedContextKey).(bool)
+	if !ok {
+		return false
+	}
+
+	return isVerified
+}
+
+// The authenticatedUserID helper returns the ID of the currently logged-in
+// user, or 0 if the request is not authenticated. The ID is put in the
+// request context by the authenticate middleware (or authenticateAPI for the
+// API), rather than read from the session, so that it works for API requests
+// which have no session.
+func (app *application) authenticatedUserID(r *http.Request) int {
+	id, ok := r.Context().Value(authenticatedUserIDContextKey).(int)
+	if !ok {
+		return 0
+	}
+
+	return id
+}
+
+// The snippetFromPath helper looks up the snippet identified by the {id}
+// wildcard in the request path. If the id is invalid, no matching snippet
+// exists, or the snippet is private and not owned by the logged-in user, it
+// sends a 404 Not Found response, and for any other error a 500 Internal
+// Server Error. Old-style numeric ids are permanently redirected to the
+// snippet's short ID. In all of these cases the returned bool is false and the
+// caller should return straight away.
+func (app *application) snippetFromPath(w http.ResponseWriter, r *http.Request) (models.Snippet, bool) {
+	shortID := r.PathValue("id")
+
+	// Snippets used to be identified by their sequential integer id. Legacy
+	// integer ids are always shorter than a short ID, so there's no ambiguity
+	// between the two forms.
+	if legacyID, err := strconv.Atoi(shortID); err == nil && len(shortID) < models.ShortIDLength {
+		app.redirectLegacySnippet(w, r, legacyID)
+		return models.Snippet{}, false
+	}
+
+	// Check that the id looks like a short ID before hitting the database. If
+	// it doesn't, we return a 404 page not found response.
+	if !validator.Matches(shortID, validator.ShortIDRX) {
+		http.NotFound(w, r)
+		return models.Snippet{}, false
+	}
+
+	snippet, err := app.snippets.Get(shortID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			http.NotFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return models.Snippet{}, false
+	}
+
+	// Private snippets are reported as missing to everyone but their owner, so
+	// that their existence isn't leaked.
+	if !snippet.VisibleTo(app.authenticatedUserID(r)) {
+		http.NotFound(w, r)
+		return models.Snippet{}, false
+	}
+
+	return snippet, true
+}
+
+// The redirectLegacySnippet helper sends a 301 Moved Permanently response
+// from an old numeric snippet URL to the same URL using the snippet's short
+// ID. Only safe methods are redirected, and only for public snippets which
+// had a numeric URL in the first place; anything else gets a 404.
+func (app *application) redirectLegacySnippet(w http.ResponseWriter, r *http.Request, id int) {
+	if id < 1 || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+		http.NotFound(w, r)
+		return
+	}
+
+	shortID, err := app.snippets.LegacyShortID(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			http.NotFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	// Swap the id path segment for the short ID, keeping the rest of the path
+	// (e.g. /history) and the query string intact.
+	target := *r.URL
+	target.Path = strings.Replace(r.URL.Path, "/"+r.PathValue("id"), "/"+shortID, 1)
+	target.RawPath = ""
+
+	http.Redirect(w, r, target.RequestURI(), http.StatusMovedPermanently)
+}
+
+// The ownedSnippet helper works like snippetFromPath, but additionally sends
+// a 403 Forbidden response if the snippet isn't owned by the logged-in user.
+func (app *application) ownedSnippet(w http.ResponseWriter, r *http.Request) (models.Snippet, bool) {
+	snippet, ok := app.snippetFromPath(w, r)
+	if !ok {
+		return models.Snippet{}, false
+	}
+
+	if snippet.UserID != app.authenticatedUserID(r) {
+		app.clientError(w, http.StatusForbidden)
+		return models.Snippet{}, false
+	}
+
+	return snippet, true
+}
+
+// The commentFromPath helper looks up the comment identified by the {id}
+// wildcard in the request path, along with the snippet it was left on. Like
+// snippetFromPath, it sends a 404 Not Found response if either can't be found,
+// the comment has been deleted (but kept for its replies), or the snippet is
+// private and not owned by the logged-in user, and the returned bool is then
+// false.
+func (app *application) commentFromPath(w http.ResponseWriter, r *http.Request) (models.Comment, models.Snippet, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		http.NotFound(w, r)
+		return models.Comment{}, models.Snippet{}, false
+	}
+
+	comment, err := app.comments.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			http.NotFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return models.Comment{}, models.Snippet{}, false
+	}
+
+	if comment.Deleted {
+		http.NotFound(w, r)
+		return models.Comment{}, models.Snippet{}, false
+	}
+
+	snippet, err := app.snippets.Get(comment.SnippetShortID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			http.NotFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return models.Comment{}, models.Snippet{}, false
+	}
+
+	if !snippet.VisibleTo(app.authenticatedUserID(r)) {
+		http.NotFound(w, r)
+		return models.Comment{}, models.Snippet{}, false
+	}
+
+	return comment, snippet, true
+}
+
+// The collectionFromPath helper looks up the collection identified by the
+// {id} wildcard in the request path. Like snippetFromPath, it sends a 404 Not
+// Found response if the id is invalid, no matching collection exists, or the
+// collection is private and not owned by the logged-in user, and the
+// returned bool is then false.
+func (app *application) collectionFromPath(w http.ResponseWriter, r *http.Request) (models.Collection, bool) {
+	shortID := r.PathValue("id")
+
+	if !validator.Matches(shortID, validator.ShortIDRX) {
+		http.NotFound(w, r)
+		return models.Collection{}, false
+	}
+
+	collection, err := app.collections.Get(shortID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			http.NotFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return models.Collection{}, false
+	}
+
+	if !collection.VisibleTo(app.authenticatedUserID(r)) {
+		http.NotFound(w, r)
+		return models.Collection{}, false
+	}
+
+	return collection, true
+}
+
+// The ownedCollection helper works like collectionFromPath, but additionally
+// sends a 403 Forbidden response if the collection isn't owned by the
+// logged-in user.
+func (app *application) ownedCollection(w http.ResponseWriter, r *http.Request) (models.Collection, bool) {
+	collection, ok := app.collectionFromPath(w, r)
+	if !ok {
+		return models.Collection{}, false
+	}
+
+	if !collection.OwnedBy(app.authenticatedUserID(r)) {
+		app.clientError(w, http.StatusForbidden)
+		return models.Collection{}, false
+	}
+
+	return collection, true
+}
+
+// unlockedSnippetKey returns the session key used to record that the
+// passphrase for a protected snippet has been entered correctly. The value
+// stored under it is the snippet's PassphraseVersion at the time.
+func unlockedSnippetKey(id int) string {
+	return fmt.Sprintf("unlockedSnippet:%d", id)
+}
+
+// The isUnlocked helper reports whether the current user may get past the
+// passphrase of a protected snippet: either they own it, or they have
+// already entered its current passphrase during this session. Versions start
+// at 1, so a session which has never unlocked the snippet (and so gets 0)
+// never matches.
+func (app *application) isUnlocked(r *http.Request, snippet models.Snippet) bool {
+	if !snippet.Protected || snippet.OwnedBy(app.authenticatedUserID(r)) {
+		return true
+	}
+
+	return app.sessionManager.GetInt(r.Context(), unlockedSnippetKey(snippet.ID)) == snippet.PassphraseVersion
+}
+
+// The canReadContent helper reports whether the content of a snippet can be
+// shown to the current user straight away. The owner can always read their
+// own snippets. Everyone else must first unlock protected snippets, and
+// burn-after-reading snippets must go through the confirmation step in
+// snippetViewPost.
+func (app *application) canReadContent(r *http.Request, snippet models.Snippet) bool {
+	if snippet.OwnedBy(app.authenticatedUserID(r)) {
+		return true
+	}
+
+	return app.isUnlocked(r, snippet) && !snippet.BurnAfterReading
+}
@@ -1,13 +1,21 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"database/sql"
+	"errors"
 	"flag"
+	"fmt"
 	"html/template"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/alexedwards/scs/mysqlstore"
@@ -24,10 +32,38 @@ type application struct {
 	logger         *slog.Logger
 	snippets       models.SnippetModelInterface
 	users          models.UserModelInterface
+	sessions       models.SessionModelInterface
+	stars          models.StarModelInterface
+	comments       models.CommentModelInterface
+	collections    models.CollectionModelInterface
+	tokens         models.TokenModelInterface
+	webhooks       models.WebhookModelInterface
 	templateCache  map[string]*template.Template
 	formDecoder    *form.Decoder
 	sessionManager *scs.SessionManager
 	debug          bool
+	// maxUnverifiedLifetime is the longest a snippet can be kept for when
+	// its owner's account hasn't been verified yet.
+	maxUnverifiedLifetime time.Duration
+	// reapBatchSize is the number of expired rows deleted by each statement
+	// when reaping.
+	reapBatchSize int
+	// commentEditWindow is how long after posting a comment its author can
+	// still edit or delete it.
+	commentEditWindow time.Duration
+	// webhookEvents queues events for the webhook workers, and
+	// webhookClient sends the deliveries.
+	webhookEvents chan webhookEvent
+	webhookClient *http.Client
+	// webhookMaxAttempts is how many times a delivery is attempted before
+	// it's given up on, and webhookBackoff how long to wait before the
+	// first retry (which doubles for each retry after that).
+	webhookMaxAttempts int
+	webhookBackoff     time.Duration
+	// baseURL is the address the site is served from, such as
+	// "https://snippetbox.example.com", without a trailing slash. It's used
+	// for links which are sent outside of a request, such as in webhooks.
+	baseURL string
 }
 
 func main() {
@@ -41,6 +77,32 @@ func main() {
 	// define a new command-line flag for the MYSQL DSN string.
 	dsn := flag.String("dsn", "web:password@/snippetbox?parseTime=true", "MySQL, data source name")
 
+	// Define a flag for the maximum lifetime of snippets created by users
+	// whose accounts haven't been verified.
+	maxUnverifiedLifetime := flag.Duration("max-unverified-lifetime", 7*24*time.Hour, "Maximum lifetime of snippets created by unverified accounts")
+
+	// Define flags for how often expired snippets, sessions and API tokens are
+	// deleted, and how many rows are deleted at a time.
+	reapInterval := flag.Duration("reap-interval", 10*time.Minute, "Interval between deleting expired snippets, sessions and API tokens")
+	reapBatchSize := flag.Int("reap-batch-size", 1000, "Number of expired rows to delete per statement")
+
+	// Define a flag for how long comments can be edited or deleted by their
+	// author after they're posted.
+	commentEditWindow := flag.Duration("comment-edit-window", 15*time.Minute, "How long after posting a comment its author can edit or delete it")
+
+	// Define flags for how webhooks are sent: the number of workers sending
+	// them, how often and how soon failed deliveries are retried, and
+	// whether they can be sent to private addresses (such as when testing
+	// against a receiver on localhost).
+	webhookWorkers := flag.Int("webhook-workers", 4, "Number of workers sending webhooks")
+	webhookMaxAttempts := flag.Int("webhook-attempts", 6, "Number of times a webhook delivery is attempted before giving up")
+	webhookBackoff := flag.Duration("webhook-backoff", 30*time.Second, "Delay before retrying a failed webhook delivery, doubled for each further retry up to a day")
+	webhookAllowPrivate := flag.Bool("webhook-allow-private", false, "Allow webhooks to be sent to loopback and private network addresses")
+
+	// Define a flag for the public address of the site. It can't be taken
+	// from the Host header of requests, as that's chosen by the client.
+	baseURL := flag.String("base-url", "https://localhost:4000", "Public address of the site, used for links in webhooks")
+
 	// Importantly, we use the flag.Parse() function to parse the command-line flag.
 	// This read in the command-line flag value and assigns it to the addr variable.
 	// You need to call this *before* you use the addr variable, otherwise
@@ -48,6 +110,34 @@ func main() {
 	// during parsing the application will be terminated.
 	flag.Parse()
 
+	// A batch size of zero would make the reaper loop forever, and a zero
+	// interval makes time.NewTicker() panic, so reject any of these settings
+	// which aren't positive before doing anything else.
+	if err := checkPositive(flag.CommandLine, "reap-interval", "reap-batch-size", "webhook-workers", "webhook-attempts", "webhook-backoff"); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	base, err := parseBaseURL(*baseURL)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	// There are two subcommands: "reap", which deletes expired rows once and
+	// exits, so that it can be run from cron, and "verify", which marks the
+	// accounts with the given email addresses as verified. With no
+	// subcommand we start the server as normal.
+	command := flag.Arg(0)
+	switch {
+	case command == "verify" && flag.NArg() < 2:
+		fmt.Fprintln(os.Stderr, "usage: web [flags] verify email...")
+		os.Exit(2)
+	case command != "" && command != "reap" && command != "verify":
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", command)
+		os.Exit(2)
+	}
+
 	// Use he slog.New() function to initialise a new structured logger, which
 	// writes to the standard out and uses the default settings.
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
@@ -78,9 +168,10 @@ func main() {
 	// Use the scs.New() function to initialize a new session manager. Then we
 	// configure it to use our MySQL database as the session store, and set a
 	// lifetime of 12 hours (so that sessions automatically expire 12 hours
-	// after first being created).
+	// after first being created). The store's own cleanup goroutine is
+	// disabled, as expired sessions are deleted by our reaper instead.
 	sessionManager := scs.New()
-	sessionManager.Store = mysqlstore.New(db)
+	sessionManager.Store = mysqlstore.NewWithCleanupInterval(db, 0)
 	sessionManager.Lifetime = 12 * time.Hour
 
 	tslConfig := &tls.Config{
@@ -92,10 +183,43 @@ func main() {
 		logger:         logger,
 		users:          &models.UserModel{DB: db},
 		snippets:       &models.SnippetModel{DB: db},
+		sessions:       &models.SessionModel{DB: db},
+		stars:          &models.StarModel{DB: db},
+		comments:       &models.CommentModel{DB: db},
+		collections:    &models.CollectionModel{DB: db},
+		tokens:         &models.TokenModel{DB: db},
+		webhooks:       &models.WebhookModel{DB: db},
 		templateCache:  templateCache,
 		formDecoder:    formDecoder,
 		sessionManager: sessionManager,
 		debug:          *debug,
+
+		maxUnverifiedLifetime: *maxUnverifiedLifetime,
+		reapBatchSize:         *reapBatchSize,
+		commentEditWindow:     *commentEditWindow,
+		webhookEvents:         make(chan webhookEvent, 1000),
+		webhookClient:         newWebhookClient(*webhookAllowPrivate),
+		webhookMaxAttempts:    *webhookMaxAttempts,
+		webhookBackoff:        *webhookBackoff,
+		baseURL:               base,
+	}
+
+	if command == "reap" {
+		err = app.reap()
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	if command == "verify" {
+		err = app.verify(flag.Args()[1:])
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+		return
 	}
 
 	// Initalise a new http.Server struct. We set the Addr and Handler fields
@@ -115,18 +239,106 @@ func main() {
 	// The value returned from the flag.String() function is a pointer to the flag
 	// value, not the value itself. So in this code, that means the addr variable
 	// is actually a pointer, and we need to dereference it (i.e. prefix it with
-	// the * symbol) before using it. Note that we're using the log.Printf()
-	// function to interpolate the address with the log message.
+	// the * symbol) before using it.
 	logger.Info("starting server", slog.String("addr", *addr))
 
-	// And we pass the dereferenced addr pointer to http.ListenAndServe() too.
-	err = srv.ListenAndServeTLS("./tls/cert.pem", "./tls/key.pem")
+	err = app.serve(srv, *reapInterval, *webhookWorkers)
 
 	// And we also use the Error() method to log any error message returned by
-	// http.ListenAndServe() at Error severity (with no additional attributes),
-	// and then call os.Exit(1) to terminate the application with exit code 1.
-	logger.Error(err.Error())
-	os.Exit(1)
+	// serve() at Error severity (with no additional attributes), and then call
+	// os.Exit(1) to terminate the application with exit code 1.
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	logger.Info("stopped server")
+}
+
+// The serve() method runs the server, the background reaper and the webhook
+// workers until the process receives an interrupt or SIGTERM signal. It
+// then stops the reaper, gives in-flight requests up to 30 seconds to
+// complete, stops the webhook workers once no more events can be queued,
+// and returns.
+func (app *application) serve(srv *http.Server, reapInterval time.Duration, webhookWorkers int) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		app.runReaper(ctx, reapInterval)
+	}()
+
+	webhookCtx, stopWebhooks := context.WithCancel(context.Background())
+	defer stopWebhooks()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		app.runWebhooks(webhookCtx, webhookWorkers, webhookPollInterval)
+	}()
+
+	// Once the server has been shut down, ListenAndServeTLS() returns
+	// http.ErrServerClosed straight away, so wait for Shutdown() to finish
+	// before returning.
+	shutdownErr := make(chan error, 1)
+	go func() {
+		<-ctx.Done()
+		app.logger.Info("shutting down server")
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		shutdownErr <- srv.Shutdown(shutdownCtx)
+	}()
+
+	err := srv.ListenAndServeTLS("./tls/cert.pem", "./tls/key.pem")
+	if !errors.Is(err, http.ErrServerClosed) {
+		stop()
+		stopWebhooks()
+		wg.Wait()
+		return err
+	}
+
+	err = <-shutdownErr
+	stopWebhooks()
+	wg.Wait()
+
+	return err
+}
+
+// checkPositive returns an error naming the first of the given int or
+// duration flags whose value is zero or negative.
+func checkPositive(fs *flag.FlagSet, names ...string) error {
+	for _, name := range names {
+		var positive bool
+
+		switch value := fs.Lookup(name).Value.(flag.Getter).Get().(type) {
+		case int:
+			positive = value > 0
+		case time.Duration:
+			positive = value > 0
+		}
+
+		if !positive {
+			return fmt.Errorf("-%s must be greater than zero", name)
+		}
+	}
+
+	return nil
+}
+
+// parseBaseURL checks that s is an absolute http:// or https:// URL, and
+// returns it without any trailing slash.
+func parseBaseURL(s string) (string, error) {
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", errors.New("-base-url must be an http:// or https:// URL")
+	}
+
+	return strings.TrimRight(s, "/"), nil
 }
 
 // The openDB() function wraps sql.Open() and returns a sql.DB connection pool
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/mixnblend/snippetbox/internal/assert"
+)
+
+func TestCheckPositive(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{name: "Defaults", args: nil},
+		{name: "Positive values", args: []string{"-batch-size=1", "-interval=1ms"}},
+		{name: "Zero batch size", args: []string{"-batch-size=0"}, wantErr: "-batch-size must be greater than zero"},
+		{name: "Negative batch size", args: []string{"-batch-size=-5"}, wantErr: "-batch-size must be greater than zero"},
+		{name: "Zero interval", args: []string{"-interval=0s"}, wantErr: "-interval must be greater than zero"},
+		{name: "Negative interval", args: []string{"-interval=-1m"}, wantErr: "-interval must be greater than zero"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given ... we have a set of flags, parsed from the arguments
+			fs := flag.NewFlagSet("web", flag.ContinueOnError)
+			fs.Int("batch-size", 1000, "")
+			fs.Duration("interval", time.Minute, "")
+			assert.NilError(t, fs.Parse(tt.args))
+
+			// When ... we check that they're positive
+			err := checkPositive(fs, "batch-size", "interval")
+
+			// Then ... any which aren't should be named in the error
+			if tt.wantErr == "" {
+				assert.NilError(t, err)
+			} else {
+				assert.Equal(t, err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseBaseURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		baseURL string
+		want    string
+		wantErr bool
+	}{
+		{name: "HTTPS", baseURL: "https://snippetbox.example.com", want: "https://snippetbox.example.com"},
+		{name: "Trailing slash", baseURL: "https://snippetbox.example.com/", want: "https://snippetbox.example.com"},
+		{name: "Path", baseURL: "http://example.com/snippetbox/", want: "http://example.com/snippetbox"},
+		{name: "No scheme", baseURL: "snippetbox.example.com", wantErr: true},
+		{name: "Other scheme", baseURL: "ftp://snippetbox.example.com", wantErr: true},
+		{name: "Empty", baseURL: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When ... we parse the -base-url flag
+			got, err := parseBaseURL(tt.baseURL)
+
+			// Then ... it should be accepted without a trailing slash, or
+			// refused if it isn't an absolute http:// or https:// URL
+			if tt.wantErr {
+				assert.Equal(t, err.Error(), "-base-url must be an http:// or https:// URL")
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, got, tt.want)
+		})
+	}
+}
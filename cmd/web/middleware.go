@@ -2,10 +2,12 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 
 	"github.com/justinas/nosurf"
+	"github.com/mixnblend/snippetbox/internal/models"
 )
 
 func commonHeaders(next http.Handler) http.Handler {
@@ -52,8 +54,13 @@ func (app *application) recoverPanic(next http.Handler) http.Handler {
 			if err := recover(); err != nil {
 				// Set a "Connection:close" header on the response
 				w.Header().Set("Connection", "close")
-				// Call the app.serverError helper method to return a 500
-				app.serverError(w, r, fmt.Errorf("%s", err))
+				// Call the app.serverError helper method to return a 500, or
+				// its JSON equivalent for the API.
+				if isAPIRequest(r) {
+					app.apiServerError(w, r, fmt.Errorf("%s", err))
+				} else {
+					app.serverError(w, r, fmt.Errorf("%s", err))
+				}
 			}
 		}()
 
@@ -93,8 +100,10 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 			return
 		}
 
-		exists, err := app.users.Exists(id)
-		if err != nil {
+		// Fetch the user rather than just checking that they exist, as we
+		// also need to know whether their account has been verified.
+		user, err := app.users.Get(id)
+		if err != nil && !errors.Is(err, models.ErrNoRecord) {
 			app.serverError(w, r, err)
 			return
 		}
@@ -102,16 +111,28 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 		// If a matching user is found, we know that the request is
 		// coming from an authenticated user who exists in our database. We
 		// create a new copy of the request (with an isAuthenticatedContextKey
-		// value of true in the request context) and assign it to r.
-		if exists {
-			ctx := context.WithValue(r.Context(), isAuthenticatedContextKey, true)
-			r = r.WithContext(ctx)
+		// value of true and the user's ID in the request context) and assign
+		// it to r.
+		if err == nil {
+			r = withAuthenticatedUser(r, user)
 		}
 
 		next.ServeHTTP(w, r)
 	})
 }
 
+// withAuthenticatedUser returns a copy of the request with the user recorded
+// as authenticated in its context, along with whether their account has been
+// verified. Both the session and API
+// middleware use it, so that handlers don't need to know how the user signed
+// in.
+func withAuthenticatedUser(r *http.Request, user models.User) *http.Request {
+	ctx := context.WithValue(r.Context(), isAuthenticatedContextKey, true)
+	ctx = context.WithValue(ctx, authenticatedUserIDContextKey, user.ID)
+	ctx = context.WithValue(ctx, isVerifiedContextKey, user.Verified)
+	return r.WithContext(ctx)
+}
+
 func noSurf(next http.Handler) http.Handler {
 	csrfHandler := nosurf.New(next)
 	csrfHandler.SetBaseCookie(http.Cookie{
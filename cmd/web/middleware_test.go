@@ -65,3 +65,47 @@ func TestCommonHeaders(t *testing.T) {
 
 	assert.Equal(t, string(body), "OK")
 }
+
+func TestRecoverPanic(t *testing.T) {
+	tests := []struct {
+		name            string
+		urlPath         string
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:            "Page",
+			urlPath:         "/snippet/view/silentPond",
+			wantContentType: "text/plain; charset=utf-8",
+			wantBody:        "Internal Server Error",
+		},
+		{
+			name:            "API",
+			urlPath:         "/api/v1/snippets/silentPond",
+			wantContentType: "application/json",
+			wantBody:        `"status": 500`,
+		},
+	}
+
+	for _, tableTest := range tests {
+		t.Run(tableTest.name, func(t *testing.T) {
+			// given ... we have an application and a handler which panics
+			app := newTestApplication(t)
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				panic("oops")
+			})
+
+			// when ... we call the recoverPanic middleware with a request for the path
+			responseRecorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodGet, tableTest.urlPath, nil)
+			app.recoverPanic(next).ServeHTTP(responseRecorder, request)
+
+			// then ... a 500 should be returned in the format the path expects
+			response := responseRecorder.Result()
+			assert.Equal(t, response.StatusCode, http.StatusInternalServerError)
+			assert.Equal(t, response.Header.Get("Content-Type"), tableTest.wantContentType)
+			assert.Equal(t, response.Header.Get("Connection"), "close")
+			assert.StringContains(t, responseRecorder.Body.String(), tableTest.wantBody)
+		})
+	}
+}
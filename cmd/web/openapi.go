@@ -0,0 +1,354 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mixnblend/snippetbox/internal/models"
+)
+
+// The OpenAPI document describing the JSON API is served at
+// /api/openapi.json, so that clients can be generated from it. The schemas
+// of the request and response bodies are generated from the same structs the
+// handlers decode and encode, so they can't drift apart; routes are described
+// by hand in openAPIPaths(), and a test checks that every API route in
+// routes.go is there.
+
+// An openAPISchema is an OpenAPI 3.0 schema object, with only the parts we
+// use.
+type openAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Description          string                    `json:"description,omitempty"`
+	Nullable             bool                      `json:"nullable,omitempty"`
+	Enum                 []string                  `json:"enum,omitempty"`
+	Pattern              string                    `json:"pattern,omitempty"`
+	AllOf                []*openAPISchema          `json:"allOf,omitempty"`
+	Items                *openAPISchema            `json:"items,omitempty"`
+	Properties           map[string]*openAPISchema `json:"properties,omitempty"`
+	AdditionalProperties *openAPISchema            `json:"additionalProperties,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+}
+
+// openAPIRef returns a schema which refers to one of the named schemas in
+// the components section of the document.
+func openAPIRef(name string) *openAPISchema {
+	return &openAPISchema{Ref: "#/components/schemas/" + name}
+}
+
+// An openAPISchemas generates schemas from Go types, using their json
+// struct tags. Struct types with a name in the map are referred to by that
+// name rather than being described inline.
+type openAPISchemas map[reflect.Type]string
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaOf returns the schema for values of type t. For response bodies,
+// every field is required unless it's tagged omitempty; request bodies are
+// described without any required fields, and are checked by validation
+// instead.
+func (names openAPISchemas) schemaOf(t reflect.Type, response bool) *openAPISchema {
+	switch {
+	case t == timeType:
+		return &openAPISchema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.Pointer:
+		s := names.schemaOf(t.Elem(), response)
+		if s.Ref != "" {
+			// $ref can't have siblings in OpenAPI 3.0, so wrap it.
+			return &openAPISchema{Nullable: true, AllOf: []*openAPISchema{s}}
+		}
+		s.Nullable = true
+		return s
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &openAPISchema{Type: "string"}
+	case reflect.Bool:
+		return &openAPISchema{Type: "boolean"}
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return &openAPISchema{Type: "integer"}
+	case reflect.Slice:
+		return &openAPISchema{Type: "array", Items: names.schemaOf(t.Elem(), response)}
+	case reflect.Map:
+		return &openAPISchema{Type: "object", AdditionalProperties: names.schemaOf(t.Elem(), response)}
+	case reflect.Struct:
+		if name, ok := names[t]; ok {
+			return openAPIRef(name)
+		}
+		return names.structSchema(t, response)
+	}
+
+	panic("openapi: unsupported type " + t.String())
+}
+
+// structSchema returns the schema for a struct type, with a property for
+// each field which is encoded as JSON.
+func (names openAPISchemas) structSchema(t reflect.Type, response bool) *openAPISchema {
+	s := &openAPISchema{Type: "object", Properties: map[string]*openAPISchema{}}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if !field.IsExported() || tag == "-" {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+
+		s.Properties[name] = names.schemaOf(field.Type, response)
+		if response && !strings.Contains(options, "omitempty") {
+			s.Required = append(s.Required, name)
+		}
+	}
+
+	return s
+}
+
+// openAPIComponents returns the named schemas of the document.
+func openAPIComponents() map[string]*openAPISchema {
+	names := openAPISchemas{
+		reflect.TypeOf(snippetFileForm{}): "SnippetFileInput",
+		reflect.TypeOf(apiFile{}):         "SnippetFile",
+		reflect.TypeOf(apiSnippet{}):      "Snippet",
+		reflect.TypeOf(apiLinks{}):        "Links",
+		reflect.TypeOf(apiError{}):        "Error",
+	}
+
+	schemas := map[string]*openAPISchema{
+		"SnippetInput":     names.structSchema(reflect.TypeOf(snippetCreateForm{}), false),
+		"SnippetFileInput": names.structSchema(reflect.TypeOf(snippetFileForm{}), false),
+		"SnippetFile":      names.structSchema(reflect.TypeOf(apiFile{}), true),
+		"Snippet":          names.structSchema(reflect.TypeOf(apiSnippet{}), true),
+		"Links":            names.structSchema(reflect.TypeOf(apiLinks{}), true),
+		"Error":            names.structSchema(reflect.TypeOf(apiError{}), true),
+	}
+
+	// The envelopes around each response body.
+	schemas["SnippetResponse"] = &openAPISchema{
+		Type:       "object",
+		Properties: map[string]*openAPISchema{"snippet": openAPIRef("Snippet")},
+		Required:   []string{"snippet"},
+	}
+	schemas["SnippetListResponse"] = &openAPISchema{
+		Type: "object",
+		Properties: map[string]*openAPISchema{
+			"snippets": {Type: "array", Items: openAPIRef("Snippet")},
+			"links":    openAPIRef("Links"),
+		},
+		Required: []string{"snippets", "links"},
+	}
+	schemas["ErrorResponse"] = &openAPISchema{
+		Type:       "object",
+		Properties: map[string]*openAPISchema{"error": openAPIRef("Error")},
+		Required:   []string{"error"},
+	}
+
+	// Add what can't be worked out from the types alone.
+	input := schemas["SnippetInput"].Properties
+	input["visibility"].Enum = []string{models.VisibilityPublic, models.VisibilityUnlisted, models.VisibilityPrivate}
+	input["expires"].Description = "How long to keep the snippet, such as 10m, 6h or 30d, or never."
+	input["expiresAt"].Description = "When the snippet expires, as YYYY-MM-DDTHH:MM in UTC. Takes precedence over expires."
+	input["passphrase"].Description = "Protects the snippet with a passphrase. When updating, leave blank to keep the current one."
+	input["tags"].Description = "Comma-separated tags."
+	input["files"].Description = fmt.Sprintf("Up to %d files, with at most %dKB of content between them.", maxSnippetFiles, maxSnippetBytes/1024)
+	schemas["SnippetFileInput"].Properties["language"].Description = "The language to highlight the file as. Leave blank to detect it."
+	schemas["Snippet"].Properties["files"].Description = "Left out of listings, and of protected and burn-after-reading snippets unless they belong to you."
+	schemas["Snippet"].Properties["expires"].Description = "Null if the snippet never expires."
+	schemas["Error"].Properties["fieldErrors"].Description = "Validation errors, keyed by the name of the invalid field, such as title or files[0].content."
+
+	return schemas
+}
+
+// openAPIErrorResponse describes an error response.
+func openAPIErrorResponse(description string) map[string]any {
+	return map[string]any{
+		"description": description,
+		"content": map[string]any{
+			"application/json": map[string]any{"schema": openAPIRef("ErrorResponse")},
+		},
+	}
+}
+
+// openAPIResponse describes a successful response with a JSON body.
+func openAPIResponse(description, schema string) map[string]any {
+	return map[string]any{
+		"description": description,
+		"content": map[string]any{
+			"application/json": map[string]any{"schema": openAPIRef(schema)},
+		},
+	}
+}
+
+// openAPIPaths returns the operations of the API, keyed by path and then by
+// lowercase method, in the same way as the document.
+func openAPIPaths() map[string]map[string]any {
+	idParameter := map[string]any{
+		"name":     "id",
+		"in":       "path",
+		"required": true,
+		"schema":   openAPISchema{Type: "string", Pattern: "^[0-9A-Za-z]{10}$"},
+	}
+
+	snippetBody := map[string]any{
+		"required": true,
+		"content": map[string]any{
+			"application/json": map[string]any{"schema": openAPIRef("SnippetInput")},
+		},
+	}
+
+	// Reading is open to everyone, but a token lets you read your own
+	// private snippets. Changes need a token with the snippets:write scope.
+	readSecurity := []map[string][]string{{}, {"bearerAuth": {}}}
+	writeSecurity := []map[string][]string{{"bearerAuth": {}}}
+
+	var (
+		badRequest   = openAPIErrorResponse("The request body or query string is malformed.")
+		unauthorized = openAPIErrorResponse("The API token is missing, unknown, expired or revoked.")
+		forbidden    = openAPIErrorResponse("The token doesn't have the scope needed, or the snippet belongs to someone else.")
+		notFound     = openAPIErrorResponse("The snippet doesn't exist, or is private.")
+		unsupported  = openAPIErrorResponse("The request body isn't JSON.")
+		invalid      = openAPIErrorResponse("Some fields are invalid; see fieldErrors.")
+	)
+
+	return map[string]map[string]any{
+		"/api/openapi.json": {
+			"get": map[string]any{
+				"operationId": "getOpenAPI",
+				"summary":     "Get this document",
+				"responses": map[string]any{
+					"200": map[string]any{
+						"description": "The OpenAPI document.",
+						"content":     map[string]any{"application/json": map[string]any{"schema": openAPISchema{Type: "object"}}},
+					},
+				},
+			},
+		},
+		"/api/v1/snippets": {
+			"get": map[string]any{
+				"operationId": "listSnippets",
+				"summary":     "List public snippets, newest first unless sorted otherwise",
+				"security":    readSecurity,
+				"parameters": []map[string]any{
+					{"name": "page", "in": "query", "description": "The cursor from links.next or links.prev.", "schema": openAPISchema{Type: "string"}},
+					{"name": "per_page", "in": "query", "schema": map[string]any{"type": "integer", "minimum": 1, "maximum": maxPerPage, "default": defaultPerPage}},
+					{"name": "sort", "in": "query", "schema": openAPISchema{Type: "string", Enum: []string{models.SortNewest, models.SortOldest}}},
+				},
+				"responses": map[string]any{
+					"200": openAPIResponse("A page of snippets.", "SnippetListResponse"),
+					"400": badRequest,
+					"401": unauthorized,
+					"403": forbidden,
+				},
+			},
+			"post": map[string]any{
+				"operationId": "createSnippet",
+				"summary":     "Create a snippet",
+				"security":    writeSecurity,
+				"requestBody": snippetBody,
+				"responses": map[string]any{
+					"201": map[string]any{
+						"description": "The new snippet.",
+						"headers": map[string]any{
+							"Location": map[string]any{"schema": openAPISchema{Type: "string"}, "description": "The URL of the new snippet."},
+						},
+						"content": map[string]any{"application/json": map[string]any{"schema": openAPIRef("SnippetResponse")}},
+					},
+					"400": badRequest,
+					"401": unauthorized,
+					"403": forbidden,
+					"415": unsupported,
+					"422": invalid,
+				},
+			},
+		},
+		"/api/v1/snippets/{id}": {
+			"parameters": []map[string]any{idParameter},
+			"get": map[string]any{
+				"operationId": "getSnippet",
+				"summary":     "Get a snippet and its files",
+				"security":    readSecurity,
+				"responses": map[string]any{
+					"200": openAPIResponse("The snippet.", "SnippetResponse"),
+					"401": unauthorized,
+					"403": forbidden,
+					"404": notFound,
+				},
+			},
+			"put": map[string]any{
+				"operationId": "updateSnippet",
+				"summary":     "Replace one of your snippets",
+				"security":    writeSecurity,
+				"requestBody": snippetBody,
+				"responses": map[string]any{
+					"200": openAPIResponse("The updated snippet.", "SnippetResponse"),
+					"400": badRequest,
+					"401": unauthorized,
+					"403": forbidden,
+					"404": notFound,
+					"415": unsupported,
+					"422": invalid,
+				},
+			},
+			"delete": map[string]any{
+				"operationId": "deleteSnippet",
+				"summary":     "Delete one of your snippets",
+				"security":    writeSecurity,
+				"responses": map[string]any{
+					"204": map[string]any{"description": "The snippet was deleted."},
+					"401": unauthorized,
+					"403": forbidden,
+					"404": notFound,
+				},
+			},
+		},
+	}
+}
+
+// openAPIDocument returns the whole OpenAPI document.
+func openAPIDocument() map[string]any {
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "Snippetbox API",
+			"version":     "1.0.0",
+			"description": "Errors are always returned in the ErrorResponse format.",
+		},
+		"paths": openAPIPaths(),
+		"components": map[string]any{
+			"schemas": openAPIComponents(),
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{
+					"type":        "http",
+					"scheme":      "bearer",
+					"description": "A personal API token, created on the account page. Tokens have the snippets:read and/or snippets:write scopes.",
+				},
+			},
+		},
+	}
+}
+
+// The document never changes, so it's only encoded once.
+var openAPIJSON = sync.OnceValues(func() ([]byte, error) {
+	return json.MarshalIndent(openAPIDocument(), "", "\t")
+})
+
+func (app *application) openAPI(w http.ResponseWriter, r *http.Request) {
+	js, err := openAPIJSON()
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(js)
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mixnblend/snippetbox/internal/assert"
+)
+
+// registeredAPIRoutes returns the method and path of every route under /api/
+// registered in routes.go, such as "GET /api/v1/snippets/{id}". The source
+// is read rather than the mux, as a ServeMux can't list its routes.
+func registeredAPIRoutes(t *testing.T) []string {
+	t.Helper()
+
+	file, err := parser.ParseFile(token.NewFileSet(), "routes.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var routes []string
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) == 0 {
+			return true
+		}
+
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || (sel.Sel.Name != "Handle" && sel.Sel.Name != "HandleFunc") {
+			return true
+		}
+
+		lit, ok := call.Args[0].(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return true
+		}
+
+		pattern, err := strconv.Unquote(lit.Value)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, path, _ := strings.Cut(pattern, " "); strings.HasPrefix(path, "/api/") {
+			routes = append(routes, pattern)
+		}
+
+		return true
+	})
+
+	return routes
+}
+
+func TestOpenAPIRoutes(t *testing.T) {
+	// Given ... the routes registered under /api/
+	routes := registeredAPIRoutes(t)
+	assert.Equal(t, len(routes) > 0, true)
+
+	// And ... the operations described in the OpenAPI document
+	var documented []string
+	for path, item := range openAPIPaths() {
+		for method := range item {
+			if method != "parameters" {
+				documented = append(documented, strings.ToUpper(method)+" "+path)
+			}
+		}
+	}
+
+	// Then ... every registered route should be documented
+	for _, route := range routes {
+		if !slices.Contains(documented, route) {
+			t.Errorf("the route %q is missing from the OpenAPI document (see openAPIPaths())", route)
+		}
+	}
+
+	// And ... nothing should be documented which isn't registered
+	for _, operation := range documented {
+		if !slices.Contains(routes, operation) {
+			t.Errorf("the OpenAPI document describes %q, which isn't a registered route", operation)
+		}
+	}
+}
+
+func TestOpenAPISchemas(t *testing.T) {
+	// Given ... the schemas generated from the structs used by the API,
+	// which panics if one of them has a field of an unsupported type
+	schemas := openAPIComponents()
+
+	t.Run("Request bodies", func(t *testing.T) {
+		// When ... we look at the schema generated from snippetCreateForm
+		input := schemas["SnippetInput"]
+
+		// Then ... it should have a property for each JSON field
+		assert.Equal(t, input.Properties["title"].Type, "string")
+		assert.Equal(t, input.Properties["burnAfterReading"].Type, "boolean")
+		assert.Equal(t, input.Properties["files"].Items.Ref, "#/components/schemas/SnippetFileInput")
+
+		// And ... the fields which are only used by the HTML form should be left out
+		for _, name := range []string{"addFile", "removeFile", "AddFile", "Validator", "FieldErrors"} {
+			_, ok := input.Properties[name]
+			assert.Equal(t, ok, false)
+		}
+
+		// And ... none of the fields should be required, as validation checks them
+		assert.Equal(t, len(input.Required), 0)
+	})
+
+	t.Run("Response bodies", func(t *testing.T) {
+		// When ... we look at the schema generated from apiSnippet
+		snippet := schemas["Snippet"]
+
+		// Then ... times should be date-times, and never-expiring snippets nullable
+		assert.Equal(t, snippet.Properties["created"].Format, "date-time")
+		assert.Equal(t, snippet.Properties["expires"].Nullable, true)
+
+		// And ... only the fields which are always sent should be required
+		assert.Equal(t, slices.Contains(snippet.Required, "id"), true)
+		assert.Equal(t, slices.Contains(snippet.Required, "files"), false)
+	})
+
+	t.Run("References", func(t *testing.T) {
+		// When ... we encode the whole document
+		js, err := json.Marshal(openAPIDocument())
+		assert.NilError(t, err)
+
+		// Then ... every schema referred to should exist
+		var refs []string
+		var walk func(v any)
+		walk = func(v any) {
+			switch v := v.(type) {
+			case map[string]any:
+				for key, value := range v {
+					if s, ok := value.(string); ok && key == "$ref" {
+						refs = append(refs, s)
+					}
+					walk(value)
+				}
+			case []any:
+				for _, value := range v {
+					walk(value)
+				}
+			}
+		}
+
+		var document any
+		assert.NilError(t, json.Unmarshal(js, &document))
+		walk(document)
+
+		assert.Equal(t, len(refs) > 0, true)
+		for _, ref := range refs {
+			_, ok := schemas[strings.TrimPrefix(ref, "#/components/schemas/")]
+			if !ok {
+				t.Errorf("the schema %q doesn't exist", ref)
+			}
+		}
+	})
+}
+
+func TestOpenAPIE2E(t *testing.T) {
+	endToEndTest(t)
+	// Given ... we have an application with a structured logger which discards everthing.
+	app := newTestApplication(t)
+
+	// And ... we have created a new test server
+	testServer := newTestServer(t, app.routes())
+	defer testServer.Close()
+
+	// When ... we fetch the OpenAPI document
+	code, headers, body := testServer.get(t, "/api/openapi.json")
+
+	// Then ... it should be returned as JSON
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, headers.Get("Content-Type"), "application/json")
+	assert.StringContains(t, body, `"openapi": "3.0.3"`)
+	assert.StringContains(t, body, `"/api/v1/snippets/{id}"`)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mixnblend/snippetbox/internal/models"
+	"github.com/mixnblend/snippetbox/internal/validator"
+)
+
+// The number of snippets shown on each page of a listing, unless the
+// per_page query string parameter says otherwise.
+const (
+	defaultPerPage = 20
+	maxPerPage     = 100
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// encodeCursor turns a cursor into an opaque token for the page query string
+// parameter. The token holds the direction, the creation time in
+// microseconds and the ID of the snippet.
+func encodeCursor(c models.SnippetCursor) string {
+	direction := "a"
+	if c.Backward {
+		direction = "b"
+	}
+
+	token := fmt.Sprintf("%s.%d.%d", direction, c.Created.UnixMicro(), c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(token))
+}
+
+// decodeCursor is the inverse of encodeCursor(). An empty token decodes to
+// the zero cursor, which selects the first page.
+func decodeCursor(token string) (models.SnippetCursor, error) {
+	if token == "" {
+		return models.SnippetCursor{}, nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return models.SnippetCursor{}, errInvalidCursor
+	}
+
+	parts := strings.Split(string(b), ".")
+	if len(parts) != 3 || (parts[0] != "a" && parts[0] != "b") {
+		return models.SnippetCursor{}, errInvalidCursor
+	}
+
+	micros, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return models.SnippetCursor{}, errInvalidCursor
+	}
+
+	id, err := strconv.Atoi(parts[2])
+	if err != nil || id < 1 {
+		return models.SnippetCursor{}, errInvalidCursor
+	}
+
+	return models.SnippetCursor{
+		Created:  time.UnixMicro(micros).UTC(),
+		ID:       id,
+		Backward: parts[0] == "b",
+	}, nil
+}
+
+// parseSnippetPage reads the page, per_page and sort query string parameters
+// of a snippet listing, applying the defaults for any which are missing.
+func parseSnippetPage(query url.Values) (models.SnippetPage, error) {
+	page := models.SnippetPage{Sort: models.SortNewest, Limit: defaultPerPage}
+
+	if sort := query.Get("sort"); sort != "" {
+		if !validator.PermittedValue(sort, models.SortNewest, models.SortOldest) {
+			return models.SnippetPage{}, fmt.Errorf("invalid sort %q", sort)
+		}
+		page.Sort = sort
+	}
+
+	if perPage := query.Get("per_page"); perPage != "" {
+		n, err := strconv.Atoi(perPage)
+		if err != nil || n < 1 || n > maxPerPage {
+			return models.SnippetPage{}, fmt.Errorf("invalid per_page %q", perPage)
+		}
+		page.Limit = n
+	}
+
+	cursor, err := decodeCursor(query.Get("page"))
+	if err != nil {
+		return models.SnippetPage{}, err
+	}
+	page.Cursor = cursor
+
+	return page, nil
+}
+
+// pagination holds the links between the pages of a snippet listing.
+type pagination struct {
+	Path    string
+	Sort    string
+	PerPage int
+	PrevURL string
+	NextURL string
+}
+
+// newPagination builds the links to the pages either side of list, which was
+// fetched from the listing at path using page.
+func newPagination(path string, page models.SnippetPage, list models.SnippetList) pagination {
+	p := pagination{Path: path, Sort: page.Sort, PerPage: page.Limit}
+
+	link := func(cursor models.SnippetCursor) string {
+		query := url.Values{}
+		query.Set("sort", page.Sort)
+		query.Set("per_page", strconv.Itoa(page.Limit))
+		query.Set("page", encodeCursor(cursor))
+		return path + "?" + query.Encode()
+	}
+
+	if list.Prev != nil {
+		p.PrevURL = link(*list.Prev)
+	}
+	if list.Next != nil {
+		p.NextURL = link(*list.Next)
+	}
+
+	return p
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/mixnblend/snippetbox/internal/assert"
+	"github.com/mixnblend/snippetbox/internal/models"
+)
+
+func TestCursor(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor models.SnippetCursor
+	}{
+		{
+			name:   "Forward",
+			cursor: models.SnippetCursor{Created: time.Date(2024, 3, 17, 10, 15, 0, 0, time.UTC), ID: 42},
+		},
+		{
+			name:   "Backward",
+			cursor: models.SnippetCursor{Created: time.Date(2024, 3, 17, 10, 15, 0, 0, time.UTC), ID: 7, Backward: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given ... we have encoded a cursor
+			token := encodeCursor(tt.cursor)
+
+			// When ... we decode it again
+			cursor, err := decodeCursor(token)
+
+			// Then ... we should get the same cursor back
+			assert.NilError(t, err)
+			assert.Equal(t, cursor, tt.cursor)
+		})
+	}
+
+	t.Run("Empty", func(t *testing.T) {
+		// When ... we decode an empty token
+		cursor, err := decodeCursor("")
+
+		// Then ... we should get the zero cursor
+		assert.NilError(t, err)
+		assert.Equal(t, cursor.IsZero(), true)
+	})
+
+	for _, token := range []string{"!!!", "YS4xLjA", "eC4xLjI", "YS54LjI"} {
+		t.Run("Invalid "+token, func(t *testing.T) {
+			// When ... we decode a malformed token
+			_, err := decodeCursor(token)
+
+			// Then ... it should be rejected
+			assert.Equal(t, err, errInvalidCursor)
+		})
+	}
+}
+
+func TestParseSnippetPage(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		wantSort  string
+		wantLimit int
+		wantErr   bool
+	}{
+		{name: "Defaults", query: "", wantSort: models.SortNewest, wantLimit: defaultPerPage},
+		{name: "Oldest first", query: "sort=oldest&per_page=5", wantSort: models.SortOldest, wantLimit: 5},
+		{name: "Maximum page size", query: "per_page=100", wantSort: models.SortNewest, wantLimit: maxPerPage},
+		{name: "Unknown sort", query: "sort=title", wantErr: true},
+		{name: "Page size too big", query: "per_page=101", wantErr: true},
+		{name: "Zero page size", query: "per_page=0", wantErr: true},
+		{name: "Invalid page size", query: "per_page=ten", wantErr: true},
+		{name: "Invalid cursor", query: "page=nope", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given ... we have a query string
+			query, err := url.ParseQuery(tt.query)
+			assert.NilError(t, err)
+
+			// When ... we parse it
+			page, err := parseSnippetPage(query)
+
+			// Then ... we should get the expected page or an error
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("got: nil; want: error")
+				}
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, page.Sort, tt.wantSort)
+			assert.Equal(t, page.Limit, tt.wantLimit)
+		})
+	}
+}
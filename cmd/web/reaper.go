@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/mixnblend/snippetbox/internal/models"
+)
+
+// The reap() method permanently deletes expired snippets, sessions and API
+// tokens, and old webhook deliveries. Rows are deleted in batches of app.reapBatchSize
+// until none are left, so that a large backlog doesn't hold locks on the
+// tables for a long time. The number of rows deleted is logged.
+func (app *application) reap() error {
+	snippets, err := reapBatches(app.deleteExpiredSnippets, app.reapBatchSize)
+	if err != nil {
+		return err
+	}
+
+	sessions, err := reapBatches(app.sessions.DeleteExpired, app.reapBatchSize)
+	if err != nil {
+		return err
+	}
+
+	tokens, err := reapBatches(app.tokens.DeleteExpired, app.reapBatchSize)
+	if err != nil {
+		return err
+	}
+
+	deliveries, err := reapBatches(app.webhooks.DeleteExpiredDeliveries, app.reapBatchSize)
+	if err != nil {
+		return err
+	}
+
+	app.logger.Info("reaped expired rows", "snippets", snippets, "sessions", sessions, "tokens", tokens, "deliveries", deliveries)
+
+	return nil
+}
+
+// The deleteExpiredSnippets() method deletes a batch of expired snippets,
+// and records an expired event for each one. The deliveries are recorded
+// rather than queued, as the reaper isn't in a hurry and may be running
+// without any webhook workers (as with the "reap" command); they're sent
+// once the server's workers find them. A failure to record them is logged
+// rather than stopping the reaper, as the snippets are already gone.
+func (app *application) deleteExpiredSnippets(limit int) (int, error) {
+	deleted, err := app.snippets.DeleteExpired(limit)
+
+	for _, snippet := range deleted {
+		e := webhookEvent{Type: models.EventSnippetExpired, Snippet: snippet, Time: time.Now().UTC()}
+
+		_, recordErr := app.recordWebhookEvent(e, time.Now())
+		if recordErr != nil {
+			app.logger.Error(recordErr.Error())
+		}
+	}
+
+	return len(deleted), err
+}
+
+// reapBatches calls deleteExpired with the given batch size until it deletes
+// fewer rows than that, and returns the total number of rows deleted.
+func reapBatches(deleteExpired func(limit int) (int, error), batchSize int) (int, error) {
+	total := 0
+
+	for {
+		n, err := deleteExpired(batchSize)
+		total += n
+		if err != nil {
+			return total, err
+		}
+
+		if n < batchSize {
+			return total, nil
+		}
+	}
+}
+
+// The runReaper() method calls reap() straight away, so that rows which
+// expired while the server was down don't hang around for another interval,
+// and then every interval until the context is cancelled. Errors are logged
+// rather than returned, so that one failed run doesn't stop the next.
+func (app *application) runReaper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := app.reap()
+		if err != nil {
+			app.logger.Error(err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mixnblend/snippetbox/internal/assert"
+)
+
+func TestReapBatches(t *testing.T) {
+	tests := []struct {
+		name      string
+		expired   int
+		batchSize int
+		wantCalls int
+	}{
+		{name: "Nothing expired", expired: 0, batchSize: 10, wantCalls: 1},
+		{name: "Less than a batch", expired: 7, batchSize: 10, wantCalls: 1},
+		{name: "Exactly one batch", expired: 10, batchSize: 10, wantCalls: 2},
+		{name: "Several batches", expired: 25, batchSize: 10, wantCalls: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given ... we have a table with some expired rows in it
+			remaining := tt.expired
+			calls := 0
+			deleteExpired := func(limit int) (int, error) {
+				calls++
+				n := min(remaining, limit)
+				remaining -= n
+				return n, nil
+			}
+
+			// When ... we reap them in batches
+			total, err := reapBatches(deleteExpired, tt.batchSize)
+
+			// Then ... every expired row should have been deleted, one batch
+			// at a time
+			assert.NilError(t, err)
+			assert.Equal(t, total, tt.expired)
+			assert.Equal(t, remaining, 0)
+			assert.Equal(t, calls, tt.wantCalls)
+		})
+	}
+
+	t.Run("Error", func(t *testing.T) {
+		// Given ... we have a table which fails on the second batch
+		wantErr := errors.New("connection lost")
+		calls := 0
+		deleteExpired := func(limit int) (int, error) {
+			calls++
+			if calls == 2 {
+				return 0, wantErr
+			}
+			return limit, nil
+		}
+
+		// When ... we reap it
+		total, err := reapBatches(deleteExpired, 10)
+
+		// Then ... the error should be returned along with the rows deleted
+		// before it happened
+		assert.Equal(t, err, wantErr)
+		assert.Equal(t, total, 10)
+	})
+}
+
+func TestRunReaper(t *testing.T) {
+	// Given ... we have an application
+	app := newTestApplication(t)
+
+	// When ... we run the reaper and then cancel its context
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		app.runReaper(ctx, time.Millisecond)
+		close(done)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	// Then ... the reaper should stop
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("reaper did not stop after its context was cancelled")
+	}
+}
+
+// reapCounter is a session model which reports each time its expired
+// sessions are deleted.
+type reapCounter struct {
+	reaped chan struct{}
+}
+
+func (m *reapCounter) DeleteExpired(limit int) (int, error) {
+	m.reaped <- struct{}{}
+	return 0, nil
+}
+
+func TestRunReaperAtStartup(t *testing.T) {
+	// Given ... we have an application which reports each reap
+	app := newTestApplication(t)
+	sessions := &reapCounter{reaped: make(chan struct{}, 1)}
+	app.sessions = sessions
+
+	// When ... we run the reaper with a long interval
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go app.runReaper(ctx, time.Hour)
+
+	// Then ... it should reap straight away, rather than waiting for the
+	// first tick
+	select {
+	case <-sessions.reaped:
+	case <-time.After(time.Second):
+		t.Fatal("reaper did not reap at startup")
+	}
+}
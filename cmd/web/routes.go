@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/justinas/alice"
+	"github.com/mixnblend/snippetbox/internal/models"
 	"github.com/mixnblend/snippetbox/ui"
 )
 
@@ -12,6 +13,7 @@ func (app *application) routes() http.Handler {
 	mux := http.NewServeMux()
 
 	mux.Handle("GET /static/", http.FileServerFS(ui.Files))
+	mux.HandleFunc("GET /static/css/highlight.css", app.highlightCSS)
 
 	mux.HandleFunc("GET /ping", ping)
 
@@ -22,7 +24,20 @@ func (app *application) routes() http.Handler {
 
 	mux.Handle("GET /{$}", dynamic.ThenFunc(app.home))
 	mux.Handle("GET /about", dynamic.ThenFunc(app.about))
+	mux.Handle("GET /snippets", dynamic.ThenFunc(app.snippetList))
+	mux.Handle("GET /search", dynamic.ThenFunc(app.snippetSearch))
+	mux.Handle("GET /tag/{name}", dynamic.ThenFunc(app.snippetListByTag))
 	mux.Handle("GET /snippet/view/{id}", dynamic.ThenFunc(app.snippetView))
+	mux.Handle("POST /snippet/view/{id}", dynamic.ThenFunc(app.snippetViewPost))
+	mux.Handle("POST /snippet/unlock/{id}", dynamic.ThenFunc(app.snippetUnlockPost))
+	mux.Handle("GET /snippet/view/{id}/history", dynamic.ThenFunc(app.snippetHistory))
+	mux.Handle("GET /snippet/view/{id}/diff", dynamic.ThenFunc(app.snippetDiff))
+	// GET routes also match HEAD requests, which the raw and download
+	// handlers answer without a body.
+	mux.Handle("GET /snippet/raw/{id}", dynamic.ThenFunc(app.snippetRaw))
+	mux.Handle("GET /snippet/download/{id}", dynamic.ThenFunc(app.snippetDownload))
+	mux.Handle("GET /snippet/zip/{id}", dynamic.ThenFunc(app.snippetArchive))
+	mux.Handle("GET /collection/{id}", dynamic.ThenFunc(app.collectionView))
 
 	mux.Handle("GET /user/signup", dynamic.ThenFunc(app.userSignup))
 	mux.Handle("POST /user/signup", dynamic.ThenFunc(app.userSignupPost))
@@ -34,12 +49,66 @@ func (app *application) routes() http.Handler {
 	protected := dynamic.Append(app.requireAuthentication)
 
 	mux.Handle("GET /account/view", protected.ThenFunc(app.accountView))
+	mux.Handle("GET /account/stars", protected.ThenFunc(app.accountStars))
+	mux.Handle("GET /account/tokens/create", protected.ThenFunc(app.accountTokenCreate))
+	mux.Handle("POST /account/tokens/create", protected.ThenFunc(app.accountTokenCreatePost))
+	mux.Handle("POST /account/tokens/revoke/{id}", protected.ThenFunc(app.accountTokenRevokePost))
+	mux.Handle("GET /account/webhooks/create", protected.ThenFunc(app.accountWebhookCreate))
+	mux.Handle("POST /account/webhooks/create", protected.ThenFunc(app.accountWebhookCreatePost))
+	mux.Handle("POST /account/webhooks/delete/{id}", protected.ThenFunc(app.accountWebhookDeletePost))
 	mux.Handle("GET /account/password/update", protected.ThenFunc(app.accountPasswordUpdate))
 	mux.Handle("POST /account/password/update", protected.ThenFunc(app.accountPasswordUpdatePost))
 	mux.Handle("GET /snippet/create", protected.ThenFunc(app.snippetCreate))
 	mux.Handle("POST /snippet/create", protected.ThenFunc(app.snippetCreatePost))
+	mux.Handle("POST /snippet/preview", protected.ThenFunc(app.snippetPreviewPost))
+	mux.Handle("POST /snippet/preview/{id}", protected.ThenFunc(app.snippetPreviewPost))
+	mux.Handle("GET /snippet/edit/{id}", protected.ThenFunc(app.snippetEdit))
+	mux.Handle("POST /snippet/edit/{id}", protected.ThenFunc(app.snippetEditPost))
+	mux.Handle("POST /snippet/delete/{id}", protected.ThenFunc(app.snippetDeletePost))
+	mux.Handle("POST /snippet/fork/{id}", protected.ThenFunc(app.snippetForkPost))
+	mux.Handle("POST /snippet/star/{id}", protected.ThenFunc(app.snippetStarPost))
+	mux.Handle("POST /snippet/unstar/{id}", protected.ThenFunc(app.snippetUnstarPost))
+	mux.Handle("POST /snippet/comment/{id}", protected.ThenFunc(app.snippetCommentPost))
+	mux.Handle("GET /comment/edit/{id}", protected.ThenFunc(app.commentEdit))
+	mux.Handle("POST /comment/edit/{id}", protected.ThenFunc(app.commentEditPost))
+	mux.Handle("POST /comment/delete/{id}", protected.ThenFunc(app.commentDeletePost))
+	mux.Handle("POST /snippet/collect/{id}", protected.ThenFunc(app.snippetCollectPost))
+	mux.Handle("GET /collection/create", protected.ThenFunc(app.collectionCreate))
+	mux.Handle("POST /collection/create", protected.ThenFunc(app.collectionCreatePost))
+	mux.Handle("GET /collection/edit/{id}", protected.ThenFunc(app.collectionEdit))
+	mux.Handle("POST /collection/edit/{id}", protected.ThenFunc(app.collectionEditPost))
+	mux.Handle("POST /collection/delete/{id}", protected.ThenFunc(app.collectionDeletePost))
+	mux.Handle("POST /collection/remove/{id}", protected.ThenFunc(app.collectionRemovePost))
+	mux.Handle("POST /collection/move/{id}", protected.ThenFunc(app.collectionMovePost))
 	mux.Handle("POST /user/logout", protected.ThenFunc(app.userLogoutPost))
 
+	// The JSON API has its own middleware chain, without the session and
+	// CSRF middleware used by the HTML pages. Clients send an API token with
+	// each request instead (see authenticateAPI), and the token's scopes
+	// decide which routes it can use.
+	api := alice.New(app.authenticateAPI)
+	readAPI := api.Append(app.requireAPIScope(models.ScopeSnippetsRead))
+	writeAPI := api.Append(app.requireAPIAuthentication, app.requireAPIScope(models.ScopeSnippetsWrite))
+
+	mux.Handle("GET /api/v1/snippets", readAPI.ThenFunc(app.apiSnippetList))
+	mux.Handle("GET /api/v1/snippets/{id}", readAPI.ThenFunc(app.apiSnippetGet))
+	mux.Handle("POST /api/v1/snippets", writeAPI.ThenFunc(app.apiSnippetCreate))
+	mux.Handle("PUT /api/v1/snippets/{id}", writeAPI.ThenFunc(app.apiSnippetUpdate))
+	mux.Handle("DELETE /api/v1/snippets/{id}", writeAPI.ThenFunc(app.apiSnippetDelete))
+
+	// The OpenAPI document describing the routes above is public, so that
+	// clients can be generated without a token.
+	mux.HandleFunc("GET /api/openapi.json", app.openAPI)
+
+	// Anything else under /api/ gets an error in the API's JSON envelope,
+	// rather than the ServeMux's plain text: 405 Method Not Allowed for the
+	// paths above, and 404 Not Found for everything else. The routes above
+	// have methods, so they take precedence over these.
+	mux.Handle("/api/v1/snippets", app.apiMethodNotAllowed(http.MethodGet, http.MethodPost))
+	mux.Handle("/api/v1/snippets/{id}", app.apiMethodNotAllowed(http.MethodGet, http.MethodPut, http.MethodDelete))
+	mux.Handle("/api/openapi.json", app.apiMethodNotAllowed(http.MethodGet))
+	mux.HandleFunc("/api/", app.apiNotFound)
+
 	// Create a middleware chain containing our 'standard' middleware
 	// which will be used for every request our application receives.
 	standard := alice.New(app.recoverPanic, app.logRequest, commonHeaders)
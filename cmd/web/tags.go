@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/mixnblend/snippetbox/internal/models"
+)
+
+// maxSnippetTags is the most tags a snippet can have.
+const maxSnippetTags = 10
+
+// The tag cloud on the home page shows up to tagCloudLimit of the most used
+// tags, in tagCloudSizes different sizes.
+const (
+	tagCloudLimit = 30
+	tagCloudSizes = 5
+)
+
+// tagPath returns the path of the page listing the snippets with a tag. Tags
+// may contain characters such as # which need escaping in a URL path.
+func tagPath(tag string) string {
+	return "/tag/" + url.PathEscape(tag)
+}
+
+// A tagCloudEntry is a tag in the tag cloud. Size runs from 1 for the least
+// used tags up to tagCloudSizes for the most used.
+type tagCloudEntry struct {
+	Name  string
+	Count int
+	Size  int
+}
+
+// newTagCloud sizes each tag according to how many snippets use it, scaled
+// between the least and most used tags, and sorts them alphabetically.
+func newTagCloud(counts []models.TagCount) []tagCloudEntry {
+	if len(counts) == 0 {
+		return nil
+	}
+
+	least, most := counts[0].Count, counts[0].Count
+	for _, c := range counts {
+		least = min(least, c.Count)
+		most = max(most, c.Count)
+	}
+
+	cloud := make([]tagCloudEntry, len(counts))
+	for i, c := range counts {
+		size := 1
+		if most > least {
+			size += (c.Count - least) * (tagCloudSizes - 1) / (most - least)
+		}
+		cloud[i] = tagCloudEntry{Name: c.Name, Count: c.Count, Size: size}
+	}
+
+	slices.SortFunc(cloud, func(a, b tagCloudEntry) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return cloud
+}
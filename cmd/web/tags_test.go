@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mixnblend/snippetbox/internal/assert"
+	"github.com/mixnblend/snippetbox/internal/models"
+)
+
+func TestTagPath(t *testing.T) {
+	assert.Equal(t, tagPath("go"), "/tag/go")
+	assert.Equal(t, tagPath("c#"), "/tag/c%23")
+	assert.Equal(t, tagPath("c++"), "/tag/c++")
+}
+
+func TestNewTagCloud(t *testing.T) {
+	t.Run("Empty", func(t *testing.T) {
+		assert.Equal(t, len(newTagCloud(nil)), 0)
+	})
+
+	t.Run("Sizes", func(t *testing.T) {
+		// Given ... we have some tags, most used first
+		counts := []models.TagCount{
+			{Name: "go", Count: 9},
+			{Name: "sql", Count: 5},
+			{Name: "k8s", Count: 1},
+		}
+
+		// When ... we build the tag cloud
+		cloud := newTagCloud(counts)
+
+		// Then ... the tags should be sorted by name and scaled by their use
+		assert.Equal(t, len(cloud), 3)
+		assert.Equal(t, cloud[0], tagCloudEntry{Name: "go", Count: 9, Size: tagCloudSizes})
+		assert.Equal(t, cloud[1], tagCloudEntry{Name: "k8s", Count: 1, Size: 1})
+		assert.Equal(t, cloud[2], tagCloudEntry{Name: "sql", Count: 5, Size: 3})
+	})
+
+	t.Run("Equally used", func(t *testing.T) {
+		// Given ... we have tags which are all used equally
+		counts := []models.TagCount{{Name: "go", Count: 2}, {Name: "sql", Count: 2}}
+
+		// When ... we build the tag cloud
+		cloud := newTagCloud(counts)
+
+		// Then ... they should all be the smallest size
+		assert.Equal(t, cloud[0].Size, 1)
+		assert.Equal(t, cloud[1].Size, 1)
+	})
+}
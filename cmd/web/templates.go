@@ -6,7 +6,10 @@ import (
 	"path/filepath"
 	"time"
 
+	"github.com/mixnblend/snippetbox/internal/diff"
+	"github.com/mixnblend/snippetbox/internal/highlight"
 	"github.com/mixnblend/snippetbox/internal/models"
+	"github.com/mixnblend/snippetbox/internal/search"
 	"github.com/mixnblend/snippetbox/ui"
 )
 
@@ -15,14 +18,46 @@ import (
 // At the moment it only contains one field, but we'll add more
 // to it as the build progresses.
 type templateData struct {
-	CurrentYear     int
-	Snippet         models.Snippet
-	Snippets        []models.Snippet
-	Form            any
-	Flash           string
-	IsAuthenticated bool
-	CSRFToken       string
-	User            models.User
+	CurrentYear         int
+	Snippet             models.Snippet
+	Snippets            []models.Snippet
+	Form                any
+	Flash               string
+	IsAuthenticated     bool
+	IsVerified          bool
+	AuthenticatedUserID int
+	CSRFToken           string
+	User                models.User
+	Revisions           []models.SnippetRevision
+	DiffFrom            models.SnippetRevision
+	DiffTo              models.SnippetRevision
+	DiffHunks           []diff.Hunk
+	Pagination          pagination
+	Query               string
+	SearchResults       []searchResult
+	Tag                 string
+	TagCloud            []tagCloudEntry
+	Files               []renderedFile
+	Preview             bool
+	Starred             bool
+	MostStarred         []models.Snippet
+	Comment             models.Comment
+	Comments            []commentView
+	Collection          models.Collection
+	Collections         []models.Collection
+	Tokens              []models.Token
+	NewToken            string
+	Webhooks            []models.Webhook
+	Deliveries          []models.WebhookDelivery
+	NewWebhook          models.Webhook
+}
+
+// A searchResult is a snippet found by a search, with the matching parts of
+// its title and an excerpt of its content highlighted.
+type searchResult struct {
+	Snippet models.Snippet
+	Title   []search.Fragment
+	Excerpt []search.Fragment
 }
 
 // Create a humanDate function which returns a nicely formatted string
@@ -35,11 +70,30 @@ func humanDate(t time.Time) string {
 	return t.UTC().Format("02 Jan 2006 at 15:04")
 }
 
+// isoDate returns the RFC 3339 representation of a time.Time object in UTC,
+// for use in machine-readable attributes such as <time datetime='...'>.
+func isoDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
+
+// languages returns the languages which can be chosen for a snippet, for
+// the language <select> in the snippet form.
+func languages() []highlight.Language {
+	return highlight.Languages
+}
+
 // Initialize a template.FuncMap object and store it in a global variable. This is
 // essentially a string-keyed map which acts as a lookup between the names of our
 // custom template functions and the functions themselves.
 var functions = template.FuncMap{
 	"humanDate": humanDate,
+	"isoDate":   isoDate,
+	"tagPath":   tagPath,
+	"languages": languages,
 }
 
 func newTemplateCache() (map[string]*template.Template, error) {
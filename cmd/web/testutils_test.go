@@ -10,6 +10,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"regexp"
+	"strings"
 	"testing"
 	"time"
 
@@ -36,9 +37,24 @@ func newTestApplication(t *testing.T) *application {
 		logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
 		snippets:       &mocks.SnippetModel{},
 		users:          &mocks.UserModel{},
+		sessions:       &mocks.SessionModel{},
+		stars:          &mocks.StarModel{},
+		comments:       &mocks.CommentModel{},
+		collections:    &mocks.CollectionModel{},
+		tokens:         &mocks.TokenModel{},
+		webhooks:       &mocks.WebhookModel{},
 		templateCache:  templateCache,
 		formDecoder:    formDecoder,
 		sessionManager: sessionManager,
+
+		maxUnverifiedLifetime: 7 * 24 * time.Hour,
+		reapBatchSize:         1000,
+		commentEditWindow:     15 * time.Minute,
+		webhookEvents:         make(chan webhookEvent, 100),
+		webhookClient:         newWebhookClient(false),
+		webhookMaxAttempts:    6,
+		webhookBackoff:        30 * time.Second,
+		baseURL:               "https://snippetbox.example.com",
 	}
 }
 
@@ -93,6 +109,64 @@ func (ts *testServer) get(t *testing.T, urlPath string) (int, http.Header, strin
 	return rs.StatusCode, rs.Header, string(body)
 }
 
+// The request() method works like get(), but lets the caller choose the HTTP
+// method and add request headers, e.g. for HEAD or conditional requests.
+func (ts *testServer) request(t *testing.T, method, urlPath string, header http.Header) (int, http.Header, string) {
+	req, err := http.NewRequest(method, ts.URL+urlPath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	rs, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer rs.Body.Close()
+	body, err := io.ReadAll(rs.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return rs.StatusCode, rs.Header, string(body)
+}
+
+// The sendJSON() method works like request(), but also sends body (if it
+// isn't empty) as a JSON request body, for testing the API.
+func (ts *testServer) sendJSON(t *testing.T, method, urlPath string, header http.Header, body string) (int, http.Header, string) {
+	var reqBody io.Reader
+	if body != "" {
+		reqBody = strings.NewReader(body)
+	}
+
+	req, err := http.NewRequest(method, ts.URL+urlPath, reqBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	rs, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer rs.Body.Close()
+	respBody, err := io.ReadAll(rs.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return rs.StatusCode, rs.Header, string(bytes.TrimSpace(respBody))
+}
+
 // Create a postForm method for sending POST requests to the test server. The
 // final parameter to this method is a url.Values object which can contain any
 // form data that you want to send in the request body.
@@ -128,6 +202,33 @@ func extractCSRFToken(t *testing.T, body string) string {
 	return html.UnescapeString(string(matches[1]))
 }
 
+// Create a login helper which fetches a CSRF token from the login page and
+// then logs in as the mock user with valid credentials. The session cookie is
+// stored in the test server client's cookie jar, so subsequent requests are
+// authenticated.
+func (ts *testServer) login(t *testing.T) {
+	t.Helper()
+	ts.loginAs(t, mocks.ValidUserCredentials)
+}
+
+// The loginAs helper logs in as the mock user with the given credentials.
+func (ts *testServer) loginAs(t *testing.T, credentials *mocks.UserCredentials) {
+	t.Helper()
+
+	_, _, body := ts.get(t, "/user/login")
+	validCSRFToken := extractCSRFToken(t, body)
+
+	form := url.Values{}
+	form.Add("email", credentials.UserName)
+	form.Add("password", credentials.Password)
+	form.Add("csrf_token", validCSRFToken)
+
+	code, _, _ := ts.postForm(t, "/user/login", form)
+	if code != http.StatusSeeOther {
+		t.Fatalf("login failed with status %d", code)
+	}
+}
+
 func endToEndTest(t *testing.T) {
 	t.Helper()
 	if testing.Short() {
@@ -141,3 +242,20 @@ func integrationTest(t *testing.T) {
 		t.Skip("skipping integration test")
 	}
 }
+
+// Define a regular expression which captures the href of a pagination link
+// with the given class.
+var paginationLinkRX = regexp.MustCompile(`<a href='([^']+)' class='(prev|next)'>`)
+
+// extractLink returns the URL of the "prev" or "next" pagination link in the
+// body.
+func extractLink(t *testing.T, body, class string) string {
+	for _, matches := range paginationLinkRX.FindAllStringSubmatch(body, -1) {
+		if matches[2] == class {
+			return html.UnescapeString(matches[1])
+		}
+	}
+
+	t.Fatalf("no %s link found in body", class)
+	return ""
+}
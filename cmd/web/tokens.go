@@ -0,0 +1,53 @@
+package main
+
+import (
+	"slices"
+	"time"
+
+	"github.com/mixnblend/snippetbox/internal/models"
+	"github.com/mixnblend/snippetbox/internal/validator"
+)
+
+// tokenLifetimes are the lifetimes offered for new API tokens. Tokens always
+// expire, so that a forgotten token doesn't work for ever.
+var tokenLifetimes = []string{"7d", "30d", "90d", "365d"}
+
+// The tokenForm is used to create a personal API token.
+type tokenForm struct {
+	Name                string   `form:"name"`
+	Scopes              []string `form:"scopes"`
+	Expires             string   `form:"expires"`
+	validator.Validator `form:"-"`
+}
+
+func (form *tokenForm) validate() {
+	form.CheckField(validator.NotBlank(form.Name), "name", "This field cannot be blank")
+	form.CheckField(validator.MaxChars(form.Name, 100), "name", "This field cannot be more than 100 characters long")
+	form.CheckField(len(form.Scopes) > 0, "scopes", "Choose at least one scope")
+	for _, scope := range form.Scopes {
+		form.CheckField(validator.PermittedValue(scope, models.Scopes...), "scopes", "Choose from the listed scopes")
+	}
+	form.CheckField(validator.PermittedValue(form.Expires, tokenLifetimes...), "expires", "Choose one of the listed lifetimes")
+}
+
+// HasScope reports whether the scope is ticked in the form.
+func (form tokenForm) HasScope(scope string) bool {
+	return slices.Contains(form.Scopes, scope)
+}
+
+// input converts the validated form into the fields expected by the token
+// model, with the token expiring the chosen lifetime after now.
+func (form *tokenForm) input(now time.Time) models.TokenInput {
+	// The lifetimes are all permitted values, so they always parse.
+	lifetime, _ := parseLifetime(form.Expires)
+
+	// Store each scope once, in a predictable order.
+	scopes := slices.Clone(form.Scopes)
+	slices.Sort(scopes)
+
+	return models.TokenInput{
+		Name:    form.Name,
+		Scopes:  slices.Compact(scopes),
+		Expires: now.Add(lifetime),
+	}
+}
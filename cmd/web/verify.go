@@ -0,0 +1,28 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mixnblend/snippetbox/internal/models"
+)
+
+// The verify() method marks the accounts with the given email addresses as
+// verified, which lifts the limit on how long their snippets can be kept
+// (see expiryPolicy). It stops at the first address which doesn't belong to
+// an account.
+func (app *application) verify(emails []string) error {
+	for _, email := range emails {
+		err := app.users.Verify(email)
+		if err != nil {
+			if errors.Is(err, models.ErrNoRecord) {
+				return fmt.Errorf("no account has the email address %q", email)
+			}
+			return err
+		}
+
+		app.logger.Info("verified account", "email", email)
+	}
+
+	return nil
+}
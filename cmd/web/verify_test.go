@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mixnblend/snippetbox/internal/assert"
+	"github.com/mixnblend/snippetbox/internal/models/mocks"
+)
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name    string
+		emails  []string
+		wantErr string
+	}{
+		{
+			name:   "Known accounts",
+			emails: []string{mocks.ValidUserCredentials.UserName, mocks.UnverifiedUserCredentials.UserName},
+		},
+		{
+			name:    "Unknown account",
+			emails:  []string{mocks.UnverifiedUserCredentials.UserName, "nobody@example.com"},
+			wantErr: `no account has the email address "nobody@example.com"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given ... we have an application
+			app := newTestApplication(t)
+
+			// When ... we verify the accounts
+			err := app.verify(tt.emails)
+
+			// Then ... any address without an account should be reported
+			if tt.wantErr == "" {
+				assert.NilError(t, err)
+			} else {
+				assert.Equal(t, err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
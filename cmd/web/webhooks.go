@@ -0,0 +1,435 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/mixnblend/snippetbox/internal/models"
+	"github.com/mixnblend/snippetbox/internal/validator"
+)
+
+// Webhooks tell other services (such as a chat bridge) when something
+// happens to a user's snippets. Handlers never send them directly: they
+// queue a webhookEvent on app.webhookEvents, which doesn't block, and a pool
+// of workers started by runWebhooks() records a delivery for each of the
+// user's webhooks and sends it. Failed deliveries are retried with
+// exponential backoff by the same workers, which poll the database for
+// deliveries which are due, so retries also survive a restart.
+
+// maxWebhooks is the most webhooks a user can have.
+const maxWebhooks = 5
+
+// webhookLease is how long a delivery is left alone while it's being sent,
+// after which it's tried again. It must be longer than the webhook client's
+// timeout.
+const webhookLease = 5 * time.Minute
+
+// maxWebhookBackoff is the longest a failed delivery waits before it's
+// retried, however many times it has failed.
+const maxWebhookBackoff = 24 * time.Hour
+
+// webhookPollInterval is how often the database is checked for deliveries
+// which are due to be retried.
+const webhookPollInterval = 10 * time.Second
+
+// The headers sent with each delivery. The signature is the hex-encoded
+// HMAC-SHA256 of the body, keyed with the webhook's secret and prefixed with
+// "sha256=". The delivery ID is the same for each attempt, so that
+// receivers can ignore repeats.
+const (
+	webhookEventHeader     = "X-Snippetbox-Event"
+	webhookDeliveryHeader  = "X-Snippetbox-Delivery"
+	webhookSignatureHeader = "X-Snippetbox-Signature"
+)
+
+// The webhookForm is used to add a webhook.
+type webhookForm struct {
+	URL                 string   `form:"url"`
+	Events              []string `form:"events"`
+	validator.Validator `form:"-"`
+}
+
+func (form *webhookForm) validate() {
+	form.CheckField(validator.NotBlank(form.URL), "url", "This field cannot be blank")
+	form.CheckField(validator.MaxChars(form.URL, 2048), "url", "This field cannot be more than 2048 characters long")
+	form.CheckField(validWebhookURL(form.URL), "url", "This field must be an http:// or https:// URL")
+	form.CheckField(len(form.Events) > 0, "events", "Choose at least one event")
+	for _, event := range form.Events {
+		form.CheckField(validator.PermittedValue(event, models.WebhookEvents...), "events", "Choose from the listed events")
+	}
+}
+
+// validWebhookURL reports whether s is an absolute http:// or https:// URL,
+// without a username or password.
+func validWebhookURL(s string) bool {
+	u, err := url.Parse(strings.TrimSpace(s))
+	if err != nil {
+		return false
+	}
+
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && u.User == nil
+}
+
+// HasEvent reports whether the event is ticked in the form.
+func (form webhookForm) HasEvent(event string) bool {
+	return slices.Contains(form.Events, event)
+}
+
+// input converts the validated form into the fields expected by the webhook
+// model, with the events in their usual order.
+func (form *webhookForm) input() models.WebhookInput {
+	var events []string
+	for _, event := range models.WebhookEvents {
+		if form.HasEvent(event) {
+			events = append(events, event)
+		}
+	}
+
+	return models.WebhookInput{URL: strings.TrimSpace(form.URL), Events: events}
+}
+
+// A webhookEvent is something which happened to a user's snippet. The
+// snippets of created and updated events are read back from the database
+// before they're sent, so that every handler sends the same thing whatever
+// it had to hand, while deleted and expired snippets are sent as they were
+// just before they were deleted. URL is the address of the snippet's page,
+// if it still has one.
+type webhookEvent struct {
+	Type    string
+	Snippet models.Snippet
+	Reload  bool
+	URL     string
+	Time    time.Time
+}
+
+// A webhookPayload is the JSON body of a delivery. The snippet is the same
+// as in the JSON API, without its files.
+type webhookPayload struct {
+	Event   string     `json:"event"`
+	Time    time.Time  `json:"time"`
+	Snippet apiSnippet `json:"snippet"`
+	URL     string     `json:"url,omitempty"`
+}
+
+// The newSnippetEvent() method returns an event for a change to a snippet.
+// Only the short ID and owner of created and updated snippets are kept, as
+// the rest is read back from the database when the event is sent. The link
+// to the snippet's page uses app.baseURL rather than the request's Host
+// header, which is chosen by the client.
+func (app *application) newSnippetEvent(eventType string, snippet models.Snippet) webhookEvent {
+	e := webhookEvent{
+		Type:    eventType,
+		Snippet: snippet,
+		Reload:  eventType == models.EventSnippetCreated || eventType == models.EventSnippetUpdated,
+		Time:    time.Now().UTC(),
+	}
+
+	if e.Reload {
+		e.Snippet = models.Snippet{ShortID: snippet.ShortID, UserID: snippet.UserID}
+		e.URL = fmt.Sprintf("%s/snippet/view/%s", app.baseURL, snippet.ShortID)
+	}
+
+	return e
+}
+
+// The queueWebhookEvent() method passes an event to the webhook workers
+// without waiting for it to be sent, so that it doesn't slow down the
+// request. If the queue is full the event is dropped, and an error logged.
+func (app *application) queueWebhookEvent(e webhookEvent) {
+	// Anonymous snippets have no one to tell.
+	if e.Snippet.UserID == 0 {
+		return
+	}
+
+	select {
+	case app.webhookEvents <- e:
+	default:
+		app.logger.Error("webhook queue is full; dropping event", "event", e.Type, "snippet", e.Snippet.ShortID)
+	}
+}
+
+// The recordWebhookEvent() method adds a delivery of the event for each of
+// the snippet owner's webhooks which is sent it, to be attempted at
+// nextAttempt, and returns them.
+func (app *application) recordWebhookEvent(e webhookEvent, nextAttempt time.Time) ([]models.WebhookDelivery, error) {
+	webhooks, err := app.webhooks.Subscribed(e.Snippet.UserID, e.Type)
+	if err != nil || len(webhooks) == 0 {
+		return nil, err
+	}
+
+	snippet := e.Snippet
+	if e.Reload {
+		snippet, err = app.snippets.Get(e.Snippet.ShortID)
+		if err != nil {
+			// The snippet may have been deleted (or have expired) since,
+			// in which case there'll be another event for that.
+			if errors.Is(err, models.ErrNoRecord) {
+				return nil, nil
+			}
+			return nil, err
+		}
+	}
+
+	payload, err := json.Marshal(webhookPayload{
+		Event:   e.Type,
+		Time:    e.Time,
+		Snippet: newAPISnippet(snippet, false),
+		URL:     e.URL,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]models.WebhookDelivery, 0, len(webhooks))
+
+	for _, webhook := range webhooks {
+		id, err := app.webhooks.InsertDelivery(webhook.ID, e.Type, payload, nextAttempt)
+		if err != nil {
+			return nil, err
+		}
+
+		deliveries = append(deliveries, models.WebhookDelivery{
+			ID:          id,
+			WebhookID:   webhook.ID,
+			URL:         webhook.URL,
+			Secret:      webhook.Secret,
+			Event:       e.Type,
+			Payload:     payload,
+			Status:      models.DeliveryPending,
+			NextAttempt: nextAttempt,
+		})
+	}
+
+	return deliveries, nil
+}
+
+// The runWebhooks() method runs the given number of workers, which send
+// the events queued by handlers and retry failed deliveries, until the
+// context is cancelled. Any events still queued then are recorded, so that
+// they're sent after the next start.
+func (app *application) runWebhooks(ctx context.Context, workers int, pollInterval time.Duration) {
+	due := make(chan models.WebhookDelivery)
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			app.webhookWorker(ctx, due)
+		}()
+	}
+
+	app.pollWebhookDeliveries(ctx, due, pollInterval)
+	wg.Wait()
+
+	for {
+		select {
+		case e := <-app.webhookEvents:
+			_, err := app.recordWebhookEvent(e, time.Now())
+			if err != nil {
+				app.logger.Error(err.Error())
+			}
+		default:
+			return
+		}
+	}
+}
+
+// The webhookWorker() method sends new events and due deliveries until the
+// context is cancelled.
+func (app *application) webhookWorker(ctx context.Context, due <-chan models.WebhookDelivery) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-app.webhookEvents:
+			// The deliveries are leased straight away, so that the poller
+			// doesn't pick them up while they're being sent here.
+			deliveries, err := app.recordWebhookEvent(e, time.Now().Add(webhookLease))
+			if err != nil {
+				app.logger.Error(err.Error())
+				continue
+			}
+
+			for _, d := range deliveries {
+				app.deliverWebhook(d)
+			}
+		case d := <-due:
+			app.deliverWebhook(d)
+		}
+	}
+}
+
+// The pollWebhookDeliveries() method passes deliveries which are due to be
+// retried to the workers every interval, until the context is cancelled.
+func (app *application) pollWebhookDeliveries(ctx context.Context, due chan<- models.WebhookDelivery, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deliveries, err := app.webhooks.ClaimDue(100, webhookLease)
+			if err != nil {
+				app.logger.Error(err.Error())
+				continue
+			}
+
+			for _, d := range deliveries {
+				select {
+				case due <- d:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}
+}
+
+// The deliverWebhook() method attempts to send a delivery and records the
+// result. Failed deliveries are retried after webhookBackoff(), until
+// app.webhookMaxAttempts have been made.
+func (app *application) deliverWebhook(d models.WebhookDelivery) {
+	status, err := app.sendWebhook(d)
+
+	attempt := models.WebhookAttempt{Status: models.DeliverySucceeded, ResponseStatus: status}
+
+	if err != nil {
+		attempts := d.Attempts + 1
+		attempt.Error = err.Error()
+
+		if attempts < app.webhookMaxAttempts {
+			attempt.Status = models.DeliveryPending
+			attempt.NextAttempt = time.Now().Add(webhookBackoff(app.webhookBackoff, attempts))
+		} else {
+			attempt.Status = models.DeliveryFailed
+		}
+
+		app.logger.Warn("webhook delivery failed", "delivery", d.ID, "attempts", attempts, "error", err.Error())
+	}
+
+	err = app.webhooks.RecordAttempt(d.ID, attempt)
+	if err != nil {
+		app.logger.Error(err.Error())
+	}
+}
+
+// webhookBackoff returns how long to wait before retrying a delivery which
+// has failed the given number of times: base after the first failure, and
+// twice as long after each one after that, up to maxWebhookBackoff. The
+// delay is doubled one step at a time, rather than shifted by attempts, so
+// that a large -webhook-attempts can't overflow it.
+func webhookBackoff(base time.Duration, attempts int) time.Duration {
+	backoff := base
+	for i := 1; i < attempts && backoff < maxWebhookBackoff; i++ {
+		backoff *= 2
+	}
+
+	return min(backoff, maxWebhookBackoff)
+}
+
+// signWebhook returns the signature of a payload, as sent in the
+// webhookSignatureHeader.
+func signWebhook(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// The sendWebhook() method POSTs a delivery's payload to its webhook, and
+// returns the status code of the response, or 0 if there wasn't one. Any
+// response other than 2xx is an error.
+func (app *application) sendWebhook(d models.WebhookDelivery) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "snippetbox-webhooks")
+	req.Header.Set(webhookEventHeader, d.Event)
+	req.Header.Set(webhookDeliveryHeader, strconv.Itoa(d.ID))
+	req.Header.Set(webhookSignatureHeader, signWebhook(d.Secret, d.Payload))
+
+	res, err := app.webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	// Read (some of) the body, so that the connection can be reused.
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("the webhook responded with %s", res.Status)
+	}
+
+	return res.StatusCode, nil
+}
+
+// errPrivateAddress is returned when a webhook resolves to an address which
+// isn't on the public internet.
+var errPrivateAddress = errors.New("webhooks can't be sent to private addresses")
+
+// newWebhookClient returns the HTTP client used to send webhooks. As users
+// choose the URLs, the client refuses to connect to loopback, private and
+// link-local addresses (such as the cloud metadata service), so that
+// webhooks can't be used to reach the server's own network, unless
+// allowPrivate is true. The address is checked after the host name is
+// resolved, so a public name which resolves to a private address is refused
+// too. Redirects aren't followed, and there's no proxy, for the same
+// reason.
+func newWebhookClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			if allowPrivate {
+				return nil
+			}
+
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			ip := net.ParseIP(host)
+			if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+				ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+				return errPrivateAddress
+			}
+
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 5 * time.Second,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     time.Minute,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mixnblend/snippetbox/internal/assert"
+	"github.com/mixnblend/snippetbox/internal/models"
+	"github.com/mixnblend/snippetbox/internal/models/mocks"
+)
+
+// fakeWebhooks is a webhook model which keeps its deliveries in memory, so
+// that tests can follow them through the workers. Each recorded attempt is
+// also sent on the attempts channel.
+type fakeWebhooks struct {
+	mocks.WebhookModel
+	url      string
+	attempts chan models.WebhookAttempt
+
+	mu         sync.Mutex
+	deliveries []models.WebhookDelivery
+}
+
+func newFakeWebhooks(url string) *fakeWebhooks {
+	return &fakeWebhooks{url: url, attempts: make(chan models.WebhookAttempt, 10)}
+}
+
+func (m *fakeWebhooks) Subscribed(userID int, event string) ([]models.Webhook, error) {
+	return []models.Webhook{{ID: 1, UserID: userID, URL: m.url, Secret: "whsec_test", Events: models.WebhookEvents}}, nil
+}
+
+func (m *fakeWebhooks) InsertDelivery(webhookID int, event string, payload []byte, nextAttempt time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.deliveries = append(m.deliveries, models.WebhookDelivery{
+		ID:          len(m.deliveries) + 1,
+		WebhookID:   webhookID,
+		URL:         m.url,
+		Secret:      "whsec_test",
+		Event:       event,
+		Payload:     payload,
+		Status:      models.DeliveryPending,
+		NextAttempt: nextAttempt,
+	})
+
+	return len(m.deliveries), nil
+}
+
+func (m *fakeWebhooks) ClaimDue(limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var due []models.WebhookDelivery
+	for i, d := range m.deliveries {
+		if d.Status == models.DeliveryPending && !d.NextAttempt.After(time.Now()) && len(due) < limit {
+			m.deliveries[i].NextAttempt = time.Now().Add(lease)
+			due = append(due, d)
+		}
+	}
+
+	return due, nil
+}
+
+func (m *fakeWebhooks) RecordAttempt(id int, attempt models.WebhookAttempt) error {
+	m.mu.Lock()
+	d := &m.deliveries[id-1]
+	d.Status = attempt.Status
+	d.Attempts++
+	d.ResponseStatus = attempt.ResponseStatus
+	d.Error = attempt.Error
+	d.NextAttempt = attempt.NextAttempt
+	m.mu.Unlock()
+
+	m.attempts <- attempt
+	return nil
+}
+
+// nextAttempt waits for the workers to record an attempt.
+func (m *fakeWebhooks) nextAttempt(t *testing.T) models.WebhookAttempt {
+	t.Helper()
+
+	select {
+	case attempt := <-m.attempts:
+		return attempt
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a webhook delivery")
+		return models.WebhookAttempt{}
+	}
+}
+
+// startWebhooks runs the application's webhook workers, polling every
+// millisecond, until the test finishes.
+func startWebhooks(t *testing.T, app *application) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		app.runWebhooks(ctx, 2, time.Millisecond)
+		close(done)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts int
+		want     time.Duration
+	}{
+		{name: "First failure", attempts: 1, want: 30 * time.Second},
+		{name: "Second failure", attempts: 2, want: time.Minute},
+		{name: "Fifth failure", attempts: 5, want: 8 * time.Minute},
+		{name: "Capped", attempts: 20, want: maxWebhookBackoff},
+		{name: "Past the width of a duration", attempts: 100, want: maxWebhookBackoff},
+		{name: "Many failures", attempts: math.MaxInt, want: maxWebhookBackoff},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, webhookBackoff(30*time.Second, tt.attempts), tt.want)
+		})
+	}
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	// Given ... we have a receiver which records the requests it's sent
+	requests := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	receiver := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- r
+		bodies <- body
+	}))
+	defer receiver.Close()
+
+	// And ... an application whose user has a webhook pointing at it
+	app := newTestApplication(t)
+	webhooks := newFakeWebhooks(receiver.URL)
+	app.webhooks = webhooks
+	app.webhookClient = receiver.Client()
+	startWebhooks(t, app)
+
+	// When ... the user creates a snippet
+	app.queueWebhookEvent(app.newSnippetEvent(models.EventSnippetCreated, models.Snippet{ShortID: "silentPond", UserID: 1}))
+
+	// Then ... the delivery should succeed
+	attempt := webhooks.nextAttempt(t)
+	assert.Equal(t, attempt.Status, models.DeliverySucceeded)
+	assert.Equal(t, attempt.ResponseStatus, http.StatusOK)
+
+	// And ... it should say what happened
+	req := <-requests
+	body := <-bodies
+	assert.Equal(t, req.Method, http.MethodPost)
+	assert.Equal(t, req.Header.Get("Content-Type"), "application/json")
+	assert.Equal(t, req.Header.Get(webhookEventHeader), models.EventSnippetCreated)
+	assert.Equal(t, req.Header.Get(webhookDeliveryHeader), "1")
+
+	// And ... it should be signed with the webhook's secret
+	assert.Equal(t, req.Header.Get(webhookSignatureHeader), signWebhook("whsec_test", body))
+
+	// And ... the snippet should be read back, with a link to its page on
+	// the configured site
+	var payload webhookPayload
+	err := json.Unmarshal(body, &payload)
+	assert.NilError(t, err)
+	assert.Equal(t, payload.Event, models.EventSnippetCreated)
+	assert.Equal(t, payload.Snippet.ID, "silentPond")
+	assert.Equal(t, payload.Snippet.Title, "An old silent pond")
+	assert.Equal(t, payload.URL, "https://snippetbox.example.com/snippet/view/silentPond")
+}
+
+func TestWebhookRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		responses    []int
+		wantStatuses []string
+	}{
+		{
+			name:         "Succeeds on retry",
+			responses:    []int{http.StatusInternalServerError, http.StatusOK},
+			wantStatuses: []string{models.DeliveryPending, models.DeliverySucceeded},
+		},
+		{
+			name:         "Gives up",
+			responses:    []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway},
+			wantStatuses: []string{models.DeliveryPending, models.DeliveryPending, models.DeliveryFailed},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given ... we have a receiver which responds with each status
+			// in turn
+			var mu sync.Mutex
+			responses := tt.responses
+			receiver := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				w.WriteHeader(responses[0])
+				responses = responses[1:]
+			}))
+			defer receiver.Close()
+
+			// And ... an application which retries three times, straight
+			// away
+			app := newTestApplication(t)
+			webhooks := newFakeWebhooks(receiver.URL)
+			app.webhooks = webhooks
+			app.webhookClient = receiver.Client()
+			app.webhookMaxAttempts = 3
+			app.webhookBackoff = time.Millisecond
+			startWebhooks(t, app)
+
+			// When ... the user deletes a snippet
+			app.queueWebhookEvent(app.newSnippetEvent(models.EventSnippetDeleted, models.Snippet{ShortID: "silentPond", UserID: 1}))
+
+			// Then ... each attempt should be recorded, until one succeeds
+			// or the attempts run out
+			for i, want := range tt.wantStatuses {
+				attempt := webhooks.nextAttempt(t)
+				assert.Equal(t, attempt.Status, want)
+				assert.Equal(t, attempt.ResponseStatus, tt.responses[i])
+			}
+		})
+	}
+}
+
+func TestWebhookEventQueue(t *testing.T) {
+	t.Run("Anonymous snippet", func(t *testing.T) {
+		// Given ... we have an application
+		app := newTestApplication(t)
+
+		// When ... an anonymous snippet is created
+		app.queueWebhookEvent(app.newSnippetEvent(models.EventSnippetCreated, models.Snippet{ShortID: "newSnippet"}))
+
+		// Then ... there's no one to tell, so nothing should be queued
+		assert.Equal(t, len(app.webhookEvents), 0)
+	})
+
+	t.Run("Full queue", func(t *testing.T) {
+		// Given ... we have an application whose queue is full, and which
+		// logs to a buffer
+		app := newTestApplication(t)
+		var logs bytes.Buffer
+		app.logger = slog.New(slog.NewTextHandler(&logs, nil))
+		app.webhookEvents = make(chan webhookEvent, 1)
+		app.queueWebhookEvent(app.newSnippetEvent(models.EventSnippetCreated, models.Snippet{ShortID: "silentPond", UserID: 1}))
+
+		// When ... another event is queued
+		app.queueWebhookEvent(app.newSnippetEvent(models.EventSnippetUpdated, models.Snippet{ShortID: "silentPond", UserID: 1}))
+
+		// Then ... it should be dropped rather than block the request
+		assert.Equal(t, len(app.webhookEvents), 1)
+
+		// And ... the dropped event should be logged as an error
+		assert.StringContains(t, logs.String(), `level=ERROR msg="webhook queue is full; dropping event" event=snippet.updated snippet=silentPond`)
+	})
+}
+
+func TestWebhookEventsE2E(t *testing.T) {
+	endToEndTest(t)
+	// Given ... we have an application with a structured logger which discards everthing.
+	app := newTestApplication(t)
+
+	// And ... we have created a new test server
+	testServer := newTestServer(t, app.routes())
+	defer testServer.Close()
+
+	// When ... a snippet is deleted through the API
+	code, _, _ := testServer.request(t, http.MethodDelete, "/api/v1/snippets/silentPond", bearer(mocks.ValidToken))
+	assert.Equal(t, code, http.StatusNoContent)
+
+	// Then ... an event should be queued for its owner's webhooks
+	e := nextWebhookEvent(t, app)
+	assert.Equal(t, e.Type, models.EventSnippetDeleted)
+	assert.Equal(t, e.Snippet.ShortID, "silentPond")
+	assert.Equal(t, e.Snippet.UserID, 1)
+
+	// When ... a snippet is created through the API
+	code, _, _ = testServer.sendJSON(t, http.MethodPost, "/api/v1/snippets", bearer(mocks.ValidToken),
+		`{"title": "O snail", "files": [{"content": "O snail"}], "expires": "7d", "visibility": "public"}`)
+	assert.Equal(t, code, http.StatusCreated)
+	apiEvent := nextWebhookEvent(t, app)
+
+	// And ... one is created and another forked through the web pages, with
+	// a Host header which isn't the site's
+	testServer.login(t)
+	_, _, body := testServer.get(t, "/snippet/create")
+	form := url.Values{}
+	form.Add("title", "O snail")
+	form.Add("files[0].content", "O snail")
+	form.Add("expires", "7d")
+	form.Add("visibility", "public")
+	form.Add("csrf_token", extractCSRFToken(t, body))
+
+	code, _, _ = testServer.postForm(t, "/snippet/create", form)
+	assert.Equal(t, code, http.StatusSeeOther)
+	createEvent := nextWebhookEvent(t, app)
+
+	code, _, _ = testServer.postForm(t, "/snippet/fork/wintryWood", form)
+	assert.Equal(t, code, http.StatusSeeOther)
+	forkEvent := nextWebhookEvent(t, app)
+
+	// Then ... each should queue the same event, which links to the snippet
+	// on the configured site rather than the host the request was sent to
+	for _, e := range []webhookEvent{apiEvent, createEvent, forkEvent} {
+		assert.Equal(t, e.Type, models.EventSnippetCreated)
+		assert.Equal(t, e.Reload, true)
+		assert.Equal(t, e.Snippet.ShortID, "newSnippet")
+		assert.Equal(t, e.Snippet.UserID, 1)
+		assert.Equal(t, e.Snippet.Title, "")
+		assert.Equal(t, e.URL, "https://snippetbox.example.com/snippet/view/newSnippet")
+	}
+}
+
+// nextWebhookEvent returns the next event in the application's webhook
+// queue, failing the test if there isn't one.
+func nextWebhookEvent(t *testing.T, app *application) webhookEvent {
+	t.Helper()
+
+	select {
+	case e := <-app.webhookEvents:
+		return e
+	default:
+		t.Fatal("no webhook event was queued")
+		return webhookEvent{}
+	}
+}
+
+func TestWebhookClientPrivateAddress(t *testing.T) {
+	// Given ... we have a receiver on the loopback address
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer receiver.Close()
+
+	// And ... an application using the client which refuses private
+	// addresses
+	app := newTestApplication(t)
+	app.webhookClient = newWebhookClient(false)
+
+	// When ... a delivery is sent to the receiver
+	status, err := app.sendWebhook(models.WebhookDelivery{ID: 1, URL: receiver.URL, Secret: "whsec_test", Payload: []byte("{}")})
+
+	// Then ... it should be refused without connecting
+	assert.Equal(t, status, 0)
+	assert.Equal(t, errors.Is(err, errPrivateAddress), true)
+}
@@ -0,0 +1,327 @@
+// Package diff computes line-level differences between two texts using the
+// linear-space variant of the Myers O(ND) algorithm, and groups them into
+// unified diff hunks.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Op describes what happened to a line between the old and new text.
+type Op int
+
+const (
+	Equal Op = iota
+	Insert
+	Delete
+)
+
+// Line is a single line of a diff. OldLine and NewLine are the 1-based line
+// numbers of the line in the old and new text, and are 0 when the line does
+// not appear on that side (e.g. OldLine is 0 for an inserted line).
+type Line struct {
+	Op      Op
+	Text    string
+	OldLine int
+	NewLine int
+}
+
+// Hunk is a contiguous group of changed lines along with their surrounding
+// context, in the same shape as a hunk in a unified diff.
+type Hunk struct {
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	Lines    []Line
+}
+
+// Header returns the "@@ -a,b +c,d @@" range header for the hunk.
+func (h Hunk) Header() string {
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.OldStart, h.OldLines, h.NewStart, h.NewLines)
+}
+
+// Added reports whether the line was inserted into the new text.
+func (l Line) Added() bool {
+	return l.Op == Insert
+}
+
+// Removed reports whether the line was deleted from the old text.
+func (l Line) Removed() bool {
+	return l.Op == Delete
+}
+
+// splitLines splits text into lines, ignoring a single trailing newline and
+// normalising Windows line endings so they don't show up as changes.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.TrimSuffix(text, "\n")
+
+	return strings.Split(text, "\n")
+}
+
+// maxCost bounds the work done by Lines, counted in steps along the edit
+// graph. Snippets can be large and revisions completely different, so
+// without a bound a single diff could tie up the server. Once it's spent, any
+// part of the texts still to be compared is shown as deleted and then
+// inserted in full: still a correct edit script, just not the shortest one.
+const maxCost = 1 << 22
+
+// Lines returns the full line-by-line edit script which turns oldText into
+// newText. Every line from both texts appears exactly once, in order.
+//
+// It uses the linear-space variant of Myers' algorithm: rather than keeping
+// the furthest points reached after every step so that the path can be
+// traced back, it searches forwards and backwards at once for the "middle
+// snake" of the shortest path, and then diffs the texts on either side of it
+// in the same way. Memory use is linear in the length of the texts.
+func Lines(oldText, newText string) []Line {
+	a := splitLines(oldText)
+	b := splitLines(newText)
+
+	size := 2 * ((len(a) + len(b) + 1) / 2)
+	d := &differ{
+		a:      a,
+		b:      b,
+		vf:     make([]int, size+2),
+		vb:     make([]int, size+2),
+		budget: maxCost,
+		lines:  make([]Line, 0, max(len(a), len(b))),
+	}
+
+	d.compare(0, len(a), 0, len(b))
+
+	return d.lines
+}
+
+// differ holds the state shared by the recursive calls of Lines. vf and vb
+// hold the furthest x reached on each diagonal by the forward and backward
+// searches, and are reused by each call to bisect.
+type differ struct {
+	a, b   []string
+	vf, vb []int
+	budget int
+	lines  []Line
+}
+
+func (d *differ) equal(x, y int) {
+	d.lines = append(d.lines, Line{Op: Equal, Text: d.a[x], OldLine: x + 1, NewLine: y + 1})
+}
+
+// replace appends a[aLo:aHi] as deleted and then b[bLo:bHi] as inserted.
+func (d *differ) replace(aLo, aHi, bLo, bHi int) {
+	for x := aLo; x < aHi; x++ {
+		d.lines = append(d.lines, Line{Op: Delete, Text: d.a[x], OldLine: x + 1})
+	}
+	for y := bLo; y < bHi; y++ {
+		d.lines = append(d.lines, Line{Op: Insert, Text: d.b[y], NewLine: y + 1})
+	}
+}
+
+// compare appends the edit script which turns a[aLo:aHi] into b[bLo:bHi].
+func (d *differ) compare(aLo, aHi, bLo, bHi int) {
+	// Lines in common at the start and end are kept as they are, which
+	// also means the texts in between (if any) differ at both ends.
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		d.equal(aLo, bLo)
+		aLo++
+		bLo++
+	}
+
+	suffix := 0
+	for aLo < aHi-suffix && bLo < bHi-suffix && d.a[aHi-suffix-1] == d.b[bHi-suffix-1] {
+		suffix++
+	}
+	aHi -= suffix
+	bHi -= suffix
+
+	if aLo == aHi || bLo == bHi {
+		d.replace(aLo, aHi, bLo, bHi)
+	} else if x, y, ok := d.bisect(aLo, aHi, bLo, bHi); ok {
+		d.compare(aLo, x, bLo, y)
+		d.compare(x, aHi, y, bHi)
+	} else {
+		d.replace(aLo, aHi, bLo, bHi)
+	}
+
+	for i := range suffix {
+		d.equal(aHi+i, bHi+i)
+	}
+}
+
+// bisect finds a point (x, y) on a shortest path from (aLo, bLo) to (aHi,
+// bHi) which splits it roughly in half, by searching from both ends until
+// the searches overlap. It reports false if the texts have nothing in common
+// or the budget runs out first.
+//
+// Within bisect, coordinates are relative to (aLo, bLo), and diagonal k is
+// the line x - y = k. Both searches number diagonals the same way, with the
+// backward search measuring x back from aHi.
+func (d *differ) bisect(aLo, aHi, bLo, bHi int) (int, int, bool) {
+	n, m := aHi-aLo, bHi-bLo
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	vf := d.vf[:2*maxD+2]
+	vb := d.vb[:2*maxD+2]
+	for i := range vf {
+		vf[i] = -1
+		vb[i] = -1
+	}
+	vf[offset+1] = 0
+	vb[offset+1] = 0
+
+	// If the difference in length is odd the paths can only meet after a
+	// forward step, and otherwise after a backward one.
+	delta := n - m
+	front := delta%2 != 0
+
+	// Diagonals which run off the edge of the graph are skipped by
+	// narrowing the range searched at each end.
+	kfStart, kfEnd, kbStart, kbEnd := 0, 0, 0, 0
+
+	for step := 0; step < maxD; step++ {
+		for k := -step + kfStart; k <= step-kfEnd; k += 2 {
+			var x int
+			if k == -step || (k != step && vf[offset+k-1] < vf[offset+k+1]) {
+				x = vf[offset+k+1]
+			} else {
+				x = vf[offset+k-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && d.a[aLo+x] == d.b[bLo+y] {
+				x++
+				y++
+				d.budget--
+			}
+
+			vf[offset+k] = x
+			d.budget--
+
+			switch {
+			case x > n:
+				kfEnd += 2
+			case y > m:
+				kfStart += 2
+			case front:
+				kb := offset + delta - k
+				if kb >= 0 && kb < len(vb) && vb[kb] != -1 && x >= n-vb[kb] {
+					return aLo + x, bLo + y, true
+				}
+			}
+		}
+
+		for k := -step + kbStart; k <= step-kbEnd; k += 2 {
+			var x int
+			if k == -step || (k != step && vb[offset+k-1] < vb[offset+k+1]) {
+				x = vb[offset+k+1]
+			} else {
+				x = vb[offset+k-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && d.a[aHi-x-1] == d.b[bHi-y-1] {
+				x++
+				y++
+				d.budget--
+			}
+
+			vb[offset+k] = x
+			d.budget--
+
+			switch {
+			case x > n:
+				kbEnd += 2
+			case y > m:
+				kbStart += 2
+			case !front:
+				kf := offset + delta - k
+				if kf >= 0 && kf < len(vf) && vf[kf] != -1 {
+					fx := vf[kf]
+					fy := offset + fx - kf
+					if fx >= n-x {
+						return aLo + fx, bLo + fy, true
+					}
+				}
+			}
+		}
+
+		if d.budget < 0 {
+			return 0, 0, false
+		}
+	}
+
+	return 0, 0, false
+}
+
+// Unified groups the differences between oldText and newText into hunks,
+// each surrounded by up to context unchanged lines. It returns nil if the
+// texts are identical.
+func Unified(oldText, newText string, context int) []Hunk {
+	lines := Lines(oldText, newText)
+
+	// Find the [lo, hi) ranges of lines covered by each hunk. Changes which
+	// are close enough for their context to touch are merged together.
+	type span struct{ lo, hi int }
+	var spans []span
+
+	for i, line := range lines {
+		if line.Op == Equal {
+			continue
+		}
+
+		lo := max(i-context, 0)
+		hi := min(i+context+1, len(lines))
+
+		if len(spans) > 0 && lo <= spans[len(spans)-1].hi {
+			spans[len(spans)-1].hi = hi
+		} else {
+			spans = append(spans, span{lo, hi})
+		}
+	}
+
+	var hunks []Hunk
+	oldBefore, newBefore, pos := 0, 0, 0
+
+	for _, s := range spans {
+		// Count the old and new lines which come before the hunk.
+		for ; pos < s.lo; pos++ {
+			if lines[pos].Op != Insert {
+				oldBefore++
+			}
+			if lines[pos].Op != Delete {
+				newBefore++
+			}
+		}
+
+		h := Hunk{Lines: lines[s.lo:s.hi]}
+		for _, line := range h.Lines {
+			if line.Op != Insert {
+				h.OldLines++
+			}
+			if line.Op != Delete {
+				h.NewLines++
+			}
+		}
+
+		// Following the unified diff convention, a range which is empty on
+		// one side starts at the line before the hunk rather than after it.
+		h.OldStart = oldBefore + 1
+		if h.OldLines == 0 {
+			h.OldStart = oldBefore
+		}
+		h.NewStart = newBefore + 1
+		if h.NewLines == 0 {
+			h.NewStart = newBefore
+		}
+
+		hunks = append(hunks, h)
+	}
+
+	return hunks
+}
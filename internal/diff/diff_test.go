@@ -0,0 +1,273 @@
+package diff
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/mixnblend/snippetbox/internal/assert"
+)
+
+// render formats lines in the style of a unified diff body so that expected
+// results can be written as plain strings.
+func render(lines []Line) string {
+	var b strings.Builder
+
+	for _, line := range lines {
+		switch line.Op {
+		case Insert:
+			b.WriteString("+")
+		case Delete:
+			b.WriteString("-")
+		default:
+			b.WriteString(" ")
+		}
+		b.WriteString(line.Text)
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name    string
+		oldText string
+		newText string
+		want    string
+	}{
+		{
+			name:    "Identical",
+			oldText: "a\nb\nc",
+			newText: "a\nb\nc",
+			want:    " a\n b\n c\n",
+		},
+		{
+			name:    "Both empty",
+			oldText: "",
+			newText: "",
+			want:    "",
+		},
+		{
+			name:    "From empty",
+			oldText: "",
+			newText: "a\nb",
+			want:    "+a\n+b\n",
+		},
+		{
+			name:    "To empty",
+			oldText: "a\nb",
+			newText: "",
+			want:    "-a\n-b\n",
+		},
+		{
+			name:    "Changed line",
+			oldText: "a\nb\nc",
+			newText: "a\nx\nc",
+			want:    " a\n-b\n+x\n c\n",
+		},
+		{
+			// The example from Myers' paper. There's more than one shortest
+			// edit script; this is the one found by splitting at the middle
+			// snake, which is as short as the one in the paper.
+			name:    "Myers example",
+			oldText: "A\nB\nC\nA\nB\nB\nA",
+			newText: "C\nB\nA\nB\nA\nC",
+			want:    "-A\n+C\n B\n-C\n A\n B\n-B\n A\n+C\n",
+		},
+		{
+			name:    "Trailing newline and CRLF are ignored",
+			oldText: "a\r\nb\r\n",
+			newText: "a\nb",
+			want:    " a\n b\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given ... we have two texts
+			// When ... we diff them
+			result := Lines(tt.oldText, tt.newText)
+
+			// Then ... the edit script should be returned as expected
+			assert.Equal(t, render(result), tt.want)
+		})
+	}
+}
+
+// apply replays an edit script, returning the old and new texts it was made
+// from, and counts the lines inserted and deleted.
+func apply(lines []Line) (oldLines, newLines []string, edits int) {
+	for _, line := range lines {
+		if line.Op != Insert {
+			oldLines = append(oldLines, line.Text)
+		}
+		if line.Op != Delete {
+			newLines = append(newLines, line.Text)
+		}
+		if line.Op != Equal {
+			edits++
+		}
+	}
+
+	return oldLines, newLines, edits
+}
+
+// shortestEdit returns the length of the shortest edit script between a
+// and b, from the length of their longest common subsequence.
+func shortestEdit(a, b []string) int {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	return len(a) + len(b) - 2*lcs[0][0]
+}
+
+func TestLinesShortest(t *testing.T) {
+	// Given ... we have many pairs of short texts made from a small
+	// alphabet, so that they have plenty of lines in common
+	rng := rand.New(rand.NewPCG(1, 2))
+	randomText := func() []string {
+		lines := make([]string, rng.IntN(12))
+		for i := range lines {
+			lines[i] = string(rune('a' + rng.IntN(4)))
+		}
+		return lines
+	}
+
+	for range 1000 {
+		a, b := randomText(), randomText()
+
+		// When ... we diff them
+		lines := Lines(strings.Join(a, "\n"), strings.Join(b, "\n"))
+
+		// Then ... the edit script should turn one into the other
+		oldLines, newLines, edits := apply(lines)
+		assert.Equal(t, strings.Join(oldLines, "\n"), strings.Join(a, "\n"))
+		assert.Equal(t, strings.Join(newLines, "\n"), strings.Join(b, "\n"))
+
+		// And ... it should be as short as possible
+		assert.Equal(t, edits, shortestEdit(a, b))
+	}
+}
+
+func TestLinesLargeDissimilar(t *testing.T) {
+	// Given ... we have two large revisions with no lines in common, which
+	// is the most expensive case to diff
+	var oldText, newText strings.Builder
+	for i := range 20000 {
+		fmt.Fprintf(&oldText, "old line %d\n", i)
+		fmt.Fprintf(&newText, "new line %d\n", i)
+	}
+
+	// When ... we diff them
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	lines := Lines(oldText.String(), newText.String())
+	runtime.ReadMemStats(&after)
+
+	// Then ... every line should be deleted and inserted
+	oldLines, newLines, edits := apply(lines)
+	assert.Equal(t, len(oldLines), 20000)
+	assert.Equal(t, len(newLines), 20000)
+	assert.Equal(t, edits, 40000)
+
+	// And ... the memory used should be in proportion to the size of the
+	// texts, rather than growing with the square of it
+	allocated := after.TotalAlloc - before.TotalAlloc
+	if allocated > 32<<20 {
+		t.Errorf("allocated %d bytes; want at most %d", allocated, 32<<20)
+	}
+}
+
+func TestLinesNumbers(t *testing.T) {
+	// Given ... we have two texts with a line replaced in the middle
+	// When ... we diff them
+	lines := Lines("a\nb\nc", "a\nx\nc")
+
+	// Then ... each line should carry its position in the old and new text
+	want := []Line{
+		{Op: Equal, Text: "a", OldLine: 1, NewLine: 1},
+		{Op: Delete, Text: "b", OldLine: 2},
+		{Op: Insert, Text: "x", NewLine: 2},
+		{Op: Equal, Text: "c", OldLine: 3, NewLine: 3},
+	}
+
+	assert.Equal(t, len(lines), len(want))
+	for i := range want {
+		assert.Equal(t, lines[i], want[i])
+	}
+}
+
+func TestUnified(t *testing.T) {
+	oldText := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12"
+
+	tests := []struct {
+		name    string
+		newText string
+		want    []string
+	}{
+		{
+			name:    "No changes",
+			newText: oldText,
+			want:    nil,
+		},
+		{
+			name:    "Single change",
+			newText: "1\n2\n3\n4\n5\nsix\n7\n8\n9\n10\n11\n12",
+			want:    []string{"@@ -3,7 +3,7 @@"},
+		},
+		{
+			name:    "Separate changes",
+			newText: "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ntwelve",
+			want:    []string{"@@ -1,4 +1,4 @@", "@@ -9,4 +9,4 @@"},
+		},
+		{
+			name:    "Nearby changes are merged",
+			newText: "1\n2\n3\nfour\n5\n6\n7\n8\nnine\n10\n11\n12",
+			want:    []string{"@@ -1,12 +1,12 @@"},
+		},
+		{
+			name:    "Pure insertion at end",
+			newText: oldText + "\n13",
+			want:    []string{"@@ -10,3 +10,4 @@"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given ... we have an old and new text
+			// When ... we group the differences into hunks with 3 lines of context
+			hunks := Unified(oldText, tt.newText, 3)
+
+			// Then ... the hunk headers should be returned as expected
+			assert.Equal(t, len(hunks), len(tt.want))
+			for i := range tt.want {
+				assert.Equal(t, hunks[i].Header(), tt.want[i])
+			}
+		})
+	}
+}
+
+func TestUnifiedEmptySide(t *testing.T) {
+	// Given ... we diff an empty text against a non-empty one
+	// When ... we group the differences into hunks
+	hunks := Unified("", "a\nb", 3)
+
+	// Then ... the empty old range should be anchored at line 0
+	assert.Equal(t, len(hunks), 1)
+	assert.Equal(t, hunks[0].Header(), "@@ -0,0 +1,2 @@")
+}
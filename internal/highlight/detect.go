@@ -0,0 +1,88 @@
+package highlight
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2/lexers"
+)
+
+// A rule detects a language from a pattern which is characteristic of it.
+// Rules are tried in order, so more specific patterns come first.
+type rule struct {
+	language string
+	pattern  interface{ MatchString(string) bool }
+}
+
+// An exceptPattern matches content which matches pattern but not except.
+type exceptPattern struct {
+	pattern, except *regexp.Regexp
+}
+
+func (p exceptPattern) MatchString(s string) bool {
+	return p.pattern.MatchString(s) && !p.except.MatchString(s)
+}
+
+// A line starting with # is a Markdown heading, but also a comment in YAML
+// and shell scripts, so headings only count as Markdown along with some
+// other Markdown syntax after them (another heading, a list item, a link or
+// bold text), and not if there are any YAML keys or shell commands.
+var (
+	markdownHeadingRX = regexp.MustCompile(`(?m)^#{1,6} \S.*\n(.*\n)*?.*(^#{1,6} \S|^\s*([-*+]|\d+\.) \S|\[[^\]\n]+\]\([^)\s]+\)|\*\*[^*\n]+\*\*)`)
+	yamlKeyRX         = regexp.MustCompile(`(?m)^\s*[\w.-]+:(\s|$)`)
+	shellCommandRX    = regexp.MustCompile(`(?m)^\s*(\$ )?(sudo |apt(-get)? |echo |export \w+=|cd |ls |grep |curl |for \w+ in .*; do)`)
+	yamlOrShellRX     = regexp.MustCompile(yamlKeyRX.String() + "|" + shellCommandRX.String())
+)
+
+var rules = []rule{
+	{"bash", regexp.MustCompile(`\A#!\s*/(usr/)?bin/(env\s+)?(ba|z)?sh\b`)},
+	{"python", regexp.MustCompile(`\A#!\s*/(usr/)?bin/(env\s+)?python`)},
+	{"ruby", regexp.MustCompile(`\A#!\s*/(usr/)?bin/(env\s+)?ruby`)},
+	{"javascript", regexp.MustCompile(`\A#!\s*/(usr/)?bin/(env\s+)?node`)},
+	{"php", regexp.MustCompile(`\A\s*<\?php`)},
+	{"html", regexp.MustCompile(`(?i)\A\s*(<!doctype html|<html[\s>])`)},
+	{"go", regexp.MustCompile(`(?m)^package \w+\s*$|^func (\(\w+ \*?\w+\) )?\w+\(.*\) .*\{\s*$|:= `)},
+	{"rust", regexp.MustCompile(`(?m)^\s*(pub )?fn \w+.*\{\s*$|^\s*let mut |^use \w+(::\w+)+;`)},
+	{"docker", regexp.MustCompile(`(?m)\A(#.*\n|\s*\n)*FROM \S+.*\n(.*\n)*\s*(RUN|COPY|CMD|ENTRYPOINT) `)},
+	{"cpp", regexp.MustCompile(`(?m)^#include <(iostream|string|vector|map|memory)>|std::`)},
+	{"c", regexp.MustCompile(`(?m)^#include [<"][\w/]+\.h[>"]`)},
+	{"csharp", regexp.MustCompile(`(?m)^using System(\.\w+)*;|^\s*namespace [\w.]+;?\s*$`)},
+	{"java", regexp.MustCompile(`(?m)^import java\.|^\s*public (static )?(class|void|interface) `)},
+	{"typescript", regexp.MustCompile(`(?m)^\s*(interface|type) \w+ (=|\{)|^\s*(const|let) \w+: \w+`)},
+	{"javascript", regexp.MustCompile(`(?m)^\s*(const|let|var) \w+ = |=> \{|^\s*function \w*\(|console\.log\(|require\(['"]`)},
+	{"python", regexp.MustCompile(`(?m)^\s*(def|class) \w+.*:\s*$|^\s*(from [\w.]+ )?import \w+\s*$|^if __name__ == `)},
+	{"ruby", regexp.MustCompile(`(?m)^\s*(def \w+[?!]?(\(.*\))?|class \w+( < \w+)?|module \w+)\s*$(.*\n)*^\s*end\s*$`)},
+	{"sql", regexp.MustCompile(`(?is)^\s*(SELECT\s.+\sFROM\s|INSERT\s+INTO\s|UPDATE\s+\w+\s+SET\s|DELETE\s+FROM\s|CREATE\s+(TABLE|INDEX|DATABASE)\s|ALTER\s+TABLE\s)`)},
+	{"css", regexp.MustCompile(`(?m)^\s*([.#]?[\w-]+(\s*[,>+~]?\s*[.#]?[\w-]+)*)\s*\{\s*$(.*\n)*^\s*[\w-]+\s*:\s*[^;]+;`)},
+	{"markdown", regexp.MustCompile(`(?m)^\s*[-*] \[[ x]\] |^` + "```")},
+	{"markdown", exceptPattern{markdownHeadingRX, yamlOrShellRX}},
+	{"bash", shellCommandRX},
+	{"yaml", regexp.MustCompile(`(?m)\A(---\s*\n)?(\s*#.*\n)*[\w.-]+:(\s|$)`)},
+}
+
+// Detect guesses the language of some content. It looks for tell-tale
+// patterns first, then checks for JSON, and then falls back on chroma's own
+// analysis. It returns Text if it can't tell.
+func Detect(content string) string {
+	for _, r := range rules {
+		if r.pattern.MatchString(content) {
+			return r.language
+		}
+	}
+
+	trimmed := strings.TrimSpace(content)
+	if (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed)) {
+		return "json"
+	}
+
+	if lexer := lexers.Analyse(content); lexer != nil {
+		for _, alias := range append([]string{lexer.Config().Name}, lexer.Config().Aliases...) {
+			if _, ok := Lookup(strings.ToLower(alias)); ok {
+				return strings.ToLower(alias)
+			}
+		}
+	}
+
+	return Text
+}
@@ -0,0 +1,150 @@
+package highlight
+
+import (
+	"testing"
+
+	"github.com/mixnblend/snippetbox/internal/assert"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "Go",
+			content: "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n",
+			want:    "go",
+		},
+		{
+			name:    "Shell script",
+			content: "#!/usr/bin/env bash\nset -euo pipefail\n",
+			want:    "bash",
+		},
+		{
+			name:    "Python script",
+			content: "#!/usr/bin/python3\nprint('hi')\n",
+			want:    "python",
+		},
+		{
+			name:    "Python",
+			content: "import os\n\ndef main():\n    print(os.getcwd())\n",
+			want:    "python",
+		},
+		{
+			name:    "PHP",
+			content: "<?php\necho 'hi';\n",
+			want:    "php",
+		},
+		{
+			name:    "HTML",
+			content: "<!DOCTYPE html>\n<html><body></body></html>\n",
+			want:    "html",
+		},
+		{
+			name:    "Rust",
+			content: "fn main() {\n    let mut x = 1;\n}\n",
+			want:    "rust",
+		},
+		{
+			name:    "Dockerfile",
+			content: "FROM golang:1.22\nWORKDIR /app\nRUN go build ./...\n",
+			want:    "docker",
+		},
+		{
+			name:    "C",
+			content: "#include <stdio.h>\n\nint main(void) { return 0; }\n",
+			want:    "c",
+		},
+		{
+			name:    "C++",
+			content: "#include <iostream>\n\nint main() { std::cout << 1; }\n",
+			want:    "cpp",
+		},
+		{
+			name:    "JavaScript",
+			content: "const x = require('x');\nconsole.log(x);\n",
+			want:    "javascript",
+		},
+		{
+			name:    "SQL",
+			content: "SELECT id, title FROM snippets WHERE id = 1;",
+			want:    "sql",
+		},
+		{
+			name:    "JSON",
+			content: `{"title": "An old silent pond", "tags": ["haiku"]}`,
+			want:    "json",
+		},
+		{
+			name:    "YAML",
+			content: "---\nname: build\non: push\n",
+			want:    "yaml",
+		},
+		{
+			name:    "YAML with a comment",
+			content: "# Config\nkey: value\n",
+			want:    "yaml",
+		},
+		{
+			name:    "YAML with several comments",
+			content: "# Config\n# for the build\nname: build\nsteps:\n  - run: make\n",
+			want:    "yaml",
+		},
+		{
+			name:    "Shell commands with a comment",
+			content: "# install deps\napt-get install -y curl\n",
+			want:    "bash",
+		},
+		{
+			name:    "Shell commands with several comments",
+			content: "# Setup\n## Dependencies\nsudo apt-get update\n",
+			want:    "bash",
+		},
+		{
+			name:    "Markdown headings",
+			content: "# Snippetbox\n\nA place to keep snippets.\n\n## Usage\n\nRun the server.\n",
+			want:    "markdown",
+		},
+		{
+			name:    "Markdown heading and list",
+			content: "# Shopping\n\n- Eggs\n- Milk\n",
+			want:    "markdown",
+		},
+		{
+			name:    "Markdown heading and link",
+			content: "# Links\n\nSee [the book](https://lets-go.alexedwards.net).\n",
+			want:    "markdown",
+		},
+		{
+			name:    "Markdown with a code block",
+			content: "Install it with:\n\n```\nsudo apt-get install curl\n```\n",
+			want:    "markdown",
+		},
+		{
+			name:    "Prose",
+			content: "An old silent pond...\nA frog jumps into the pond,\nsplash! Silence again.",
+			want:    Text,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, Detect(tt.content), tt.want)
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	// Given a snippet whose author chose a language
+	// When it is resolved
+	// Then the chosen language is used, whatever the content looks like
+	assert.Equal(t, Resolve("sql", "package main").Name, "sql")
+
+	// Given a snippet with no language, or one we no longer support
+	// When it is resolved
+	// Then the language is detected from the content
+	assert.Equal(t, Resolve("", "package main").Name, "go")
+	assert.Equal(t, Resolve("cobol", "package main").Name, "go")
+}
@@ -0,0 +1,78 @@
+package highlight
+
+import (
+	"bytes"
+	"html/template"
+	"sync"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// LinePrefix is the prefix of the IDs given to each line, so that lines can
+// be linked to with fragments such as #L10. When a page shows several files,
+// each needs its own prefix so that the IDs don't clash.
+const LinePrefix = "L"
+
+// newFormatter returns a formatter which uses CSS classes rather than inline
+// styles, as inline styles are blocked by our Content-Security-Policy. Each
+// line number is a link to that line.
+func newFormatter(linePrefix string) *html.Formatter {
+	return html.New(
+		html.WithClasses(true),
+		html.WithLineNumbers(true),
+		html.WithLinkableLineNumbers(true, linePrefix),
+	)
+}
+
+var style = styles.Get("github")
+
+// Render returns the content as syntax highlighted HTML, using the lexer for
+// the named language, with line IDs starting with linePrefix. All of the
+// content is escaped, so the result is safe to include in a page.
+func Render(content, language, linePrefix string) (template.HTML, error) {
+	return render(content, language, newFormatter(linePrefix))
+}
+
+// RenderBlock works like Render(), but without line numbers. It's used for
+// code blocks inside other content, such as fenced code blocks in Markdown.
+func RenderBlock(content, language string) (template.HTML, error) {
+	return render(content, language, html.New(html.WithClasses(true)))
+}
+
+func render(content, language string, formatter *html.Formatter) (template.HTML, error) {
+	lexer := lexers.Get(language)
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	lexer = chroma.Coalesce(lexer)
+
+	iterator, err := lexer.Tokenise(nil, content)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+
+	err = formatter.Format(&buf, style, iterator)
+	if err != nil {
+		return "", err
+	}
+
+	return template.HTML(buf.String()), nil
+}
+
+// CSS returns the stylesheet for the classes used by Render(). It never
+// changes, so it's only generated once.
+var CSS = sync.OnceValues(func() ([]byte, error) {
+	var buf bytes.Buffer
+
+	err := newFormatter(LinePrefix).WriteCSS(&buf, style)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+})
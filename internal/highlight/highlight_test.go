@@ -0,0 +1,68 @@
+package highlight
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mixnblend/snippetbox/internal/assert"
+)
+
+func TestRender(t *testing.T) {
+	// Given some Go code containing HTML
+	content := "package main\n\nvar s = \"<script>alert(1)</script>\"\n"
+
+	// When it is rendered
+	html, err := Render(content, "go", LinePrefix)
+	assert.NilError(t, err)
+
+	// Then it is highlighted with classes rather than inline styles
+	assert.StringContains(t, string(html), `<pre class="chroma">`)
+	assert.StringContains(t, string(html), `<span class="kn">package</span>`)
+	assert.Equal(t, strings.Contains(string(html), "style="), false)
+
+	// And each line number links to itself
+	assert.StringContains(t, string(html), `<span class="ln" id="L1"><a class="lnlinks" href="#L1">1</a></span>`)
+	assert.StringContains(t, string(html), `id="L3"`)
+
+	// And the content is escaped
+	assert.StringContains(t, string(html), "&lt;script&gt;")
+	assert.Equal(t, strings.Contains(string(html), "<script>"), false)
+}
+
+func TestRenderLinePrefix(t *testing.T) {
+	// Given a second file on a page
+	// When it is rendered with its own line prefix
+	html, err := Render("one\ntwo", "text", "F2L")
+	assert.NilError(t, err)
+
+	// Then its line IDs and links use that prefix
+	assert.StringContains(t, string(html), `<span class="ln" id="F2L2"><a class="lnlinks" href="#F2L2">2</a></span>`)
+}
+
+func TestRenderUnknownLanguage(t *testing.T) {
+	// Given a language which chroma doesn't know
+	// When some content is rendered in it
+	html, err := Render("a < b", "cobol-2050", LinePrefix)
+
+	// Then it is rendered as plain text
+	assert.NilError(t, err)
+	assert.StringContains(t, string(html), "a &lt; b")
+}
+
+func TestCSS(t *testing.T) {
+	css, err := CSS()
+	assert.NilError(t, err)
+	assert.StringContains(t, string(css), ".chroma")
+}
+
+func TestRenderBlock(t *testing.T) {
+	// Given a code block from some other content
+	// When it is rendered
+	html, err := RenderBlock("SELECT 1;", "sql")
+	assert.NilError(t, err)
+
+	// Then it is highlighted without line numbers
+	assert.StringContains(t, string(html), `<pre class="chroma">`)
+	assert.StringContains(t, string(html), `<span class="k">SELECT</span>`)
+	assert.Equal(t, strings.Contains(string(html), `class="ln"`), false)
+}
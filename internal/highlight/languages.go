@@ -0,0 +1,68 @@
+// Package highlight renders snippets as syntax highlighted HTML, and guesses
+// which language a snippet is written in when its author hasn't said.
+package highlight
+
+// Text is the name of the plain text language, used when a snippet's
+// language can't be worked out.
+const Text = "text"
+
+// Markdown is the name of the Markdown language. Markdown snippets are shown
+// as formatted prose rather than highlighted source.
+const Markdown = "markdown"
+
+// A Language is one of the languages which a snippet can be written in.
+// Name is the chroma lexer name stored against the snippet, Label is shown
+// to users, and Extension is used for file names.
+type Language struct {
+	Name      string
+	Label     string
+	Extension string
+}
+
+// Languages lists the languages which authors can choose from, in the order
+// they are offered.
+var Languages = []Language{
+	{Name: "bash", Label: "Bash", Extension: ".sh"},
+	{Name: "c", Label: "C", Extension: ".c"},
+	{Name: "cpp", Label: "C++", Extension: ".cpp"},
+	{Name: "csharp", Label: "C#", Extension: ".cs"},
+	{Name: "css", Label: "CSS", Extension: ".css"},
+	{Name: "docker", Label: "Dockerfile", Extension: ".dockerfile"},
+	{Name: "go", Label: "Go", Extension: ".go"},
+	{Name: "html", Label: "HTML", Extension: ".html"},
+	{Name: "java", Label: "Java", Extension: ".java"},
+	{Name: "javascript", Label: "JavaScript", Extension: ".js"},
+	{Name: "json", Label: "JSON", Extension: ".json"},
+	{Name: Markdown, Label: "Markdown", Extension: ".md"},
+	{Name: "php", Label: "PHP", Extension: ".php"},
+	{Name: "python", Label: "Python", Extension: ".py"},
+	{Name: "ruby", Label: "Ruby", Extension: ".rb"},
+	{Name: "rust", Label: "Rust", Extension: ".rs"},
+	{Name: "sql", Label: "SQL", Extension: ".sql"},
+	{Name: "typescript", Label: "TypeScript", Extension: ".ts"},
+	{Name: "yaml", Label: "YAML", Extension: ".yaml"},
+	{Name: Text, Label: "Plain text", Extension: ".txt"},
+}
+
+// Lookup returns the language with the given name. The boolean is false if
+// there's no such language.
+func Lookup(name string) (Language, bool) {
+	for _, l := range Languages {
+		if l.Name == name {
+			return l, true
+		}
+	}
+
+	return Language{}, false
+}
+
+// Resolve returns the language of a snippet: the language its author chose,
+// or else the one detected from its content.
+func Resolve(name, content string) Language {
+	if l, ok := Lookup(name); ok {
+		return l
+	}
+
+	l, _ := Lookup(Detect(content))
+	return l
+}
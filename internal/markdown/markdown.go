@@ -0,0 +1,155 @@
+// Package markdown renders Markdown snippets, such as runbooks and notes,
+// as HTML which is safe to include in a page.
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/mixnblend/snippetbox/internal/highlight"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// The converter understands GitHub Flavored Markdown (tables, task lists,
+// strikethrough and autolinks) and gives every heading an ID. Raw HTML in
+// the source is left out, which is goldmark's default.
+var converter = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+	goldmark.WithRendererOptions(
+		renderer.WithNodeRenderers(util.Prioritized(codeBlockRenderer{}, 100)),
+	),
+)
+
+var (
+	// headingIDRX matches the heading IDs generated by headingIDs.
+	headingIDRX = regexp.MustCompile(`^[a-z0-9-]+$`)
+	// classRX matches the classes used by the syntax highlighter.
+	classRX = regexp.MustCompile(`^[a-z0-9 -]+$`)
+)
+
+// The policy is an allow-list of the elements and attributes that the
+// converter produces. Anything else is removed, so even if a bug in the
+// converter let some HTML through, it couldn't run scripts on the page.
+var policy = func() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("id").Matching(headingIDRX).OnElements("h1", "h2", "h3", "h4", "h5", "h6")
+	p.AllowAttrs("class").Matching(classRX).OnElements("pre", "code", "span")
+	// Task list items are rendered as disabled checkboxes.
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	return p
+}()
+
+// Render converts the Markdown source to sanitized HTML. Each heading gets an
+// ID starting with idPrefix, so that it can be linked to; when a page shows
+// several documents, each needs its own prefix so that the IDs don't clash.
+func Render(source, idPrefix string) (template.HTML, error) {
+	var buf bytes.Buffer
+
+	ctx := parser.NewContext(parser.WithIDs(newHeadingIDs(idPrefix)))
+
+	err := converter.Convert([]byte(source), &buf, parser.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+
+	return template.HTML(policy.SanitizeBytes(buf.Bytes())), nil
+}
+
+// headingIDs generates the IDs of headings from their text, lowercased with
+// runs of anything but letters and digits replaced by hyphens. Repeated
+// headings get a numbered suffix.
+type headingIDs struct {
+	prefix string
+	seen   map[string]bool
+}
+
+func newHeadingIDs(prefix string) *headingIDs {
+	return &headingIDs{prefix: prefix, seen: map[string]bool{}}
+}
+
+func (ids *headingIDs) Generate(value []byte, kind ast.NodeKind) []byte {
+	slug := slugify(string(value))
+	if slug == "" {
+		slug = "heading"
+	}
+
+	id := ids.prefix + slug
+	for i := 1; ids.seen[id]; i++ {
+		id = fmt.Sprintf("%s%s-%d", ids.prefix, slug, i)
+	}
+	ids.seen[id] = true
+
+	return []byte(id)
+}
+
+func (ids *headingIDs) Put(value []byte) {
+	ids.seen[string(value)] = true
+}
+
+// slugify lowercases s and replaces each run of characters other than ASCII
+// letters and digits with a single hyphen.
+func slugify(s string) string {
+	var b strings.Builder
+	hyphen := false
+
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			hyphen = false
+		} else {
+			hyphen = true
+		}
+	}
+
+	return b.String()
+}
+
+// codeBlockRenderer renders fenced code blocks with the same syntax
+// highlighter as code snippets, using the language given after the opening
+// fence.
+type codeBlockRenderer struct{}
+
+func (r codeBlockRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindFencedCodeBlock, r.renderFencedCodeBlock)
+}
+
+func (r codeBlockRenderer) renderFencedCodeBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	block := node.(*ast.FencedCodeBlock)
+
+	var code bytes.Buffer
+	lines := block.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		code.Write(line.Value(source))
+	}
+
+	html, err := highlight.RenderBlock(code.String(), string(block.Language(source)))
+	if err != nil {
+		return ast.WalkStop, err
+	}
+
+	_, err = w.WriteString(string(html))
+	if err != nil {
+		return ast.WalkStop, err
+	}
+
+	return ast.WalkSkipChildren, nil
+}
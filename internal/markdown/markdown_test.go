@@ -0,0 +1,71 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mixnblend/snippetbox/internal/assert"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		prefix   string
+		want     string
+		wantNone string
+	}{
+		{
+			name:   "Heading anchors",
+			source: "# Deploying the app\n\n## Roll back!\n\n## Roll back!",
+			prefix: "md-",
+			want:   `<h1 id="md-deploying-the-app">Deploying the app</h1>` + "\n" + `<h2 id="md-roll-back">Roll back!</h2>` + "\n" + `<h2 id="md-roll-back-1">Roll back!</h2>`,
+		},
+		{
+			name:   "Table",
+			source: "| Step | Command |\n| --- | --- |\n| 1 | `make build` |",
+			want:   "<td><code>make build</code></td>",
+		},
+		{
+			name:   "Fenced code block",
+			source: "```sql\nSELECT 1;\n```",
+			want:   `<span class="k">SELECT</span>`,
+		},
+		{
+			name:   "Task list",
+			source: "- [x] Back up the database",
+			want:   `<input checked="" disabled="" type="checkbox"> Back up the database`,
+		},
+		{
+			name:     "Raw HTML",
+			source:   "<script>alert(1)</script>\n\n<img src=x onerror=alert(1)>",
+			wantNone: "<script",
+		},
+		{
+			name:     "Script link",
+			source:   "[click](javascript:alert(1))",
+			wantNone: "javascript:",
+		},
+		{
+			name:     "Inline styles",
+			source:   "```go\npackage main\n```",
+			wantNone: "style=",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When the source is rendered
+			html, err := Render(tt.source, tt.prefix)
+			assert.NilError(t, err)
+
+			// Then it contains the expected HTML, and nothing unsafe
+			if tt.want != "" {
+				assert.StringContains(t, string(html), tt.want)
+			}
+			if tt.wantNone != "" {
+				assert.Equal(t, strings.Contains(string(html), tt.wantNone), false)
+			}
+		})
+	}
+}
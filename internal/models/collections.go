@@ -0,0 +1,274 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const collectionModelUniqueShortIDConstraint = "collections_uc_short_id"
+
+type CollectionModelInterface interface {
+	Insert(input CollectionInput, userID int) (string, error)
+	Get(shortID string) (Collection, error)
+	Update(id int, input CollectionInput) error
+	Delete(id int) error
+	ByUser(userID int) ([]Collection, error)
+	Snippets(collectionID, userID int) ([]Snippet, error)
+	AddSnippet(collectionID, snippetID int) error
+	RemoveSnippet(collectionID, snippetID int) error
+	MoveSnippet(collectionID, snippetID, offset int) error
+}
+
+// A Collection is a named, ordered list of snippets put together by a user.
+// Like snippets, collections are identified in URLs by a random short ID and
+// have their own visibility. Size is the number of snippets in the
+// collection, including any which the viewer can't see.
+type Collection struct {
+	ID         int
+	ShortID    string
+	Title      string
+	Visibility string
+	Created    time.Time
+	UserID     int
+	UserName   string
+	Size       int
+}
+
+// VisibleTo reports whether the collection can be viewed by the user with
+// the given ID. Pass 0 for anonymous visitors.
+func (c Collection) VisibleTo(userID int) bool {
+	return c.Visibility != VisibilityPrivate || c.UserID == userID
+}
+
+// OwnedBy reports whether the collection belongs to the user with the given
+// ID.
+func (c Collection) OwnedBy(userID int) bool {
+	return c.UserID == userID
+}
+
+// CollectionInput holds the user-supplied fields used to create or update a
+// collection.
+type CollectionInput struct {
+	Title      string
+	Visibility string
+}
+
+// Define a CollectionModel type which wraps a sql.DB connection pool.
+type CollectionModel struct {
+	DB *sql.DB
+}
+
+// collectionSelect selects the columns scanned by scanCollection.
+const collectionSelect = `SELECT collections.id, collections.short_id, collections.title, collections.visibility,
+	collections.created, collections.user_id, users.name,
+	(SELECT COUNT(*) FROM collection_snippets WHERE collection_snippets.collection_id = collections.id)
+	FROM collections
+	INNER JOIN users ON users.id = collections.user_id`
+
+// scanCollection scans a row selected with collectionSelect into a
+// Collection.
+func scanCollection(row rowScanner) (Collection, error) {
+	var c Collection
+
+	err := row.Scan(&c.ID, &c.ShortID, &c.Title, &c.Visibility, &c.Created, &c.UserID, &c.UserName, &c.Size)
+	return c, err
+}
+
+// This will insert a new collection owned by the given user and return its
+// short ID. As with snippets, a clashing short ID is replaced and the insert
+// retried.
+func (m *CollectionModel) Insert(input CollectionInput, userID int) (string, error) {
+	stmt := `INSERT INTO collections (short_id, title, visibility, created, user_id)
+	VALUES (?, ?, ?, UTC_TIMESTAMP(), ?)`
+
+	for attempt := 1; ; attempt++ {
+		shortID, err := newShortID()
+		if err != nil {
+			return "", err
+		}
+
+		_, err = m.DB.Exec(stmt, shortID, input.Title, input.Visibility, userID)
+		if err != nil {
+			if isDuplicateShortID(err, collectionModelUniqueShortIDConstraint) && attempt < maxShortIDAttempts {
+				continue
+			}
+			return "", err
+		}
+
+		return shortID, nil
+	}
+}
+
+// This will return a specific collection based on its short ID.
+func (m *CollectionModel) Get(shortID string) (Collection, error) {
+	stmt := collectionSelect + ` WHERE collections.short_id = ?`
+
+	c, err := scanCollection(m.DB.QueryRow(stmt, shortID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Collection{}, ErrNoRecord
+		}
+		return Collection{}, err
+	}
+
+	return c, nil
+}
+
+// This will update the title and visibility of a collection.
+func (m *CollectionModel) Update(id int, input CollectionInput) error {
+	stmt := `UPDATE collections SET title = ?, visibility = ? WHERE id = ?`
+
+	_, err := m.DB.Exec(stmt, input.Title, input.Visibility, id)
+	return err
+}
+
+// This will delete a collection. The snippets in it are left alone.
+func (m *CollectionModel) Delete(id int) error {
+	stmt := `DELETE FROM collections WHERE id = ?`
+
+	result, err := m.DB.Exec(stmt, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+// This will return all of the collections owned by a user, in alphabetical
+// order.
+func (m *CollectionModel) ByUser(userID int) ([]Collection, error) {
+	stmt := collectionSelect + ` WHERE collections.user_id = ? ORDER BY collections.title, collections.id`
+
+	rows, err := m.DB.Query(stmt, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var collections []Collection
+
+	for rows.Next() {
+		c, err := scanCollection(rows)
+		if err != nil {
+			return nil, err
+		}
+		collections = append(collections, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return collections, nil
+}
+
+// This will return the unexpired snippets in a collection, in the order
+// chosen by its owner. Private snippets are left out unless they belong to
+// the user with the given ID.
+func (m *CollectionModel) Snippets(collectionID, userID int) ([]Snippet, error) {
+	stmt := snippetSelect + `
+	INNER JOIN collection_snippets ON collection_snippets.snippet_id = snippets.id
+	WHERE ` + notExpired + ` AND collection_snippets.collection_id = ?
+	AND (snippets.visibility <> ? OR snippets.user_id = ?)
+	ORDER BY collection_snippets.position, snippets.id`
+
+	return querySnippets(m.DB, stmt, collectionID, VisibilityPrivate, userID)
+}
+
+// This will add a snippet to the end of a collection. Adding a snippet which
+// is already in the collection does nothing.
+func (m *CollectionModel) AddSnippet(collectionID, snippetID int) error {
+	stmt := `INSERT IGNORE INTO collection_snippets (collection_id, snippet_id, position)
+	SELECT ?, ?, COALESCE(MAX(position), 0) + 1 FROM collection_snippets WHERE collection_id = ?`
+
+	_, err := m.DB.Exec(stmt, collectionID, snippetID, collectionID)
+	return err
+}
+
+// This will remove a snippet from a collection, if it was in it.
+func (m *CollectionModel) RemoveSnippet(collectionID, snippetID int) error {
+	stmt := `DELETE FROM collection_snippets WHERE collection_id = ? AND snippet_id = ?`
+
+	_, err := m.DB.Exec(stmt, collectionID, snippetID)
+	return err
+}
+
+// This will move a snippet offset places towards the end of a collection
+// (or towards the start, if offset is negative), stopping at either end.
+// Expired snippets are skipped over. If the snippet isn't in the collection,
+// ErrNoRecord is returned.
+func (m *CollectionModel) MoveSnippet(collectionID, snippetID, offset int) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	stmt := `SELECT collection_snippets.snippet_id FROM collection_snippets
+	INNER JOIN snippets ON snippets.id = collection_snippets.snippet_id
+	WHERE ` + notExpired + ` AND collection_snippets.collection_id = ?
+	ORDER BY collection_snippets.position, snippets.id`
+
+	rows, err := tx.Query(stmt, collectionID)
+	if err != nil {
+		return err
+	}
+
+	var ids []int
+	from := -1
+
+	for rows.Next() {
+		var id int
+		err = rows.Scan(&id)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		if id == snippetID {
+			from = len(ids)
+		}
+		ids = append(ids, id)
+	}
+
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	if from == -1 {
+		return ErrNoRecord
+	}
+
+	to := min(max(from+offset, 0), len(ids)-1)
+	if to == from {
+		return nil
+	}
+
+	// Take the snippet out of the list, put it back in its new place and
+	// renumber the positions, which closes any gaps left by removed snippets.
+	ids = append(ids[:from], ids[from+1:]...)
+	ids = append(ids[:to], append([]int{snippetID}, ids[to:]...)...)
+
+	stmt = `UPDATE collection_snippets SET position = ? WHERE collection_id = ? AND snippet_id = ?`
+
+	for i, id := range ids {
+		_, err = tx.Exec(stmt, i+1, collectionID, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/mixnblend/snippetbox/internal/assert"
+)
+
+func TestCollectionModelIntegration(t *testing.T) {
+	integrationTest(t)
+
+	// given ... we have a database with "An old silent pond" (ID 1) and its
+	// author Alice (ID 1) in it
+	db := newTestDB(t)
+	snippets := SnippetModel{DB: db}
+	users := UserModel{DB: db}
+	m := CollectionModel{DB: db}
+
+	// and ... another user, Bob (ID 2), with a public snippet (ID 2) and a
+	// private one (ID 3)
+	err := users.Insert("Bob", "bob@example.com", "pa$$word")
+	assert.NilError(t, err)
+	_, err = snippets.Insert(SnippetInput{Title: "Two", Files: singleFile("Two"), Visibility: VisibilityPublic}, 2)
+	assert.NilError(t, err)
+	_, err = snippets.Insert(SnippetInput{Title: "Three", Files: singleFile("Three"), Visibility: VisibilityPrivate}, 2)
+	assert.NilError(t, err)
+
+	// when ... Bob creates a collection
+	shortID, err := m.Insert(CollectionInput{Title: "Haiku", Visibility: VisibilityUnlisted}, 2)
+	assert.NilError(t, err)
+
+	// then ... it can be found by its short ID
+	collection, err := m.Get(shortID)
+	assert.NilError(t, err)
+	assert.Equal(t, collection.Title, "Haiku")
+	assert.Equal(t, collection.UserName, "Bob")
+	assert.Equal(t, collection.Visibility, VisibilityUnlisted)
+
+	// when ... Bob adds all three snippets to it, the first one twice
+	for _, id := range []int{1, 2, 3, 1} {
+		assert.NilError(t, m.AddSnippet(collection.ID, id))
+	}
+
+	titles := func(userID int) string {
+		t.Helper()
+		snippets, err := m.Snippets(collection.ID, userID)
+		assert.NilError(t, err)
+		var s string
+		for _, snippet := range snippets {
+			s += snippet.Title + ","
+		}
+		return s
+	}
+
+	// then ... they should be listed in the order they were added, with the
+	// private snippet only shown to Bob
+	assert.Equal(t, titles(2), "An old silent pond,Two,Three,")
+	assert.Equal(t, titles(1), "An old silent pond,Two,")
+
+	collection, err = m.Get(shortID)
+	assert.NilError(t, err)
+	assert.Equal(t, collection.Size, 3)
+
+	// when ... Bob moves the last snippet up past the start
+	err = m.MoveSnippet(collection.ID, 3, -5)
+	assert.NilError(t, err)
+
+	// and ... back down by one
+	err = m.MoveSnippet(collection.ID, 3, 1)
+	assert.NilError(t, err)
+
+	// then ... the new order should be kept
+	assert.Equal(t, titles(2), "An old silent pond,Three,Two,")
+
+	// and ... moving a snippet which isn't in the collection should fail
+	err = m.MoveSnippet(collection.ID, 99, 1)
+	assert.Equal(t, err, ErrNoRecord)
+
+	// when ... Bob removes a snippet and renames the collection
+	assert.NilError(t, m.RemoveSnippet(collection.ID, 1))
+	assert.NilError(t, m.Update(collection.ID, CollectionInput{Title: "Favourites", Visibility: VisibilityPrivate}))
+
+	// then ... Bob's collections should reflect the changes
+	collections, err := m.ByUser(2)
+	assert.NilError(t, err)
+	assert.Equal(t, len(collections), 1)
+	assert.Equal(t, collections[0].Title, "Favourites")
+	assert.Equal(t, collections[0].Size, 2)
+	assert.Equal(t, titles(2), "Three,Two,")
+
+	// when ... Bob deletes the collection
+	err = m.Delete(collection.ID)
+	assert.NilError(t, err)
+
+	// then ... it should be gone, but the snippets should be left alone
+	_, err = m.Get(shortID)
+	assert.Equal(t, err, ErrNoRecord)
+
+	_, err = snippets.Get("silentPond")
+	assert.NilError(t, err)
+}
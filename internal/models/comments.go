@@ -0,0 +1,183 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type CommentModelInterface interface {
+	Insert(snippetID, userID, parentID int, content string) (int, error)
+	Get(id int) (Comment, error)
+	ForSnippet(snippetID int) ([]Comment, error)
+	Update(id int, content string) error
+	Delete(id int) error
+}
+
+// A Comment is left by a user under a snippet. Comments are threaded: a
+// reply holds the ID of the comment it answers in ParentID, which is 0 for
+// top-level comments. Updated is the zero time unless the comment has been
+// edited. A deleted comment which has replies is kept, with Deleted set and
+// its Content blanked, so that the replies stay in their thread.
+type Comment struct {
+	ID             int
+	SnippetID      int
+	SnippetShortID string
+	UserID         int
+	UserName       string
+	ParentID       int
+	Content        string
+	Created        time.Time
+	Updated        time.Time
+	Deleted        bool
+}
+
+// Define a CommentModel type which wraps a sql.DB connection pool.
+type CommentModel struct {
+	DB *sql.DB
+}
+
+// commentSelect selects the columns scanned by scanComment.
+const commentSelect = `SELECT comments.id, comments.snippet_id, snippets.short_id, comments.user_id, users.name,
+	comments.parent_id, comments.content, comments.created, comments.updated, comments.deleted
+	FROM comments
+	INNER JOIN snippets ON snippets.id = comments.snippet_id
+	INNER JOIN users ON users.id = comments.user_id`
+
+// scanComment scans a row selected with commentSelect into a Comment.
+func scanComment(row rowScanner) (Comment, error) {
+	var (
+		c        Comment
+		parentID sql.NullInt64
+		updated  sql.NullTime
+	)
+
+	err := row.Scan(&c.ID, &c.SnippetID, &c.SnippetShortID, &c.UserID, &c.UserName, &parentID, &c.Content, &c.Created, &updated, &c.Deleted)
+	if err != nil {
+		return Comment{}, err
+	}
+
+	c.ParentID = int(parentID.Int64)
+	c.Updated = updated.Time
+
+	return c, nil
+}
+
+// This will insert a new comment on a snippet and return its ID. Pass a
+// parentID of 0 for a top-level comment.
+func (m *CommentModel) Insert(snippetID, userID, parentID int, content string) (int, error) {
+	stmt := `INSERT INTO comments (snippet_id, user_id, parent_id, content, created)
+	VALUES (?, ?, ?, ?, UTC_TIMESTAMP())`
+
+	parent := sql.NullInt64{Int64: int64(parentID), Valid: parentID != 0}
+
+	result, err := m.DB.Exec(stmt, snippetID, userID, parent, content)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// This will return a specific comment based on its id.
+func (m *CommentModel) Get(id int) (Comment, error) {
+	stmt := commentSelect + ` WHERE comments.id = ?`
+
+	c, err := scanComment(m.DB.QueryRow(stmt, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Comment{}, ErrNoRecord
+		}
+		return Comment{}, err
+	}
+
+	return c, nil
+}
+
+// This will return all of the comments on a snippet, oldest first. Replies
+// are included; they always come after the comment they answer.
+func (m *CommentModel) ForSnippet(snippetID int) ([]Comment, error) {
+	stmt := commentSelect + ` WHERE comments.snippet_id = ? ORDER BY comments.created, comments.id`
+
+	rows, err := m.DB.Query(stmt, snippetID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var comments []Comment
+
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return comments, nil
+}
+
+// This will replace the content of a comment and mark it as edited.
+func (m *CommentModel) Update(id int, content string) error {
+	stmt := `UPDATE comments SET content = ?, updated = UTC_TIMESTAMP() WHERE id = ?`
+
+	_, err := m.DB.Exec(stmt, content, id)
+	return err
+}
+
+// This will delete a comment. A comment which has replies is only marked as
+// deleted and has its content removed, as deleting the row would take the
+// replies (which may be from other users) with it through the
+// comments_fk_parent foreign key. Comments which have already been deleted
+// are reported as ErrNoRecord.
+func (m *CommentModel) Delete(id int) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	stmt := `SELECT id FROM comments WHERE id = ? AND deleted = FALSE FOR UPDATE`
+
+	err = tx.QueryRow(stmt, id).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoRecord
+		}
+		return err
+	}
+
+	var hasReplies bool
+
+	stmt = `SELECT EXISTS(SELECT 1 FROM comments WHERE parent_id = ?)`
+
+	err = tx.QueryRow(stmt, id).Scan(&hasReplies)
+	if err != nil {
+		return err
+	}
+
+	if hasReplies {
+		stmt = `UPDATE comments SET deleted = TRUE, content = '' WHERE id = ?`
+	} else {
+		stmt = `DELETE FROM comments WHERE id = ?`
+	}
+
+	_, err = tx.Exec(stmt, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/mixnblend/snippetbox/internal/assert"
+)
+
+func TestCommentModelIntegration(t *testing.T) {
+	integrationTest(t)
+
+	// given ... we have a database with "An old silent pond" (ID 1) and its
+	// author Alice (ID 1) in it
+	db := newTestDB(t)
+	users := UserModel{DB: db}
+	m := CommentModel{DB: db}
+
+	// and ... another user, Bob (ID 2)
+	err := users.Insert("Bob", "bob@example.com", "pa$$word")
+	assert.NilError(t, err)
+
+	// when ... Bob comments on the snippet and Alice replies
+	commentID, err := m.Insert(1, 2, 0, "Lovely haiku")
+	assert.NilError(t, err)
+	replyID, err := m.Insert(1, 1, commentID, "Thank you!")
+	assert.NilError(t, err)
+
+	// then ... both comments should be listed in order, with their authors
+	comments, err := m.ForSnippet(1)
+	assert.NilError(t, err)
+	assert.Equal(t, len(comments), 2)
+	assert.Equal(t, comments[0].UserName, "Bob")
+	assert.Equal(t, comments[0].SnippetShortID, "silentPond")
+	assert.Equal(t, comments[0].ParentID, 0)
+	assert.Equal(t, comments[1].ID, replyID)
+	assert.Equal(t, comments[1].ParentID, commentID)
+	assert.Equal(t, comments[1].Updated.IsZero(), true)
+
+	// when ... Alice edits the reply
+	err = m.Update(replyID, "Thank you, Bob!")
+	assert.NilError(t, err)
+
+	// then ... the reply should be marked as edited
+	reply, err := m.Get(replyID)
+	assert.NilError(t, err)
+	assert.Equal(t, reply.Content, "Thank you, Bob!")
+	assert.Equal(t, reply.Updated.IsZero(), false)
+
+	// when ... Bob deletes the first comment
+	err = m.Delete(commentID)
+	assert.NilError(t, err)
+
+	// then ... Alice's reply should survive, with the comment it answers
+	// kept as a blank placeholder
+	reply, err = m.Get(replyID)
+	assert.NilError(t, err)
+	assert.Equal(t, reply.Content, "Thank you, Bob!")
+
+	comments, err = m.ForSnippet(1)
+	assert.NilError(t, err)
+	assert.Equal(t, len(comments), 2)
+	assert.Equal(t, comments[0].ID, commentID)
+	assert.Equal(t, comments[0].Deleted, true)
+	assert.Equal(t, comments[0].Content, "")
+	assert.Equal(t, comments[1].Deleted, false)
+
+	// and ... deleting it again should report that there's no such comment
+	err = m.Delete(commentID)
+	assert.Equal(t, err, ErrNoRecord)
+
+	// when ... Alice deletes her reply, which has no replies of its own
+	err = m.Delete(replyID)
+	assert.NilError(t, err)
+
+	// then ... it should be removed entirely
+	_, err = m.Get(replyID)
+	assert.Equal(t, err, ErrNoRecord)
+
+	comments, err = m.ForSnippet(1)
+	assert.NilError(t, err)
+	assert.Equal(t, len(comments), 1)
+}
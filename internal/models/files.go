@@ -0,0 +1,90 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// A SnippetFile is one of the named files in a snippet. An empty Language
+// means the language is detected when the file is shown.
+type SnippetFile struct {
+	Name     string
+	Language string
+	Content  string
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx, so that files can be
+// read inside or outside a transaction.
+type querier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// joinFiles returns the text stored in the content column of the snippets
+// table, which is what searches and revisions see. A snippet with a single
+// file stores that file's content unchanged. Otherwise each file is headed by
+// its name, in the same style as `head` uses for several files.
+func joinFiles(files []SnippetFile) string {
+	if len(files) == 1 {
+		return files[0].Content
+	}
+
+	var b strings.Builder
+	for i, file := range files {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		fmt.Fprintf(&b, "==> %s <==\n%s", file.Name, file.Content)
+	}
+
+	return b.String()
+}
+
+// setFiles replaces the files of a snippet. Like insertRevision(), it must
+// be called inside the same transaction as the change to the snippet.
+func setFiles(tx *sql.Tx, snippetID int, files []SnippetFile) error {
+	_, err := tx.Exec(`DELETE FROM snippet_files WHERE snippet_id = ?`, snippetID)
+	if err != nil {
+		return err
+	}
+
+	stmt := `INSERT INTO snippet_files (snippet_id, position, name, language, content) VALUES (?, ?, ?, ?, ?)`
+
+	for i, file := range files {
+		_, err = tx.Exec(stmt, snippetID, i, file.Name, file.Language, file.Content)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// selectFiles returns the files of a snippet in order.
+func selectFiles(q querier, snippetID int) ([]SnippetFile, error) {
+	stmt := `SELECT name, language, content FROM snippet_files WHERE snippet_id = ? ORDER BY position`
+
+	rows, err := q.Query(stmt, snippetID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var files []SnippetFile
+
+	for rows.Next() {
+		var file SnippetFile
+		err = rows.Scan(&file.Name, &file.Language, &file.Content)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
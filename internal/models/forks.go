@@ -0,0 +1,50 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// A SnippetRef is a short description of another snippet, such as the one
+// a snippet was forked from.
+type SnippetRef struct {
+	ID         int
+	ShortID    string
+	Title      string
+	UserID     int
+	Visibility string
+}
+
+// VisibleTo reports whether the referenced snippet can be viewed by the user
+// with the given ID, in the same way as Snippet.VisibleTo().
+func (ref SnippetRef) VisibleTo(userID int) bool {
+	return ref.Visibility != VisibilityPrivate || ref.UserID == userID
+}
+
+// This will return the snippet with the given ID as a SnippetRef, or nil if
+// it has been deleted or has expired.
+func (m *SnippetModel) ref(id int) (*SnippetRef, error) {
+	var ref SnippetRef
+
+	stmt := `SELECT id, short_id, title, user_id, visibility FROM snippets WHERE ` + notExpired + ` AND id = ?`
+
+	err := m.DB.QueryRow(stmt, id).Scan(&ref.ID, &ref.ShortID, &ref.Title, &ref.UserID, &ref.Visibility)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &ref, nil
+}
+
+// This will return the number of unexpired forks of a snippet.
+func (m *SnippetModel) forkCount(id int) (int, error) {
+	var count int
+
+	stmt := `SELECT COUNT(*) FROM snippets WHERE ` + notExpired + ` AND parent_id = ?`
+
+	err := m.DB.QueryRow(stmt, id).Scan(&count)
+	return count, err
+}
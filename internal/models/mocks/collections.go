@@ -0,0 +1,104 @@
+package mocks
+
+import (
+	"github.com/mixnblend/snippetbox/internal/models"
+)
+
+// The mock user (Alice, ID 1) owns the "SQL recipes" collection, which holds
+// mockSnippet and mockOtherSnippet. Bob's collection is private.
+var mockCollection = models.Collection{
+	ID:         1,
+	ShortID:    "sqlRecipes",
+	Title:      "SQL recipes",
+	Visibility: models.VisibilityPublic,
+	Created:    now,
+	UserID:     1,
+	UserName:   "Alice",
+	Size:       2,
+}
+
+var mockPrivateCollection = models.Collection{
+	ID:         2,
+	ShortID:    "bobsPrivat",
+	Title:      "Bob's drafts",
+	Visibility: models.VisibilityPrivate,
+	Created:    now,
+	UserID:     2,
+	UserName:   "Bob",
+}
+
+var mockOtherCollection = models.Collection{
+	ID:         3,
+	ShortID:    "bobsPublic",
+	Title:      "Onboarding scripts",
+	Visibility: models.VisibilityPublic,
+	Created:    now,
+	UserID:     2,
+	UserName:   "Bob",
+}
+
+type CollectionModel struct{}
+
+func (m *CollectionModel) Insert(input models.CollectionInput, userID int) (string, error) {
+	return "newCollect", nil
+}
+
+func (m *CollectionModel) Get(shortID string) (models.Collection, error) {
+	for _, c := range []models.Collection{mockCollection, mockPrivateCollection, mockOtherCollection} {
+		if c.ShortID == shortID {
+			return c, nil
+		}
+	}
+
+	return models.Collection{}, models.ErrNoRecord
+}
+
+func (m *CollectionModel) Update(id int, input models.CollectionInput) error {
+	return nil
+}
+
+func (m *CollectionModel) Delete(id int) error {
+	switch id {
+	case 1, 2, 3:
+		return nil
+	default:
+		return models.ErrNoRecord
+	}
+}
+
+func (m *CollectionModel) ByUser(userID int) ([]models.Collection, error) {
+	switch userID {
+	case 1:
+		return []models.Collection{mockCollection}, nil
+	case 2:
+		return []models.Collection{mockPrivateCollection, mockOtherCollection}, nil
+	default:
+		return nil, nil
+	}
+}
+
+func (m *CollectionModel) Snippets(collectionID, userID int) ([]models.Snippet, error) {
+	switch collectionID {
+	case 1:
+		return []models.Snippet{mockSnippet, mockOtherSnippet}, nil
+	default:
+		return nil, nil
+	}
+}
+
+func (m *CollectionModel) AddSnippet(collectionID, snippetID int) error {
+	return nil
+}
+
+func (m *CollectionModel) RemoveSnippet(collectionID, snippetID int) error {
+	return nil
+}
+
+func (m *CollectionModel) MoveSnippet(collectionID, snippetID, offset int) error {
+	switch {
+	case collectionID == 1 && (snippetID == 1 || snippetID == 3):
+		return nil
+	default:
+		return models.ErrNoRecord
+	}
+}
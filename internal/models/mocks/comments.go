@@ -0,0 +1,86 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/mixnblend/snippetbox/internal/models"
+)
+
+// The mock user (Alice, ID 1) owns mockSnippet, so any of the comments on it
+// can be deleted. Alice's comment on mockOtherSnippet was posted too long ago
+// to be edited, and Bob's comment there belongs to someone else entirely.
+var mockComments = []models.Comment{
+	{
+		ID:             1,
+		SnippetID:      1,
+		SnippetShortID: "silentPond",
+		UserID:         2,
+		UserName:       "Bob",
+		Content:        "What a lovely haiku",
+		Created:        now.Add(-time.Hour),
+	},
+	{
+		ID:             2,
+		SnippetID:      1,
+		SnippetShortID: "silentPond",
+		UserID:         1,
+		UserName:       "Alice",
+		ParentID:       1,
+		Content:        "Thank you, Bob!",
+		Created:        now,
+	},
+	{
+		ID:             3,
+		SnippetID:      3,
+		SnippetShortID: "wintryWood",
+		UserID:         1,
+		UserName:       "Alice",
+		Content:        "Brrr",
+		Created:        now.Add(-time.Hour),
+	},
+	{
+		ID:             4,
+		SnippetID:      3,
+		SnippetShortID: "wintryWood",
+		UserID:         2,
+		UserName:       "Bob",
+		Content:        "Wrap up warm",
+		Created:        now,
+	},
+}
+
+type CommentModel struct{}
+
+func (m *CommentModel) Insert(snippetID, userID, parentID int, content string) (int, error) {
+	return 5, nil
+}
+
+func (m *CommentModel) Get(id int) (models.Comment, error) {
+	for _, c := range mockComments {
+		if c.ID == id {
+			return c, nil
+		}
+	}
+
+	return models.Comment{}, models.ErrNoRecord
+}
+
+func (m *CommentModel) ForSnippet(snippetID int) ([]models.Comment, error) {
+	var comments []models.Comment
+
+	for _, c := range mockComments {
+		if c.SnippetID == snippetID {
+			comments = append(comments, c)
+		}
+	}
+
+	return comments, nil
+}
+
+func (m *CommentModel) Update(id int, content string) error {
+	return nil
+}
+
+func (m *CommentModel) Delete(id int) error {
+	return nil
+}
@@ -1,36 +1,308 @@
 package mocks
 
 import (
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/mixnblend/snippetbox/internal/models"
+	"github.com/mixnblend/snippetbox/internal/search"
 )
 
 var now = time.Now()
 
 var mockSnippet = models.Snippet{
-	ID:      1,
-	Title:   "An old silent pond",
-	Content: "An old silent pond...",
-	Created: now,
-	Expires: now,
+	ID:         1,
+	ShortID:    "silentPond",
+	Title:      "An old silent pond",
+	Content:    "An old silent pond...",
+	Created:    now,
+	Expires:    now,
+	UserID:     1,
+	UserName:   "Alice",
+	Visibility: models.VisibilityPublic,
+	Tags:       []string{"haiku", "nature"},
+	Files:      []models.SnippetFile{{Name: "an-old-silent-pond.txt", Content: "An old silent pond..."}},
+	Forks:      2,
+	Stars:      3,
 }
 
+// mockOtherSnippet is owned by a user other than the mock logged-in user, so
+// it can be used to exercise owner-only authorization. It never expires, has
+// two files and was forked from mockSnippet.
+var mockOtherSnippet = models.Snippet{
+	ID:         3,
+	ShortID:    "wintryWood",
+	Title:      "Over the wintry forest",
+	Content:    "==> forest.txt <==\nOver the wintry forest,\n\n==> winds.md <==\n# Winds\n\nwinds howl in rage...",
+	Created:    now,
+	UserID:     2,
+	UserName:   "Bob",
+	Visibility: models.VisibilityPublic,
+	Files: []models.SnippetFile{
+		{Name: "forest.txt", Content: "Over the wintry forest,"},
+		{Name: "winds.md", Language: "markdown", Content: "# Winds\n\nwinds howl in rage..."},
+	},
+	ParentID: 1,
+	Parent:   &models.SnippetRef{ID: 1, ShortID: "silentPond", Title: "An old silent pond", UserID: 1, Visibility: models.VisibilityPublic},
+}
+
+// mockPrivateSnippet is a private snippet owned by a user other than the mock
+// logged-in user, so it should never be visible to them.
+var mockPrivateSnippet = models.Snippet{
+	ID:         4,
+	ShortID:    "autumnMorn",
+	Title:      "First autumn morning",
+	Content:    "First autumn morning, the mirror I stare into...",
+	Created:    now,
+	Expires:    now,
+	UserID:     2,
+	UserName:   "Bob",
+	Visibility: models.VisibilityPrivate,
+	Files:      []models.SnippetFile{{Name: "first-autumn-morning.txt", Content: "First autumn morning, the mirror I stare into..."}},
+}
+
+// mockBurnSnippet is a burn-after-reading snippet owned by a user other than
+// the mock logged-in user.
+var mockBurnSnippet = models.Snippet{
+	ID:               5,
+	ShortID:          "deployTokn",
+	Title:            "Deploy token",
+	Content:          "t0k3n-s3cr3t",
+	Created:          now,
+	Expires:          now,
+	UserID:           2,
+	UserName:         "Bob",
+	Visibility:       models.VisibilityUnlisted,
+	BurnAfterReading: true,
+	Files:            []models.SnippetFile{{Name: "deploy-token.txt", Content: "t0k3n-s3cr3t"}},
+}
+
+// mockProtectedSnippet is a passphrase protected snippet owned by a user other
+// than the mock logged-in user. It can be unlocked with ValidPassphrase.
+var mockProtectedSnippet = models.Snippet{
+	ID:                6,
+	ShortID:           "contractor",
+	Title:             "Contractor onboarding",
+	Content:           "VPN: vpn.example.com",
+	Created:           now,
+	Expires:           now,
+	UserID:            2,
+	UserName:          "Bob",
+	Visibility:        models.VisibilityUnlisted,
+	Protected:         true,
+	PassphraseVersion: 1,
+	Files:             []models.SnippetFile{{Name: "contractor-onboarding.txt", Content: "VPN: vpn.example.com"}},
+}
+
+// mockNewSnippet is returned by Get() for the short ID given out by Insert(),
+// as if the snippet had just been created by Alice.
+var mockNewSnippet = models.Snippet{
+	ID:         7,
+	ShortID:    "newSnippet",
+	Title:      "O snail",
+	Content:    "O snail\nClimb Mount Fuji,",
+	Files:      []models.SnippetFile{{Name: "O snail.txt", Language: "text", Content: "O snail\nClimb Mount Fuji,"}},
+	Created:    now,
+	Expires:    now.Add(7 * 24 * time.Hour),
+	UserID:     1,
+	UserName:   "Alice",
+	Visibility: models.VisibilityPublic,
+}
+
+const (
+	ValidPassphrase     = "open sesame"
+	ThrottledPassphrase = "too many tries"
+)
+
 type SnippetModel struct{}
 
-func (m *SnippetModel) Insert(title string, content string, expires int) (int, error) {
-	return 2, nil
+var mockSnippets = []models.Snippet{
+	mockSnippet,
+	mockOtherSnippet,
+	mockPrivateSnippet,
+	mockBurnSnippet,
+	mockProtectedSnippet,
+	mockNewSnippet,
+}
+
+func (m *SnippetModel) Insert(input models.SnippetInput, userID int) (string, error) {
+	return "newSnippet", nil
+}
+
+func (m *SnippetModel) Get(shortID string) (models.Snippet, error) {
+	for _, s := range mockSnippets {
+		if s.ShortID == shortID {
+			return s, nil
+		}
+	}
+
+	return models.Snippet{}, models.ErrNoRecord
+}
+
+func (m *SnippetModel) LegacyShortID(id int) (string, error) {
+	// Only the first snippet predates short IDs.
+	if id == mockSnippet.ID {
+		return mockSnippet.ShortID, nil
+	}
+
+	return "", models.ErrNoRecord
+}
+
+func (m *SnippetModel) Latest() ([]models.Snippet, error) {
+	return []models.Snippet{mockSnippet}, nil
 }
 
-func (m *SnippetModel) Get(id int) (models.Snippet, error) {
+// List pages through the public mock snippets, newest (mockOtherSnippet)
+// first, optionally filtered by tag. The mock cursors only use the snippet
+// IDs.
+func (m *SnippetModel) List(filter models.SnippetFilter, page models.SnippetPage) (models.SnippetList, error) {
+	var snippets []models.Snippet
+	for _, s := range []models.Snippet{mockOtherSnippet, mockSnippet} {
+		if filter.Tag == "" || slices.Contains(s.Tags, filter.Tag) {
+			snippets = append(snippets, s)
+		}
+	}
+	if page.Sort == models.SortOldest {
+		slices.Reverse(snippets)
+	}
+
+	// Find where the page starts, counting backwards from the cursor if
+	// need be.
+	start := 0
+	if !page.Cursor.IsZero() {
+		for i, s := range snippets {
+			if s.ID == page.Cursor.ID {
+				start = i + 1
+				if page.Cursor.Backward {
+					start = max(i-page.Limit, 0)
+				}
+			}
+		}
+	}
+	end := min(start+page.Limit, len(snippets))
+
+	list := models.SnippetList{Snippets: snippets[start:end]}
+	if end < len(snippets) {
+		list.Next = &models.SnippetCursor{ID: snippets[end-1].ID}
+	}
+	if start > 0 {
+		list.Prev = &models.SnippetCursor{ID: snippets[start].ID, Backward: true}
+	}
+
+	return list, nil
+}
+
+// Search finds mockSnippet for any query which includes "pond".
+func (m *SnippetModel) Search(query search.Query, userID int, limit int) ([]models.Snippet, error) {
+	for _, term := range query.Included() {
+		if strings.Contains(strings.ToLower(term.Text), "pond") {
+			return []models.Snippet{mockSnippet}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (m *SnippetModel) PopularTags(limit int) ([]models.TagCount, error) {
+	return []models.TagCount{{Name: "haiku", Count: 3}, {Name: "nature", Count: 1}}, nil
+}
+
+func (m *SnippetModel) ByUser(userID int) ([]models.Snippet, error) {
+	switch userID {
+	case 1:
+		return []models.Snippet{mockSnippet}, nil
+	default:
+		return nil, nil
+	}
+}
+
+func (m *SnippetModel) Update(id int, input models.SnippetInput) error {
 	switch id {
+	case 1, 3, 4:
+		return nil
+	default:
+		return models.ErrNoRecord
+	}
+}
+
+func (m *SnippetModel) Delete(id int) error {
+	switch id {
+	case 1, 3, 4:
+		return nil
+	default:
+		return models.ErrNoRecord
+	}
+}
+
+var mockRevisions = []models.SnippetRevision{
+	{
+		ID:        2,
+		SnippetID: 1,
+		Version:   2,
+		Title:     "An old silent pond",
+		Content:   "An old silent pond...\nA frog jumps into the pond,",
+		Created:   now,
+		UserID:    1,
+		UserName:  "Alice",
+	},
+	{
+		ID:        1,
+		SnippetID: 1,
+		Version:   1,
+		Title:     "An old silent pond",
+		Content:   "An old silent pond...",
+		Created:   now,
+		UserID:    1,
+		UserName:  "Alice",
+	},
+}
+
+func (m *SnippetModel) Revisions(snippetID int) ([]models.SnippetRevision, error) {
+	switch snippetID {
 	case 1:
-		return mockSnippet, nil
+		return mockRevisions, nil
+	default:
+		return nil, nil
+	}
+}
+
+func (m *SnippetModel) Revision(snippetID int, version int) (models.SnippetRevision, error) {
+	if snippetID == 1 {
+		for _, rev := range mockRevisions {
+			if rev.Version == version {
+				return rev, nil
+			}
+		}
+	}
+
+	return models.SnippetRevision{}, models.ErrNoRecord
+}
+
+func (m *SnippetModel) Burn(id int) (models.Snippet, error) {
+	switch id {
+	case 5:
+		return mockBurnSnippet, nil
 	default:
 		return models.Snippet{}, models.ErrNoRecord
 	}
 }
 
-func (m *SnippetModel) Latest() ([]models.Snippet, error) {
-	return []models.Snippet{mockSnippet}, nil
+func (m *SnippetModel) Unlock(id int, passphrase string) error {
+	if id != 6 {
+		return nil
+	}
+
+	switch passphrase {
+	case ValidPassphrase:
+		return nil
+	case ThrottledPassphrase:
+		return models.ErrTooManyAttempts
+	default:
+		return models.ErrInvalidCredentials
+	}
+}
+
+func (m *SnippetModel) DeleteExpired(limit int) ([]models.Snippet, error) {
+	return nil, nil
 }
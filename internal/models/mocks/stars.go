@@ -0,0 +1,35 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/mixnblend/snippetbox/internal/models"
+)
+
+// StarModel pretends that the mock logged-in user has starred mockSnippet.
+type StarModel struct{}
+
+func (m *StarModel) Star(userID, snippetID int) error {
+	return nil
+}
+
+func (m *StarModel) Unstar(userID, snippetID int) error {
+	return nil
+}
+
+func (m *StarModel) Starred(userID, snippetID int) (bool, error) {
+	return userID == 1 && snippetID == 1, nil
+}
+
+func (m *StarModel) ByUser(userID int) ([]models.Snippet, error) {
+	switch userID {
+	case 1:
+		return []models.Snippet{mockSnippet}, nil
+	default:
+		return nil, nil
+	}
+}
+
+func (m *StarModel) MostStarred(since time.Time, limit int) ([]models.Snippet, error) {
+	return []models.Snippet{mockSnippet}, nil
+}
@@ -0,0 +1,90 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/mixnblend/snippetbox/internal/models"
+)
+
+// The mock user (Alice, ID 1) has two tokens: one which can read and write
+// snippets, and one which can only read them. Bob (ID 2) has a write-only
+// token. The plain text of each is below.
+const (
+	ValidToken     = "snip_valid"
+	ReadOnlyToken  = "snip_readonly"
+	BobsWriteToken = "snip_bob"
+	NewToken       = "snip_new"
+)
+
+var mockToken = models.Token{
+	ID:       1,
+	UserID:   1,
+	Name:     "CI",
+	Scopes:   []string{models.ScopeSnippetsRead, models.ScopeSnippetsWrite},
+	Created:  now,
+	Expires:  now.Add(30 * 24 * time.Hour),
+	LastUsed: now,
+}
+
+var mockReadOnlyToken = models.Token{
+	ID:      2,
+	UserID:  1,
+	Name:    "Dashboard",
+	Scopes:  []string{models.ScopeSnippetsRead},
+	Created: now,
+	Expires: now.Add(30 * 24 * time.Hour),
+}
+
+var mockBobsToken = models.Token{
+	ID:      3,
+	UserID:  2,
+	Name:    "Deploys",
+	Scopes:  []string{models.ScopeSnippetsWrite},
+	Created: now,
+	Expires: now.Add(30 * 24 * time.Hour),
+}
+
+type TokenModel struct{}
+
+func (m *TokenModel) Insert(userID int, input models.TokenInput) (string, error) {
+	return NewToken, nil
+}
+
+func (m *TokenModel) ByUser(userID int) ([]models.Token, error) {
+	switch userID {
+	case 1:
+		return []models.Token{mockToken, mockReadOnlyToken}, nil
+	case 2:
+		return []models.Token{mockBobsToken}, nil
+	default:
+		return nil, nil
+	}
+}
+
+func (m *TokenModel) Delete(id, userID int) error {
+	tokens, _ := m.ByUser(userID)
+	for _, t := range tokens {
+		if t.ID == id {
+			return nil
+		}
+	}
+
+	return models.ErrNoRecord
+}
+
+func (m *TokenModel) Authenticate(plaintext string) (models.Token, error) {
+	switch plaintext {
+	case ValidToken:
+		return mockToken, nil
+	case ReadOnlyToken:
+		return mockReadOnlyToken, nil
+	case BobsWriteToken:
+		return mockBobsToken, nil
+	default:
+		return models.Token{}, models.ErrInvalidCredentials
+	}
+}
+
+func (m *TokenModel) DeleteExpired(limit int) (int, error) {
+	return 0, nil
+}
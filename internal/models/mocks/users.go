@@ -20,6 +20,13 @@ var ValidUserCredentials = &UserCredentials{
 	Password: "pa$$word",
 }
 
+// Alice's account (ID 1) has been verified, but Bob's (ID 2) hasn't, so
+// Bob's snippets can't be kept for as long.
+var UnverifiedUserCredentials = &UserCredentials{
+	UserName: "bob@example.com",
+	Password: "pa$$word",
+}
+
 func (m *UserModel) Insert(name, email, password string) error {
 	switch email {
 	case DuplicateEmail:
@@ -33,13 +40,16 @@ func (m *UserModel) Authenticate(email, password string) (int, error) {
 	if email == ValidUserCredentials.UserName && password == ValidUserCredentials.Password {
 		return 1, nil
 	}
+	if email == UnverifiedUserCredentials.UserName && password == UnverifiedUserCredentials.Password {
+		return 2, nil
+	}
 
 	return 0, models.ErrInvalidCredentials
 }
 
 func (m *UserModel) Exists(id int) (bool, error) {
 	switch id {
-	case 1:
+	case 1, 2:
 		return true, nil
 	default:
 		return false, nil
@@ -48,11 +58,22 @@ func (m *UserModel) Exists(id int) (bool, error) {
 
 func (m *UserModel) Get(id int) (models.User, error) {
 
-	if id == 1 {
+	switch id {
+	case 1:
+		u := models.User{
+			ID:       1,
+			Name:     "Alice",
+			Email:    "alice@example.com",
+			Created:  time.Now(),
+			Verified: true,
+		}
+
+		return u, nil
+	case 2:
 		u := models.User{
-			ID:      1,
-			Name:    "Alice",
-			Email:   "alice@example.com",
+			ID:      2,
+			Name:    "Bob",
+			Email:   "bob@example.com",
 			Created: time.Now(),
 		}
 
@@ -62,6 +83,15 @@ func (m *UserModel) Get(id int) (models.User, error) {
 	return models.User{}, models.ErrNoRecord
 }
 
+func (m *UserModel) Verify(email string) error {
+	switch email {
+	case ValidUserCredentials.UserName, UnverifiedUserCredentials.UserName:
+		return nil
+	default:
+		return models.ErrNoRecord
+	}
+}
+
 func (m *UserModel) PasswordUpdate(id int, currentPassword, newPassword string) error {
 	if id == 1 {
 		return nil
@@ -0,0 +1,102 @@
+package mocks
+
+import (
+	"slices"
+	"time"
+
+	"github.com/mixnblend/snippetbox/internal/models"
+)
+
+// The mock user (Alice, ID 1) has one webhook, sent new and deleted
+// snippets, whose last delivery failed. New webhooks are given
+// NewWebhookSecret.
+const NewWebhookSecret = "whsec_new"
+
+var mockWebhook = models.Webhook{
+	ID:      1,
+	UserID:  1,
+	URL:     "https://chat.example.com/hooks/snippets",
+	Secret:  "whsec_mock",
+	Events:  []string{models.EventSnippetCreated, models.EventSnippetDeleted},
+	Created: now,
+}
+
+var mockDelivery = models.WebhookDelivery{
+	ID:             1,
+	WebhookID:      1,
+	URL:            mockWebhook.URL,
+	Secret:         mockWebhook.Secret,
+	Event:          models.EventSnippetCreated,
+	Payload:        []byte(`{"event":"snippet.created"}`),
+	Status:         models.DeliveryFailed,
+	Attempts:       6,
+	ResponseStatus: 502,
+	Created:        now,
+	LastAttempt:    now.Add(time.Hour),
+}
+
+type WebhookModel struct{}
+
+func (m *WebhookModel) Insert(userID int, input models.WebhookInput) (models.Webhook, error) {
+	return models.Webhook{
+		ID:      2,
+		UserID:  userID,
+		URL:     input.URL,
+		Secret:  NewWebhookSecret,
+		Events:  input.Events,
+		Created: now,
+	}, nil
+}
+
+func (m *WebhookModel) ByUser(userID int) ([]models.Webhook, error) {
+	switch userID {
+	case 1:
+		return []models.Webhook{mockWebhook}, nil
+	default:
+		return nil, nil
+	}
+}
+
+func (m *WebhookModel) Delete(id, userID int) error {
+	webhooks, _ := m.ByUser(userID)
+	for _, w := range webhooks {
+		if w.ID == id {
+			return nil
+		}
+	}
+
+	return models.ErrNoRecord
+}
+
+func (m *WebhookModel) Subscribed(userID int, event string) ([]models.Webhook, error) {
+	webhooks, _ := m.ByUser(userID)
+
+	return slices.DeleteFunc(webhooks, func(w models.Webhook) bool {
+		return !w.HasEvent(event)
+	}), nil
+}
+
+func (m *WebhookModel) InsertDelivery(webhookID int, event string, payload []byte, nextAttempt time.Time) (int, error) {
+	return 2, nil
+}
+
+func (m *WebhookModel) ClaimDue(limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	return nil, nil
+}
+
+func (m *WebhookModel) RecordAttempt(id int, attempt models.WebhookAttempt) error {
+	return nil
+}
+
+func (m *WebhookModel) Deliveries(userID int, limit int) ([]models.WebhookDelivery, error) {
+	switch userID {
+	case 1:
+		return []models.WebhookDelivery{mockDelivery}, nil
+	default:
+		return nil, nil
+	}
+}
+
+func (m *WebhookModel) DeleteExpiredDeliveries(limit int) (int, error) {
+	return 0, nil
+}
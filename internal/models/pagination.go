@@ -0,0 +1,82 @@
+package models
+
+import (
+	"time"
+)
+
+// Snippet listings can be sorted newest or oldest first.
+const (
+	SortNewest = "newest"
+	SortOldest = "oldest"
+)
+
+// SnippetFilter restricts which snippets are listed. The zero value lists
+// every unexpired snippet.
+type SnippetFilter struct {
+	// Visibility only lists snippets with the given visibility, if set.
+	Visibility string
+	// Tag only lists snippets with the given tag, if set.
+	Tag string
+}
+
+// SnippetPage selects one page of a snippet listing. Sort is SortNewest
+// (the default) or SortOldest, and Limit is the maximum number of snippets
+// on the page. The zero Cursor selects the first page.
+type SnippetPage struct {
+	Sort   string
+	Limit  int
+	Cursor SnippetCursor
+}
+
+// A SnippetCursor marks a position in a snippet listing by the creation time
+// and ID of a snippet. Together these are unique, and are backed by the
+// index on snippets(created), which implicitly includes the primary key.
+// A page is fetched from the snippets after the cursor, or before it if
+// Backward is set.
+type SnippetCursor struct {
+	Created  time.Time
+	ID       int
+	Backward bool
+}
+
+// IsZero reports whether the cursor is unset.
+func (c SnippetCursor) IsZero() bool {
+	return c.ID == 0
+}
+
+// SnippetList is one page of a snippet listing. Next and Prev are the
+// cursors for the following and preceding pages, or nil if there are none.
+type SnippetList struct {
+	Snippets []Snippet
+	Next     *SnippetCursor
+	Prev     *SnippetCursor
+}
+
+// newSnippetList works out the cursors either side of a page of snippets.
+// The page was fetched from cursor, and more reports whether a further
+// snippet was found beyond the page in the direction it was fetched.
+func newSnippetList(snippets []Snippet, cursor SnippetCursor, more bool) SnippetList {
+	list := SnippetList{Snippets: snippets}
+	if len(snippets) == 0 {
+		return list
+	}
+
+	first, last := snippets[0], snippets[len(snippets)-1]
+
+	// Going forwards there's a next page if more were found, and a previous
+	// page if we started from a cursor. Going backwards it's the other way
+	// around.
+	hasNext, hasPrev := more, !cursor.IsZero()
+	if cursor.Backward {
+		hasNext, hasPrev = true, more
+	}
+
+	if hasNext {
+		list.Next = &SnippetCursor{Created: last.Created, ID: last.ID}
+	}
+	if hasPrev {
+		list.Prev = &SnippetCursor{Created: first.Created, ID: first.ID, Backward: true}
+	}
+
+	return list
+}
@@ -0,0 +1,114 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Define a SnippetRevision type to hold an immutable copy of a snippet as it
+// was after it was created or edited. Versions are numbered from 1 for each
+// snippet.
+type SnippetRevision struct {
+	ID        int
+	SnippetID int
+	Version   int
+	Title     string
+	Content   string
+	Created   time.Time
+	UserID    int
+	UserName  string
+}
+
+const revisionSelect = `SELECT snippet_revisions.id, snippet_revisions.snippet_id,
+	snippet_revisions.version, snippet_revisions.title, snippet_revisions.content,
+	snippet_revisions.created, snippet_revisions.user_id, users.name
+	FROM snippet_revisions INNER JOIN users ON users.id = snippet_revisions.user_id`
+
+func scanRevision(row rowScanner) (SnippetRevision, error) {
+	var rev SnippetRevision
+
+	err := row.Scan(&rev.ID, &rev.SnippetID, &rev.Version, &rev.Title, &rev.Content,
+		&rev.Created, &rev.UserID, &rev.UserName)
+	return rev, err
+}
+
+// insertRevision copies the current state of a snippet into the
+// snippet_revisions table as its next version. It must be called inside the
+// same transaction as the change to the snippet, so that the history can
+// never disagree with the snippet itself.
+//
+// The snippet's row is locked with SELECT ... FOR UPDATE first, so that two
+// edits of the same snippet at once take turns here rather than both picking
+// the same version, and the latest version is found with a locking read too,
+// so that it sees a version committed by the other edit since this
+// transaction started.
+func insertRevision(tx *sql.Tx, snippetID int) error {
+	var id, version int
+
+	stmt := `SELECT id FROM snippets WHERE id = ? FOR UPDATE`
+
+	err := tx.QueryRow(stmt, snippetID).Scan(&id)
+	if err != nil {
+		return err
+	}
+
+	stmt = `SELECT COALESCE(MAX(version), 0) + 1 FROM snippet_revisions WHERE snippet_id = ? FOR UPDATE`
+
+	err = tx.QueryRow(stmt, snippetID).Scan(&version)
+	if err != nil {
+		return err
+	}
+
+	stmt = `INSERT INTO snippet_revisions (snippet_id, version, title, content, created, user_id)
+	SELECT id, ?, title, content, UTC_TIMESTAMP(), user_id FROM snippets WHERE id = ?`
+
+	_, err = tx.Exec(stmt, version, snippetID)
+	return err
+}
+
+// This will return every revision of a snippet, newest first.
+func (m *SnippetModel) Revisions(snippetID int) ([]SnippetRevision, error) {
+	stmt := revisionSelect + `
+	WHERE snippet_revisions.snippet_id = ? ORDER BY snippet_revisions.version DESC`
+
+	rows, err := m.DB.Query(stmt, snippetID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var revisions []SnippetRevision
+
+	for rows.Next() {
+		rev, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// This will return a specific version of a snippet.
+func (m *SnippetModel) Revision(snippetID int, version int) (SnippetRevision, error) {
+	stmt := revisionSelect + `
+	WHERE snippet_revisions.snippet_id = ? AND snippet_revisions.version = ?`
+
+	rev, err := scanRevision(m.DB.QueryRow(stmt, snippetID, version))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return SnippetRevision{}, ErrNoRecord
+		} else {
+			return SnippetRevision{}, err
+		}
+	}
+
+	return rev, nil
+}
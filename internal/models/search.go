@@ -0,0 +1,133 @@
+package models
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mixnblend/snippetbox/internal/search"
+)
+
+// likeEscaper escapes the characters which have a special meaning in a LIKE
+// pattern, using MySQL's default escape character.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// containsPattern returns a LIKE pattern which matches any string containing
+// s.
+func containsPattern(s string) string {
+	return "%" + likeEscaper.Replace(s) + "%"
+}
+
+// termCondition returns a WHERE condition, and its arguments, which matches
+// snippets containing the term in the field it is restricted to.
+func termCondition(term search.Term) (string, []any) {
+	pattern := containsPattern(term.Text)
+
+	if term.Field == search.FieldTitle {
+		return "snippets.title LIKE ?", []any{pattern}
+	}
+
+	return "(snippets.title LIKE ? OR snippets.content LIKE ?)", []any{pattern, pattern}
+}
+
+// InnoDB leaves words shorter than innodb_ft_min_token_size, and stopwords,
+// out of a FULLTEXT index. These are the server's defaults.
+const ftMinTokenSize = 3
+
+var ftStopwords = map[string]bool{
+	"a": true, "about": true, "an": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "com": true, "de": true, "en": true, "for": true,
+	"from": true, "how": true, "i": true, "in": true, "is": true, "it": true,
+	"la": true, "of": true, "on": true, "or": true, "that": true, "the": true,
+	"this": true, "to": true, "was": true, "what": true, "when": true,
+	"where": true, "who": true, "will": true, "with": true, "und": true,
+	"www": true,
+}
+
+// wordRX matches the words of a term the way the FULLTEXT parser splits them.
+// Apostrophes are included so that words like "don't", which the parser may
+// keep whole, can be left to the LIKE conditions.
+var wordRX = regexp.MustCompile(`[\pL\pN_']+`)
+
+// indexedWords returns the words of the terms which are in the FULLTEXT
+// index, without repeats.
+func indexedWords(terms []search.Term) []string {
+	var words []string
+
+	for _, term := range terms {
+		for _, word := range wordRX.FindAllString(strings.ToLower(term.Text), -1) {
+			if utf8.RuneCountInString(word) < ftMinTokenSize || ftStopwords[word] ||
+				strings.Contains(word, "'") || slices.Contains(words, word) {
+				continue
+			}
+			words = append(words, word)
+		}
+	}
+
+	return words
+}
+
+// This will return up to limit unexpired snippets matching the query, most
+// relevant first. See searchStatement() for how the query is matched.
+//
+// Only public snippets are searched, along with any snippets owned by the
+// user with the given ID (pass 0 for anonymous visitors). Passphrase
+// protected and burn-after-reading snippets are left out for everyone else,
+// so their content can't be leaked through search results.
+func (m *SnippetModel) Search(query search.Query, userID int, limit int) ([]Snippet, error) {
+	if query.IsEmpty() {
+		return nil, nil
+	}
+
+	stmt, args := searchStatement(query, userID, limit)
+
+	return querySnippets(m.DB, stmt, args...)
+}
+
+// searchStatement returns the SELECT statement, and its arguments, used by
+// Search(). Each word of the included terms which is in the FULLTEXT index on
+// snippets(title, content) must MATCH, which lets MySQL find the candidate
+// snippets through the index rather than reading the whole table. A single
+// word in natural language mode matches the rows containing it, just like a
+// required +word in boolean mode. Short words and stopwords aren't in the
+// index, so requiring them to MATCH would lose every snippet; a query made up
+// only of those (such as "go" or "sh") falls back to the LIKE conditions
+// alone, and so to a scan of the table.
+//
+// Each term is then checked with LIKE, so that phrases have to match exactly,
+// and title: and excluded terms are honoured. Results are ranked by how well
+// they MATCH the query as a whole.
+func searchStatement(query search.Query, userID int, limit int) (string, []any) {
+	conditions := []string{
+		notExpired,
+		`(snippets.user_id = ? OR (snippets.visibility = ? AND snippets.burn_after_reading = FALSE
+		AND snippets.passphrase_hash IS NULL))`,
+	}
+	args := []any{userID, VisibilityPublic}
+
+	for _, word := range indexedWords(query.Included()) {
+		conditions = append(conditions, "MATCH(snippets.title, snippets.content) AGAINST(?)")
+		args = append(args, word)
+	}
+
+	for _, term := range query.Included() {
+		condition, termArgs := termCondition(term)
+		conditions = append(conditions, condition)
+		args = append(args, termArgs...)
+	}
+
+	for _, term := range query.Excluded() {
+		condition, termArgs := termCondition(term)
+		conditions = append(conditions, "NOT "+condition)
+		args = append(args, termArgs...)
+	}
+
+	stmt := snippetSelect + `
+	WHERE ` + strings.Join(conditions, " AND ") + `
+	ORDER BY MATCH(snippets.title, snippets.content) AGAINST(?) DESC, snippets.created DESC, snippets.id DESC
+	LIMIT ?`
+	args = append(args, strings.Join(query.Highlights(), " "), limit)
+
+	return stmt, args
+}
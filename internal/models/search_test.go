@@ -0,0 +1,166 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mixnblend/snippetbox/internal/assert"
+	"github.com/mixnblend/snippetbox/internal/search"
+)
+
+func TestSnippetModelSearchIntegration(t *testing.T) {
+	integrationTest(t)
+
+	// given ... we have a database with "An old silent pond" in it
+	db := newTestDB(t)
+	m := SnippetModel{DB: db}
+
+	// and ... we have added some more snippets
+	inputs := []SnippetInput{
+		{Title: "Pond life", Files: singleFile("Frogs and newts live in the pond"), Visibility: VisibilityPublic},
+		{Title: "Winter", Files: singleFile("Over the wintry forest, the pond is frozen"), Visibility: VisibilityPublic},
+		{Title: "Private pond", Files: singleFile("A secret pond"), Visibility: VisibilityPrivate},
+		{Title: "Protected pond", Files: singleFile("A hidden pond"), Visibility: VisibilityPublic, Passphrase: "open sesame"},
+		{Title: "Burning pond", Files: singleFile("A burning pond"), Visibility: VisibilityPublic, BurnAfterReading: true},
+		{Title: "Shell one-liners", Files: singleFile("#!/bin/sh\nls -la | wc -l # count the files"), Visibility: VisibilityPublic},
+	}
+	for _, input := range inputs {
+		_, err := m.Insert(input, 1)
+		assert.NilError(t, err)
+	}
+
+	titles := func(snippets []Snippet) string {
+		var titles []string
+		for _, s := range snippets {
+			titles = append(titles, s.Title)
+		}
+		return strings.Join(titles, ",")
+	}
+
+	tests := []struct {
+		name   string
+		query  string
+		userID int
+		want   string
+	}{
+		{
+			name:  "Word",
+			query: "frogs",
+			want:  "Pond life",
+		},
+		{
+			name:  "Every word must match",
+			query: "pond frozen",
+			want:  "Winter",
+		},
+		{
+			name:  "Phrase",
+			query: `"silent pond"`,
+			want:  "An old silent pond",
+		},
+		{
+			name:  "Exclude",
+			query: "pond -frozen -newts",
+			want:  "An old silent pond",
+		},
+		{
+			name:  "Title",
+			query: "title:winter",
+			want:  "Winter",
+		},
+		{
+			name:   "Owner sees their own hidden snippets",
+			query:  "title:private secret",
+			userID: 1,
+			want:   "Private pond",
+		},
+		{
+			name:  "Hidden snippets are left out for everyone else",
+			query: "secret hidden burning",
+			want:  "",
+		},
+		{
+			// Words this short aren't in the FULLTEXT index at all.
+			name:  "Short word",
+			query: "sh",
+			want:  "Shell one-liners",
+		},
+		{
+			name:  "Short words and stopwords",
+			query: `"ls -la" the`,
+			want:  "Shell one-liners",
+		},
+		{
+			name:  "Short word with a longer one",
+			query: "wc files",
+			want:  "Shell one-liners",
+		},
+		{
+			name:  "Only exclusions",
+			query: "-pond",
+			want:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// when ... we search for the query
+			snippets, err := m.Search(search.Parse(tt.query), tt.userID, 10)
+
+			// then ... the expected snippets should be found
+			assert.NilError(t, err)
+			assert.Equal(t, titles(snippets), tt.want)
+		})
+	}
+}
+
+func TestIndexedWords(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{name: "Words", query: "silent pond", want: []string{"silent", "pond"}},
+		{name: "Phrase", query: `"Silent Pond"`, want: []string{"silent", "pond"}},
+		{name: "Short words", query: "go sh", want: nil},
+		{name: "Stopwords", query: "the pond of", want: []string{"pond"}},
+		{name: "Punctuation", query: "ls -la wc-files", want: []string{"files"}},
+		{name: "Apostrophe", query: "don't panic", want: []string{"panic"}},
+		{name: "Repeated", query: "pond title:pond", want: []string{"pond"}},
+		{name: "Excluded", query: "pond -frozen", want: []string{"pond"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// when ... we find the indexed words of the included terms
+			words := indexedWords(search.Parse(tt.query).Included())
+
+			// then ... only those in the FULLTEXT index should be returned
+			assert.Equal(t, strings.Join(words, ","), strings.Join(tt.want, ","))
+		})
+	}
+}
+
+func TestSearchStatement(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		wantMatches int
+	}{
+		{name: "Indexed words", query: "silent pond", wantMatches: 2},
+		{name: "Indexed and short words", query: "wc files", wantMatches: 1},
+		{name: "Only short words", query: "sh", wantMatches: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// when ... we build the statement for a search
+			stmt, _ := searchStatement(search.Parse(tt.query), 0, 10)
+
+			// then ... each indexed word should be matched in the WHERE clause,
+			// which MySQL always evaluates with the FULLTEXT index
+			where := stmt[strings.Index(stmt, "WHERE"):strings.Index(stmt, "ORDER BY")]
+			assert.Equal(t, strings.Count(where, "MATCH(snippets.title, snippets.content) AGAINST(?)"), tt.wantMatches)
+		})
+	}
+}
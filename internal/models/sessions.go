@@ -0,0 +1,34 @@
+package models
+
+import (
+	"database/sql"
+)
+
+type SessionModelInterface interface {
+	DeleteExpired(limit int) (int, error)
+}
+
+// Define a SessionModel type which wraps a sql.DB connection pool. Sessions
+// are read and written by the scs MySQL store; this model only takes care of
+// removing the ones which have expired.
+type SessionModel struct {
+	DB *sql.DB
+}
+
+// This will permanently delete up to limit expired sessions and return how
+// many were deleted, in the same way as SnippetModel.DeleteExpired().
+func (m *SessionModel) DeleteExpired(limit int) (int, error) {
+	stmt := `DELETE FROM sessions WHERE expiry < UTC_TIMESTAMP(6) LIMIT ?`
+
+	result, err := m.DB.Exec(stmt, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(rows), nil
+}
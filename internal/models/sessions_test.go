@@ -0,0 +1,32 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/mixnblend/snippetbox/internal/assert"
+)
+
+func TestSessionModelDeleteExpiredIntegration(t *testing.T) {
+	integrationTest(t)
+
+	// given ... we have a database with an expired and a current session in it
+	db := newTestDB(t)
+	m := SessionModel{DB: db}
+
+	_, err := db.Exec(`INSERT INTO sessions (token, data, expiry) VALUES
+	('expired', '', DATE_SUB(UTC_TIMESTAMP(6), INTERVAL 1 HOUR)),
+	('current', '', DATE_ADD(UTC_TIMESTAMP(6), INTERVAL 1 HOUR))`)
+	assert.NilError(t, err)
+
+	// when ... we delete expired sessions
+	n, err := m.DeleteExpired(10)
+
+	// then ... only the expired session should be deleted
+	assert.NilError(t, err)
+	assert.Equal(t, n, 1)
+
+	var count int
+	err = db.QueryRow(`SELECT COUNT(*) FROM sessions WHERE token = 'current'`).Scan(&count)
+	assert.NilError(t, err)
+	assert.Equal(t, count, 1)
+}
@@ -1,26 +1,257 @@
 package models
 
 import (
+	"crypto/rand"
 	"database/sql"
 	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
 	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/mixnblend/snippetbox/internal/search"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type SnippetModelInterface interface {
-	Insert(title string, content string, expires int) (int, error)
-	Get(id int) (Snippet, error)
+	Insert(input SnippetInput, userID int) (string, error)
+	Get(shortID string) (Snippet, error)
+	LegacyShortID(id int) (string, error)
 	Latest() ([]Snippet, error)
+	List(filter SnippetFilter, page SnippetPage) (SnippetList, error)
+	Search(query search.Query, userID int, limit int) ([]Snippet, error)
+	PopularTags(limit int) ([]TagCount, error)
+	ByUser(userID int) ([]Snippet, error)
+	Update(id int, input SnippetInput) error
+	Delete(id int) error
+	Revisions(snippetID int) ([]SnippetRevision, error)
+	Revision(snippetID int, version int) (SnippetRevision, error)
+	Burn(id int) (Snippet, error)
+	Unlock(id int, passphrase string) error
+	DeleteExpired(limit int) ([]Snippet, error)
 }
 
+// Snippets are identified in URLs by a random short ID of ShortIDLength
+// characters drawn from shortIDAlphabet, rather than by their sequential
+// integer ID, so that they can't be enumerated.
+const (
+	ShortIDLength   = 10
+	shortIDAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+	// The chance of a collision is tiny, but we retry a few times rather
+	// than failing the insert outright.
+	maxShortIDAttempts = 5
+
+	snippetModelUniqueShortIDConstraint = "snippets_uc_short_id"
+)
+
+// After maxUnlockAttempts wrong passphrases in a row, a protected snippet
+// refuses any further unlock attempts for unlockLockout.
+const (
+	maxUnlockAttempts = 5
+	unlockLockout     = 15 * time.Minute
+)
+
+// The visibility of a snippet controls who can see it. Public snippets are
+// listed on the home page, unlisted snippets can be viewed by anyone with the
+// link, and private snippets can only be viewed by their owner.
+const (
+	VisibilityPublic   = "public"
+	VisibilityUnlisted = "unlisted"
+	VisibilityPrivate  = "private"
+)
+
 // Define a Snippet type to hold the data for an individual snippet. Notice how
 // the fields of the struct correspond to the fields in our MySQL snippets
-// table?
+// table? Content holds the content of all the snippet's files joined
+// together (see joinFiles()), while the files themselves are only loaded by
+// Get() and Burn(). ParentID is the ID of the snippet this one was forked
+// from (0 if it wasn't); Get() also loads that snippet into Parent (nil if it
+// no longer exists) and counts the snippet's own forks. Stars is the number
+// of users who have starred the snippet. PassphraseVersion goes up each time
+// the passphrase of a protected snippet is changed or removed.
 type Snippet struct {
-	ID      int
-	Title   string
-	Content string
-	Created time.Time
-	Expires time.Time
+	ID                int
+	ShortID           string
+	Title             string
+	Content           string
+	Created           time.Time
+	Expires           time.Time
+	UserID            int
+	UserName          string
+	Visibility        string
+	BurnAfterReading  bool
+	Protected         bool
+	PassphraseVersion int
+	Tags              []string
+	Files             []SnippetFile
+	ParentID          int
+	Parent            *SnippetRef
+	Forks             int
+	Stars             int
+}
+
+// VisibleTo reports whether the snippet can be viewed by the user with the
+// given ID. Pass 0 for anonymous visitors.
+func (s Snippet) VisibleTo(userID int) bool {
+	return s.Visibility != VisibilityPrivate || s.UserID == userID
+}
+
+// OwnedBy reports whether the snippet belongs to the user with the given ID.
+func (s Snippet) OwnedBy(userID int) bool {
+	return s.UserID == userID
+}
+
+// SnippetInput holds the user-supplied fields used to create or update a
+// snippet. Expires is the time at which the snippet expires; the zero time
+// means it never expires.
+// Passphrase is stored as a bcrypt hash if it isn't empty; when updating a
+// snippet an empty Passphrase leaves the existing one in place unless
+// RemovePassphrase is set. Tags replace any existing tags, and should
+// already have been normalised with validator.NormalizeTags(). Files also
+// replace any existing files, and must each have a unique name. ParentID is
+// the ID of the snippet being forked, and is only used by Insert().
+type SnippetInput struct {
+	Title            string
+	Files            []SnippetFile
+	Expires          time.Time
+	Visibility       string
+	BurnAfterReading bool
+	Passphrase       string
+	RemovePassphrase bool
+	Tags             []string
+	ParentID         int
+}
+
+// passphraseHash returns the bcrypt hash of the input's passphrase, or nil if
+// no passphrase was given.
+func (input SnippetInput) passphraseHash() ([]byte, error) {
+	if input.Passphrase == "" {
+		return nil, nil
+	}
+
+	return bcrypt.GenerateFromPassword([]byte(input.Passphrase), 12)
+}
+
+// expires returns the input's expiry time as a nullable value, using NULL
+// for snippets which never expire.
+func (input SnippetInput) expires() sql.NullTime {
+	return sql.NullTime{Time: input.Expires.UTC(), Valid: !input.Expires.IsZero()}
+}
+
+// parentID returns the ID of the snippet being forked as a nullable value,
+// using NULL for snippets which aren't forks.
+func (input SnippetInput) parentID() sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(input.ParentID), Valid: input.ParentID != 0}
+}
+
+// notExpired is the WHERE condition which filters out expired snippets. A
+// NULL expiry means the snippet never expires.
+const notExpired = `(snippets.expires IS NULL OR snippets.expires > UTC_TIMESTAMP())`
+
+// snippetSelect is the SELECT clause shared by every snippet query. The
+// columns are listed in the order expected by scanSnippet(), and the author's
+// name is joined in from the users table so that templates can show who wrote
+// each snippet. The stars are counted with a subquery, which uses the index
+// on stars.snippet_id.
+const snippetSelect = `SELECT snippets.id, snippets.short_id, snippets.title, snippets.content, snippets.created,
+	snippets.expires, snippets.user_id, users.name, snippets.visibility,
+	snippets.burn_after_reading, snippets.passphrase_hash IS NOT NULL, snippets.passphrase_version, snippets.parent_id,
+	(SELECT COUNT(*) FROM stars WHERE stars.snippet_id = snippets.id)
+	FROM snippets INNER JOIN users ON users.id = snippets.user_id`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanSnippet copies the columns listed in snippetSelect into a Snippet. A
+// NULL expiry is left as the zero time, and a NULL parent as 0.
+func scanSnippet(row rowScanner) (Snippet, error) {
+	var s Snippet
+	var expires sql.NullTime
+	var parentID sql.NullInt64
+
+	err := row.Scan(&s.ID, &s.ShortID, &s.Title, &s.Content, &s.Created, &expires, &s.UserID, &s.UserName,
+		&s.Visibility, &s.BurnAfterReading, &s.Protected, &s.PassphraseVersion, &parentID, &s.Stars)
+	s.Expires = expires.Time
+	s.ParentID = int(parentID.Int64)
+	return s, err
+}
+
+// querySnippets runs a query which selects snippets with snippetSelect, and
+// returns them.
+func querySnippets(q querier, stmt string, args ...any) ([]Snippet, error) {
+	rows, err := q.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	// We defer rows.Close() to ensure the sql.Rows resultset is
+	// always properly closed before querySnippets() returns. This defer
+	// statement should come *after* you check for an error from the Query()
+	// method. Otherwise, if Query() returns an error, you'll get a panic
+	// trying to close a nil resultset.
+	defer rows.Close()
+
+	var snippets []Snippet
+
+	// Use rows.Next to iterate through the rows in the resultset. This
+	// prepares the first (and then each subsequent) row to be acted on by the
+	// rows.Scan() method. If iteration over all the rows completes then the
+	// resultset automatically closes itself and frees-up the underlying
+	// database connection.
+	for rows.Next() {
+		// Use scanSnippet() to copy the values from each field in the row to
+		// a new Snippet object.
+		s, err := scanSnippet(rows)
+		if err != nil {
+			return nil, err
+		}
+		// Append it to the slice of snippets.
+		snippets = append(snippets, s)
+	}
+
+	// When the rows.Next() loop has finished we call rows.Err() to retrieve any
+	// error that was encountered during the iteration. It's important to
+	// call this - don't assume that a successful iteration was completed
+	// over the whole resultset.
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return snippets, nil
+}
+
+// newShortID returns a new random short ID. It uses crypto/rand so that IDs
+// can't be predicted from one another.
+func newShortID() (string, error) {
+	var b strings.Builder
+	max := big.NewInt(int64(len(shortIDAlphabet)))
+
+	for range ShortIDLength {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b.WriteByte(shortIDAlphabet[n.Int64()])
+	}
+
+	return b.String(), nil
+}
+
+// isDuplicateShortID reports whether err was caused by a clash on the given
+// unique short ID constraint.
+func isDuplicateShortID(err error, constraint string) bool {
+	var mySQLError *mysql.MySQLError
+	if errors.As(err, &mySQLError) {
+		return mySQLError.Number == mysqlDuplicateKeyEntryError &&
+			strings.Contains(mySQLError.Message, constraint)
+	}
+
+	return false
 }
 
 // Define a SnippetModel type which wraps a sql.DB connection pool.
@@ -28,59 +259,108 @@ type SnippetModel struct {
 	DB *sql.DB
 }
 
-// This will insert a new snippet into the database.
-func (m *SnippetModel) Insert(title string, content string, expires int) (int, error) {
-	// Write the SQL statement we want to execute. I've split it over two lines
-	// for readability (which is why it's surrounded with backquotes instead
-	// of normal double quotes).
-	stmt := `INSERT INTO snippets (title, content, created, expires) 
-	VALUES (?, ?, UTC_TIMESTAMP(), DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY))`
+// This will insert a new snippet into the database, owned by the given user,
+// and return its short ID. If the randomly generated short ID happens to
+// clash with an existing snippet, a new one is generated and the insert is
+// retried.
+func (m *SnippetModel) Insert(input SnippetInput, userID int) (string, error) {
+	// Hash the passphrase (if any) before starting the transaction, as bcrypt
+	// is deliberately slow.
+	passphraseHash, err := input.passphraseHash()
+	if err != nil {
+		return "", err
+	}
+
+	for attempt := 1; ; attempt++ {
+		shortID, err := newShortID()
+		if err != nil {
+			return "", err
+		}
+
+		err = m.insert(shortID, input, passphraseHash, userID)
+		if err != nil {
+			if isDuplicateShortID(err, snippetModelUniqueShortIDConstraint) && attempt < maxShortIDAttempts {
+				continue
+			}
+			return "", err
+		}
 
-	// Use the Exec() method on the embedded connection pool to execute the
-	// statement. The first parameter is the SQL statement, followed by the
-	// values for the placeholder parameters: title, content and expiry in
-	// that order. This method returns a sql.Result type, which contains some
-	// basic information about what happened when the statement was executed.
-	result, err := m.DB.Exec(stmt, title, content, expires)
+		return shortID, nil
+	}
+}
+
+// insert writes the snippet and its first revision in a single transaction.
+func (m *SnippetModel) insert(shortID string, input SnippetInput, passphraseHash []byte, userID int) error {
+	tx, err := m.DB.Begin()
 	if err != nil {
-		return 0, err
+		return err
 	}
 
-	// Use the lastInsertId() method on the result to get the ID of our newly inserted
-	// record in the snippets table.
+	// Calling Rollback() after a successful Commit() is a no-op, so deferring
+	// it here makes sure the transaction is always closed if we return early.
+	defer tx.Rollback()
+
+	// Write the SQL statement we want to execute. I've split it over a few
+	// lines for readability (which is why it's surrounded with backquotes
+	// instead of normal double quotes).
+	stmt := `INSERT INTO snippets (short_id, title, content, created, expires, user_id, visibility,
+	burn_after_reading, passphrase_hash, parent_id) 
+	VALUES (?, ?, ?, UTC_TIMESTAMP(), ?, ?, ?, ?, ?, ?)`
 
+	// Use the Exec() method on the transaction to execute the statement. The
+	// first parameter is the SQL statement, followed by the values for the
+	// placeholder parameters in the same order as the columns. This method
+	// returns a sql.Result type, which contains some basic information about
+	// what happened when the statement was executed.
+	result, err := tx.Exec(stmt, shortID, input.Title, joinFiles(input.Files), input.expires(), userID,
+		input.Visibility, input.BurnAfterReading, passphraseHash, input.parentID())
+	if err != nil {
+		return err
+	}
+
+	// Use the lastInsertId() method on the result to get the ID of our newly
+	// inserted record in the snippets table, so we can record its first
+	// revision.
 	id, err := result.LastInsertId()
 	if err != nil {
-		return 0, err
+		return err
+	}
+
+	err = setFiles(tx, int(id), input.Files)
+	if err != nil {
+		return err
+	}
+
+	err = insertRevision(tx, int(id))
+	if err != nil {
+		return err
 	}
 
-	// The ID returned has the type int64, so we convert it to an int type before returning.
-	return int(id), nil
+	err = setTags(tx, int(id), input.Tags)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-// This will return a specific snippet based on its id.
-func (m *SnippetModel) Get(id int) (Snippet, error) {
+// This will return a specific snippet based on its short id.
+func (m *SnippetModel) Get(shortID string) (Snippet, error) {
 
 	// Write the SQL statement we want to execute. Again, I've split it over two
 	// lines for readability.
-	stmt := `SELECT id, title, content, created, expires FROM snippets 
-	WHERE expires > UTC_TIMESTAMP() and id = ?`
+	stmt := snippetSelect + `
+	WHERE ` + notExpired + ` AND snippets.short_id = ?`
 
 	// Use the QueryRow() method on the connection pool to execute our
-	// SQL statement, passing in the untrusted id variable as the value for the
-	// placeholder parameter. This returns a pointer to a sql.Row object which
-	// holds the result from the database.
-	row := m.DB.QueryRow(stmt, id)
-
-	// Initialise a new zeroed Snippet struct.
-	var s Snippet
+	// SQL statement, passing in the untrusted shortID variable as the value for
+	// the placeholder parameter. This returns a pointer to a sql.Row object
+	// which holds the result from the database.
+	row := m.DB.QueryRow(stmt, shortID)
 
-	// Use row.Scan() to copy the values from each field in sql.Row to the
-	// corresponding field in the Snippet struct. Notice that the arguments
-	// to row.Scan are *pointers* to the place you want to copy the data into,
-	// and the number of arguments must be exactly the same as the number of
-	// columns returned by your statement.
-	err := row.Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires)
+	// Use scanSnippet() to copy the values from each field in sql.Row to the
+	// corresponding field in a new Snippet struct.
+	s, err := scanSnippet(row)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -90,55 +370,391 @@ func (m *SnippetModel) Get(id int) (Snippet, error) {
 		}
 	}
 
+	// Fetch the snippet's tags, files and forks with some more queries.
+	s.Tags, err = m.tags(s.ID)
+	if err != nil {
+		return Snippet{}, err
+	}
+
+	s.Files, err = selectFiles(m.DB, s.ID)
+	if err != nil {
+		return Snippet{}, err
+	}
+
+	if s.ParentID != 0 {
+		s.Parent, err = m.ref(s.ParentID)
+		if err != nil {
+			return Snippet{}, err
+		}
+	}
+
+	s.Forks, err = m.forkCount(s.ID)
+	if err != nil {
+		return Snippet{}, err
+	}
+
 	return s, nil
 }
 
-// This will return the 10 most recently created snippets
+// This will return the short ID of the snippet with the given integer ID, so
+// that old numeric snippet URLs can be redirected to their new form. Only
+// public snippets which already existed when short IDs were introduced (and
+// so are marked as legacy) are looked up. The id column is still
+// AUTO_INCREMENT, so otherwise anyone could count up from 1 to find every
+// snippet's short ID, unlisted ones included.
+func (m *SnippetModel) LegacyShortID(id int) (string, error) {
+	var shortID string
+
+	stmt := `SELECT short_id FROM snippets WHERE ` + notExpired + ` AND id = ?
+	AND legacy = TRUE AND visibility = 'public'`
+
+	err := m.DB.QueryRow(stmt, id).Scan(&shortID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrNoRecord
+		} else {
+			return "", err
+		}
+	}
+
+	return shortID, nil
+}
+
+// This will return the 10 most recently created public snippets. Unlisted
+// and private snippets are never listed.
 func (m *SnippetModel) Latest() ([]Snippet, error) {
-	// write the SQL statement we want to execute.
-	stmt := `SELECT id, title, content, created, expires FROM snippets 
-	WHERE expires > UTC_TIMESTAMP() ORDER BY id DESC LIMIT 10`
+	list, err := m.List(SnippetFilter{Visibility: VisibilityPublic}, SnippetPage{Limit: 10})
+	if err != nil {
+		return nil, err
+	}
+
+	return list.Snippets, nil
+}
+
+// This will return one page of the unexpired snippets matching the filter,
+// in the order given by page.Sort. Pages are found by their position
+// relative to a cursor (keyset pagination) rather than with an OFFSET, so
+// that later pages are as quick to fetch as the first one.
+func (m *SnippetModel) List(filter SnippetFilter, page SnippetPage) (SnippetList, error) {
+	var conditions []string
+	var args []any
+
+	conditions = append(conditions, notExpired)
 
-	rows, err := m.DB.Query(stmt)
+	if filter.Visibility != "" {
+		conditions = append(conditions, "snippets.visibility = ?")
+		args = append(args, filter.Visibility)
+	}
+
+	if filter.Tag != "" {
+		conditions = append(conditions, `EXISTS (SELECT 1 FROM snippet_tags
+		INNER JOIN tags ON tags.id = snippet_tags.tag_id
+		WHERE snippet_tags.snippet_id = snippets.id AND tags.name = ?)`)
+		args = append(args, filter.Tag)
+	}
+
+	// Work out which way to walk the (created, id) index. Fetching the page
+	// before the cursor means walking it backwards and reversing the rows
+	// afterwards.
+	descending := page.Sort != SortOldest
+	if page.Cursor.Backward {
+		descending = !descending
+	}
+
+	comparison, order := ">", "ASC"
+	if descending {
+		comparison, order = "<", "DESC"
+	}
+
+	if !page.Cursor.IsZero() {
+		conditions = append(conditions, fmt.Sprintf(
+			"(snippets.created %[1]s ? OR (snippets.created = ? AND snippets.id %[1]s ?))", comparison))
+		args = append(args, page.Cursor.Created, page.Cursor.Created, page.Cursor.ID)
+	}
+
+	// Ask for one more row than we need, so we know whether there's another
+	// page after this one.
+	stmt := snippetSelect + `
+	WHERE ` + strings.Join(conditions, " AND ") + fmt.Sprintf(`
+	ORDER BY snippets.created %[1]s, snippets.id %[1]s LIMIT ?`, order)
+	args = append(args, page.Limit+1)
+
+	snippets, err := querySnippets(m.DB, stmt, args...)
+	if err != nil {
+		return SnippetList{}, err
+	}
+
+	more := len(snippets) > page.Limit
+	if more {
+		snippets = snippets[:page.Limit]
+	}
+
+	if page.Cursor.Backward {
+		slices.Reverse(snippets)
+	}
+
+	return newSnippetList(snippets, page.Cursor, more), nil
+}
+
+// This will return all unexpired snippets owned by a specific user, newest
+// first.
+func (m *SnippetModel) ByUser(userID int) ([]Snippet, error) {
+	stmt := snippetSelect + `
+	WHERE ` + notExpired + ` AND snippets.user_id = ? ORDER BY snippets.id DESC`
+
+	return querySnippets(m.DB, stmt, userID)
+}
+
+// This will update the title, content, expiry and visibility of an existing
+// snippet. The edit is recorded as a new revision in the same transaction. If
+// the snippet no longer exists (because it expired or was burnt since the
+// handler read it), ErrNoRecord is returned.
+func (m *SnippetModel) Update(id int, input SnippetInput) error {
+	passphraseHash, err := input.passphraseHash()
+	if err != nil {
+		return err
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	// Lock the snippet, checking that it's still there. The UPDATE's
+	// RowsAffected can't be used for this, as MySQL only counts the rows which
+	// actually changed, and an edit may leave the snippets row as it was (if
+	// only the tags were changed, say).
+	stmt := `SELECT id FROM snippets WHERE ` + notExpired + ` AND snippets.id = ? FOR UPDATE`
+
+	err = tx.QueryRow(stmt, id).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoRecord
+		} else {
+			return err
+		}
+	}
+
+	stmt = `UPDATE snippets SET title = ?, content = ?, expires = ?, visibility = ?, burn_after_reading = ?
+	WHERE id = ?`
+
+	_, err = tx.Exec(stmt, input.Title, joinFiles(input.Files), input.expires(), input.Visibility,
+		input.BurnAfterReading, id)
+	if err != nil {
+		return err
+	}
+
+	// Only touch the passphrase if a new one was given or the owner asked for
+	// it to be removed. Changing it also clears any failed unlock attempts,
+	// and bumps its version so that sessions which unlocked the snippet with
+	// the old passphrase have to enter the new one.
+	if passphraseHash != nil || input.RemovePassphrase {
+		stmt = `UPDATE snippets SET passphrase_hash = ?, passphrase_version = passphrase_version + 1,
+		unlock_failures = 0, unlock_locked_until = NULL WHERE id = ?`
+
+		_, err = tx.Exec(stmt, passphraseHash, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = setFiles(tx, id, input.Files)
+	if err != nil {
+		return err
+	}
+
+	err = insertRevision(tx, id)
+	if err != nil {
+		return err
+	}
+
+	err = setTags(tx, id, input.Tags)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// This will delete a specific snippet based on its id. If no snippet with
+// that id exists, ErrNoRecord is returned.
+func (m *SnippetModel) Delete(id int) error {
+	stmt := `DELETE FROM snippets WHERE id = ?`
+
+	result, err := m.DB.Exec(stmt, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+// This will atomically read and delete a burn-after-reading snippet. The row
+// is locked with SELECT ... FOR UPDATE before it is deleted, so if two
+// viewers try to burn the same snippet concurrently only one of them gets it
+// back; the other receives ErrNoRecord.
+func (m *SnippetModel) Burn(id int) (Snippet, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return Snippet{}, err
+	}
+
+	defer tx.Rollback()
+
+	stmt := snippetSelect + `
+	WHERE ` + notExpired + ` AND snippets.id = ? AND snippets.burn_after_reading = TRUE
+	FOR UPDATE`
+
+	s, err := scanSnippet(tx.QueryRow(stmt, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Snippet{}, ErrNoRecord
+		} else {
+			return Snippet{}, err
+		}
+	}
+
+	// The files are deleted along with the snippet, so read them first.
+	s.Files, err = selectFiles(tx, id)
+	if err != nil {
+		return Snippet{}, err
+	}
+
+	_, err = tx.Exec(`DELETE FROM snippets WHERE id = ?`, id)
+	if err != nil {
+		return Snippet{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return Snippet{}, err
+	}
+
+	return s, nil
+}
+
+// This will check a passphrase against a protected snippet, in the same way
+// that UserModel.Authenticate() checks passwords. Wrong passphrases are
+// counted per snippet, and after maxUnlockAttempts failures in a row the
+// snippet is locked for unlockLockout, during which ErrTooManyAttempts is
+// returned without checking the passphrase at all.
+func (m *SnippetModel) Unlock(id int, passphrase string) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	var passphraseHash []byte
+	var failures int
+	var locked bool
+
+	// Lock the row so that concurrent attempts are counted correctly.
+	stmt := `SELECT passphrase_hash, unlock_failures,
+	COALESCE(unlock_locked_until > UTC_TIMESTAMP(), FALSE)
+	FROM snippets WHERE ` + notExpired + ` AND id = ? FOR UPDATE`
+
+	err = tx.QueryRow(stmt, id).Scan(&passphraseHash, &failures, &locked)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoRecord
+		} else {
+			return err
+		}
+	}
+
+	// A snippet without a passphrase is always unlocked.
+	if passphraseHash == nil {
+		return nil
+	}
+
+	if locked {
+		return ErrTooManyAttempts
+	}
+
+	err = bcrypt.CompareHashAndPassword(passphraseHash, []byte(passphrase))
+	if err != nil {
+		if !errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return err
+		}
+
+		// Record the failure, locking the snippet once the limit is reached.
+		failures++
+		if failures >= maxUnlockAttempts {
+			stmt = `UPDATE snippets SET unlock_failures = 0,
+			unlock_locked_until = DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? SECOND) WHERE id = ?`
+			_, err = tx.Exec(stmt, int(unlockLockout.Seconds()), id)
+		} else {
+			stmt = `UPDATE snippets SET unlock_failures = ? WHERE id = ?`
+			_, err = tx.Exec(stmt, failures, id)
+		}
+		if err != nil {
+			return err
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			return err
+		}
+
+		return ErrInvalidCredentials
+	}
+
+	// The passphrase is correct, so reset the failure count.
+	stmt = `UPDATE snippets SET unlock_failures = 0, unlock_locked_until = NULL WHERE id = ?`
+
+	_, err = tx.Exec(stmt, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// This will permanently delete up to limit expired snippets, along with
+// their revisions, and return them (without their files or tags) so that
+// their owners can be told. Deleting in bounded batches keeps each
+// transaction (and the locks it holds) short, so callers should keep
+// calling it until fewer than limit snippets are returned. The snippets are
+// locked with SELECT ... FOR UPDATE, in the same way as Burn(), so that only
+// the snippets returned are deleted.
+func (m *SnippetModel) DeleteExpired(limit int) ([]Snippet, error) {
+	tx, err := m.DB.Begin()
 	if err != nil {
 		return nil, err
 	}
 
-	// We defer rows.Close() to ensure the sql.Rows resultset is
-	// always properly closed before the Latest() method returns. This defer
-	// statement should come *after* you check for an error from the Query()
-	// method. Otherwise, if Query() returns an error, you'll get a panic
-	// trying to close a nil resultset.
-	defer rows.Close()
+	defer tx.Rollback()
 
-	var snippets []Snippet
+	stmt := snippetSelect + `
+	WHERE snippets.expires <= UTC_TIMESTAMP() ORDER BY snippets.id LIMIT ? FOR UPDATE`
 
-	// Use rows.Next to iterate through the rows in the resultset. This
-	// prepares the first (and then each subsequent) row to be acted on by the
-	// rows.Scan() method. If iteration over all the rows completes then the
-	// resultset automatically closes itself and frees-up the underlying
-	// database connection.
-	for rows.Next() {
-		// Create a pointer to a new zeroed Snippet struct.
-		var s Snippet
-		// Use rows.Scan() to copy the values from each field in the row to the
-		// new Snippet object that we created. Again, the arguments to row.Scan()
-		// must be pointers to the place you want to copy the data into, and the
-		// number of arguments must be exactly the same as the number of
-		// columns returned by your statement.
-		err = rows.Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires)
+	snippets, err := querySnippets(tx, stmt, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range snippets {
+		_, err = tx.Exec(`DELETE FROM snippets WHERE id = ?`, s.ID)
 		if err != nil {
 			return nil, err
 		}
-		// Append it to the slice of snippets.
-		snippets = append(snippets, s)
 	}
 
-	// When the rows.Next() loop has finished we call rows.Err() to retrieve any
-	// error that was encountered during the iteration. It's important to
-	// call this - don't assume that a successful iteration was completed
-	// over the whole resultset.
-	if err = rows.Err(); err != nil {
+	err = tx.Commit()
+	if err != nil {
 		return nil, err
 	}
 
@@ -0,0 +1,683 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mixnblend/snippetbox/internal/assert"
+)
+
+func TestSnippetModelInsertIntegration(t *testing.T) {
+	integrationTest(t)
+
+	// given ... we have a database with a user record in it
+	db := newTestDB(t)
+	m := SnippetModel{DB: db}
+
+	// when ... we insert a snippet owned by that user
+	input := SnippetInput{
+		Title:      "O snail",
+		Files:      singleFile("O snail\nClimb Mount Fuji,"),
+		Expires:    time.Now().Add(7 * 24 * time.Hour),
+		Visibility: VisibilityUnlisted,
+	}
+	shortID, err := m.Insert(input, 1)
+	assert.NilError(t, err)
+
+	// then ... the snippet should be retrievable by its short ID along with
+	// its author
+	snippet, err := m.Get(shortID)
+	assert.NilError(t, err)
+	assert.Equal(t, len(shortID), ShortIDLength)
+	assert.Equal(t, snippet.ShortID, shortID)
+	assert.Equal(t, snippet.Title, "O snail")
+	assert.Equal(t, snippet.UserID, 1)
+	assert.Equal(t, snippet.UserName, "Alice Jones")
+	assert.Equal(t, snippet.Visibility, VisibilityUnlisted)
+}
+
+func TestSnippetModelExpiryIntegration(t *testing.T) {
+	integrationTest(t)
+
+	// given ... we have a database with a user record in it
+	db := newTestDB(t)
+	m := SnippetModel{DB: db}
+
+	// when ... we insert a snippet which never expires
+	input := SnippetInput{Title: "Forever", Files: singleFile("Forever"), Visibility: VisibilityPublic}
+	neverID, err := m.Insert(input, 1)
+	assert.NilError(t, err)
+
+	// and ... a snippet which has already expired
+	input.Expires = time.Now().Add(-time.Minute)
+	expiredID, err := m.Insert(input, 1)
+	assert.NilError(t, err)
+
+	// then ... the first snippet should be retrievable without an expiry time
+	snippet, err := m.Get(neverID)
+	assert.NilError(t, err)
+	assert.Equal(t, snippet.Expires.IsZero(), true)
+
+	// and ... the expired snippet should be treated as missing
+	_, err = m.Get(expiredID)
+	assert.Equal(t, err, ErrNoRecord)
+}
+
+func TestSnippetModelFilesIntegration(t *testing.T) {
+	integrationTest(t)
+
+	// given ... we have a database with a user record in it
+	db := newTestDB(t)
+	m := SnippetModel{DB: db}
+
+	// when ... we insert a snippet with two files
+	input := SnippetInput{
+		Title: "Compose",
+		Files: []SnippetFile{
+			{Name: "Dockerfile", Language: "docker", Content: "FROM golang"},
+			{Name: "compose.yaml", Content: "services:"},
+		},
+		Expires:    time.Now().Add(time.Hour),
+		Visibility: VisibilityPublic,
+	}
+	shortID, err := m.Insert(input, 1)
+	assert.NilError(t, err)
+
+	// then ... the files should be retrievable in order
+	snippet, err := m.Get(shortID)
+	assert.NilError(t, err)
+	assert.Equal(t, len(snippet.Files), 2)
+	assert.Equal(t, snippet.Files[0], input.Files[0])
+	assert.Equal(t, snippet.Files[1], input.Files[1])
+
+	// and ... the content should hold both files, headed by their names
+	assert.Equal(t, snippet.Content, "==> Dockerfile <==\nFROM golang\n\n==> compose.yaml <==\nservices:")
+
+	// when ... we update it to a single file
+	input.Files = []SnippetFile{{Name: "compose.yaml", Language: "yaml", Content: "services: {}"}}
+	err = m.Update(snippet.ID, input)
+	assert.NilError(t, err)
+
+	// then ... the files should be replaced, and the content should be that
+	// of the file alone
+	snippet, err = m.Get(shortID)
+	assert.NilError(t, err)
+	assert.Equal(t, len(snippet.Files), 1)
+	assert.Equal(t, snippet.Files[0], input.Files[0])
+	assert.Equal(t, snippet.Content, "services: {}")
+}
+
+func TestSnippetModelForkIntegration(t *testing.T) {
+	integrationTest(t)
+
+	// given ... we have a database with "An old silent pond" (ID 1) in it
+	db := newTestDB(t)
+	m := SnippetModel{DB: db}
+
+	// when ... we insert a fork of it
+	input := SnippetInput{Title: "A new silent pond", Files: singleFile("A new silent pond..."), Visibility: VisibilityPublic, ParentID: 1}
+	shortID, err := m.Insert(input, 1)
+	assert.NilError(t, err)
+
+	// then ... the fork should refer back to the original
+	fork, err := m.Get(shortID)
+	assert.NilError(t, err)
+	assert.Equal(t, fork.ParentID, 1)
+	assert.Equal(t, fork.Parent.ShortID, "silentPond")
+	assert.Equal(t, fork.Forks, 0)
+
+	// and ... the original should count the fork
+	original, err := m.Get("silentPond")
+	assert.NilError(t, err)
+	assert.Equal(t, original.Parent == nil, true)
+	assert.Equal(t, original.Forks, 1)
+
+	// when ... the original is deleted
+	err = m.Delete(1)
+	assert.NilError(t, err)
+
+	// then ... the fork should remain, without a parent
+	fork, err = m.Get(shortID)
+	assert.NilError(t, err)
+	assert.Equal(t, fork.ParentID, 0)
+	assert.Equal(t, fork.Parent == nil, true)
+}
+
+func TestSnippetModelDeleteExpiredIntegration(t *testing.T) {
+	integrationTest(t)
+
+	// given ... we have a database with an unexpired snippet in it
+	db := newTestDB(t)
+	m := SnippetModel{DB: db}
+
+	// and ... we have added three expired snippets and one which never expires
+	input := SnippetInput{Title: "Expired", Files: singleFile("Expired"), Visibility: VisibilityPublic}
+	input.Expires = time.Now().Add(-time.Hour)
+	for range 3 {
+		_, err := m.Insert(input, 1)
+		assert.NilError(t, err)
+	}
+
+	input.Expires = time.Time{}
+	neverID, err := m.Insert(input, 1)
+	assert.NilError(t, err)
+
+	// when ... we delete expired snippets in batches of two
+	// then ... two and then one should be deleted and returned
+	deleted, err := m.DeleteExpired(2)
+	assert.NilError(t, err)
+	assert.Equal(t, len(deleted), 2)
+	assert.Equal(t, deleted[0].Title, "Expired")
+	assert.Equal(t, deleted[0].UserID, 1)
+
+	deleted, err = m.DeleteExpired(2)
+	assert.NilError(t, err)
+	assert.Equal(t, len(deleted), 1)
+
+	deleted, err = m.DeleteExpired(2)
+	assert.NilError(t, err)
+	assert.Equal(t, len(deleted), 0)
+
+	// and ... the unexpired snippets should be left alone
+	_, err = m.Get("silentPond")
+	assert.NilError(t, err)
+
+	_, err = m.Get(neverID)
+	assert.NilError(t, err)
+}
+
+func TestSnippetModelListIntegration(t *testing.T) {
+	integrationTest(t)
+
+	// given ... we have a database with a public snippet in it
+	db := newTestDB(t)
+	m := SnippetModel{DB: db}
+
+	// and ... we have added four more public snippets and a private one. Most
+	// of these will share the same creation time, so the listing has to fall
+	// back on their IDs to order them.
+	input := SnippetInput{Visibility: VisibilityPublic}
+	for _, title := range []string{"Two", "Three", "Four", "Five"} {
+		input.Title, input.Files = title, singleFile(title)
+		_, err := m.Insert(input, 1)
+		assert.NilError(t, err)
+	}
+
+	_, err := m.Insert(SnippetInput{Title: "Private", Files: singleFile("Private"), Visibility: VisibilityPrivate}, 1)
+	assert.NilError(t, err)
+
+	filter := SnippetFilter{Visibility: VisibilityPublic}
+	titles := func(list SnippetList) string {
+		var titles []string
+		for _, s := range list.Snippets {
+			titles = append(titles, s.Title)
+		}
+		return strings.Join(titles, ",")
+	}
+
+	// when ... we page forwards through the public snippets, newest first
+	// then ... each page should follow on from the last
+	first, err := m.List(filter, SnippetPage{Limit: 2})
+	assert.NilError(t, err)
+	assert.Equal(t, titles(first), "Five,Four")
+	assert.Equal(t, first.Prev == nil, true)
+
+	second, err := m.List(filter, SnippetPage{Limit: 2, Cursor: *first.Next})
+	assert.NilError(t, err)
+	assert.Equal(t, titles(second), "Three,Two")
+
+	third, err := m.List(filter, SnippetPage{Limit: 2, Cursor: *second.Next})
+	assert.NilError(t, err)
+	assert.Equal(t, titles(third), "An old silent pond")
+	assert.Equal(t, third.Next == nil, true)
+
+	// and ... paging backwards should return the same pages
+	back, err := m.List(filter, SnippetPage{Limit: 2, Cursor: *third.Prev})
+	assert.NilError(t, err)
+	assert.Equal(t, titles(back), "Three,Two")
+
+	back, err = m.List(filter, SnippetPage{Limit: 2, Cursor: *back.Prev})
+	assert.NilError(t, err)
+	assert.Equal(t, titles(back), "Five,Four")
+	assert.Equal(t, back.Prev == nil, true)
+
+	// when ... we list them oldest first
+	// then ... the order should be reversed
+	oldest, err := m.List(filter, SnippetPage{Sort: SortOldest, Limit: 3})
+	assert.NilError(t, err)
+	assert.Equal(t, titles(oldest), "An old silent pond,Two,Three")
+}
+
+// idFor looks up the integer ID of the snippet with the given short ID.
+func idFor(m SnippetModel, shortID string) (int, error) {
+	snippet, err := m.Get(shortID)
+	return snippet.ID, err
+}
+
+func TestSnippetModelLegacyShortIDIntegration(t *testing.T) {
+	integrationTest(t)
+
+	// given ... we have a database with a legacy public snippet in it
+	db := newTestDB(t)
+	m := SnippetModel{DB: db}
+
+	// and ... we have added a public snippet since short IDs were introduced
+	input := SnippetInput{Title: "New", Files: singleFile("New"), Visibility: VisibilityPublic}
+	shortID, err := m.Insert(input, 1)
+	assert.NilError(t, err)
+
+	newID, err := idFor(m, shortID)
+	assert.NilError(t, err)
+
+	// and ... a legacy snippet which has been made unlisted
+	input = SnippetInput{Title: "Unlisted", Files: singleFile("Unlisted"), Visibility: VisibilityUnlisted}
+	shortID, err = m.Insert(input, 1)
+	assert.NilError(t, err)
+
+	unlistedID, err := idFor(m, shortID)
+	assert.NilError(t, err)
+
+	_, err = db.Exec(`UPDATE snippets SET legacy = TRUE WHERE id = ?`, unlistedID)
+	assert.NilError(t, err)
+
+	testCases := []struct {
+		name      string
+		snippetID int
+		want      string
+		wantErr   error
+	}{
+		{
+			name:      "Legacy snippet",
+			snippetID: 1,
+			want:      "silentPond",
+		},
+		{
+			name:      "New snippet",
+			snippetID: newID,
+			wantErr:   ErrNoRecord,
+		},
+		{
+			name:      "Unlisted legacy snippet",
+			snippetID: unlistedID,
+			wantErr:   ErrNoRecord,
+		},
+		{
+			name:      "Non-existent snippet",
+			snippetID: 1000,
+			wantErr:   ErrNoRecord,
+		},
+	}
+
+	for _, tableTest := range testCases {
+		t.Run(tableTest.name, func(t *testing.T) {
+			// when ... we look up the short ID for a numeric ID
+			result, err := m.LegacyShortID(tableTest.snippetID)
+			// then ... the short ID should be returned as expected
+			assert.Equal(t, result, tableTest.want)
+			assert.Equal(t, err, tableTest.wantErr)
+		})
+	}
+}
+
+func TestSnippetModelByUserIntegration(t *testing.T) {
+	integrationTest(t)
+
+	testCases := []struct {
+		name   string
+		userID int
+		want   int
+	}{
+		{
+			name:   "Owner",
+			userID: 1,
+			want:   1,
+		},
+		{
+			name:   "Non-existent user",
+			userID: 2,
+			want:   0,
+		},
+	}
+
+	for _, tableTest := range testCases {
+		t.Run(tableTest.name, func(t *testing.T) {
+			// given ... we have a database with a snippet owned by user 1
+			db := newTestDB(t)
+			m := SnippetModel{DB: db}
+			// when ... we fetch the snippets owned by a user
+			snippets, err := m.ByUser(tableTest.userID)
+			// then ... only that user's snippets should be returned
+			assert.NilError(t, err)
+			assert.Equal(t, len(snippets), tableTest.want)
+		})
+	}
+}
+
+func TestSnippetModelDeleteIntegration(t *testing.T) {
+	integrationTest(t)
+
+	testCases := []struct {
+		name      string
+		snippetID int
+		wantErr   error
+	}{
+		{
+			name:      "Existing snippet",
+			snippetID: 1,
+			wantErr:   nil,
+		},
+		{
+			name:      "Non-existent snippet",
+			snippetID: 2,
+			wantErr:   ErrNoRecord,
+		},
+	}
+
+	for _, tableTest := range testCases {
+		t.Run(tableTest.name, func(t *testing.T) {
+			// given ... we have a database with a snippet in it
+			db := newTestDB(t)
+			m := SnippetModel{DB: db}
+			// when ... we delete a snippet
+			err := m.Delete(tableTest.snippetID)
+			// then ... the error should be returned as expected
+			assert.Equal(t, err, tableTest.wantErr)
+		})
+	}
+}
+
+func TestSnippetModelInsertLargeIntegration(t *testing.T) {
+	integrationTest(t)
+
+	// given ... we have a database
+	db := newTestDB(t)
+	m := SnippetModel{DB: db}
+
+	// when ... we insert a snippet whose files add up to more than a TEXT
+	// column can hold
+	content := strings.Repeat("An old silent pond...\n", 4000)
+	files := []SnippetFile{{Name: "one.txt", Content: content}, {Name: "two.txt", Content: content}}
+	shortID, err := m.Insert(SnippetInput{Title: "Large", Files: files, Visibility: VisibilityPublic}, 1)
+	assert.NilError(t, err)
+
+	// then ... all of the content should be kept
+	snippet, err := m.Get(shortID)
+	assert.NilError(t, err)
+	assert.Equal(t, len(snippet.Files), 2)
+	assert.Equal(t, snippet.Files[1].Content, content)
+	assert.Equal(t, len(snippet.Content) > 2*len(content), true)
+}
+
+func TestSnippetModelUpdateGoneIntegration(t *testing.T) {
+	integrationTest(t)
+
+	// given ... we have a database with a snippet which has expired
+	db := newTestDB(t)
+	m := SnippetModel{DB: db}
+
+	input := SnippetInput{Title: "Expired", Files: singleFile("Expired"), Visibility: VisibilityPublic}
+	shortID, err := m.Insert(input, 1)
+	assert.NilError(t, err)
+
+	expiredID, err := idFor(m, shortID)
+	assert.NilError(t, err)
+
+	_, err = db.Exec(`UPDATE snippets SET expires = DATE_SUB(UTC_TIMESTAMP(), INTERVAL 1 HOUR) WHERE id = ?`, expiredID)
+	assert.NilError(t, err)
+
+	testCases := []struct {
+		name      string
+		snippetID int
+	}{
+		{name: "Expired snippet", snippetID: expiredID},
+		{name: "Deleted snippet", snippetID: 1000},
+	}
+
+	for _, tableTest := range testCases {
+		t.Run(tableTest.name, func(t *testing.T) {
+			// when ... we update a snippet which is no longer there
+			input := SnippetInput{Title: "Edited", Files: singleFile("Edited"), Visibility: VisibilityPublic}
+			err := m.Update(tableTest.snippetID, input)
+
+			// then ... ErrNoRecord should be returned
+			assert.Equal(t, err, ErrNoRecord)
+		})
+	}
+
+	// and ... no revision should have been recorded for the expired snippet
+	revisions, err := m.Revisions(expiredID)
+	assert.NilError(t, err)
+	assert.Equal(t, len(revisions), 1)
+}
+
+func TestSnippetModelUnchangedUpdateIntegration(t *testing.T) {
+	integrationTest(t)
+
+	// given ... we have a database with a snippet in it
+	db := newTestDB(t)
+	m := SnippetModel{DB: db}
+
+	input := SnippetInput{Title: "O snail", Files: singleFile("O snail"), Visibility: VisibilityPublic}
+	shortID, err := m.Insert(input, 1)
+	assert.NilError(t, err)
+
+	id, err := idFor(m, shortID)
+	assert.NilError(t, err)
+
+	// when ... we save it again without changing anything but its tags
+	input.Tags = []string{"haiku"}
+	err = m.Update(id, input)
+
+	// then ... the update should still succeed
+	assert.NilError(t, err)
+
+	snippet, err := m.Get(shortID)
+	assert.NilError(t, err)
+	assert.Equal(t, len(snippet.Tags), 1)
+}
+
+func TestSnippetModelRevisionsIntegration(t *testing.T) {
+	integrationTest(t)
+
+	// given ... we have a database with a snippet in it
+	db := newTestDB(t)
+	m := SnippetModel{DB: db}
+
+	// when ... we create a snippet and then edit it
+	input := SnippetInput{Title: "O snail", Files: singleFile("O snail"), Expires: time.Now().Add(7 * 24 * time.Hour), Visibility: VisibilityPublic}
+	shortID, err := m.Insert(input, 1)
+	assert.NilError(t, err)
+
+	id, err := idFor(m, shortID)
+	assert.NilError(t, err)
+
+	input.Files = singleFile("O snail\nClimb Mount Fuji,")
+	err = m.Update(id, input)
+	assert.NilError(t, err)
+
+	// then ... both versions should be recorded, newest first
+	revisions, err := m.Revisions(id)
+	assert.NilError(t, err)
+	assert.Equal(t, len(revisions), 2)
+	assert.Equal(t, revisions[0].Version, 2)
+	assert.Equal(t, revisions[0].Content, "O snail\nClimb Mount Fuji,")
+	assert.Equal(t, revisions[1].Version, 1)
+	assert.Equal(t, revisions[1].UserName, "Alice Jones")
+
+	// and ... a specific version should be retrievable
+	rev, err := m.Revision(id, 1)
+	assert.NilError(t, err)
+	assert.Equal(t, rev.Content, "O snail")
+
+	_, err = m.Revision(id, 3)
+	assert.Equal(t, err, ErrNoRecord)
+}
+
+func TestSnippetModelLatestIntegration(t *testing.T) {
+	integrationTest(t)
+
+	// given ... we have a database with a public snippet in it
+	db := newTestDB(t)
+	m := SnippetModel{DB: db}
+
+	// and ... we have added an unlisted and a private snippet
+	for _, visibility := range []string{VisibilityUnlisted, VisibilityPrivate} {
+		input := SnippetInput{Title: visibility, Files: singleFile(visibility), Expires: time.Now().Add(7 * 24 * time.Hour), Visibility: visibility}
+		_, err := m.Insert(input, 1)
+		assert.NilError(t, err)
+	}
+
+	// when ... we fetch the latest snippets
+	snippets, err := m.Latest()
+
+	// then ... only the public snippet should be listed
+	assert.NilError(t, err)
+	assert.Equal(t, len(snippets), 1)
+	assert.Equal(t, snippets[0].Visibility, VisibilityPublic)
+}
+
+func TestSnippetModelBurnIntegration(t *testing.T) {
+	integrationTest(t)
+
+	// given ... we have a database with a burn-after-reading snippet in it
+	db := newTestDB(t)
+	m := SnippetModel{DB: db}
+
+	input := SnippetInput{
+		Title:            "Token",
+		Files:            singleFile("s3cr3t"),
+		Expires:          time.Now().Add(24 * time.Hour),
+		Visibility:       VisibilityUnlisted,
+		BurnAfterReading: true,
+	}
+	shortID, err := m.Insert(input, 1)
+	assert.NilError(t, err)
+
+	id, err := idFor(m, shortID)
+	assert.NilError(t, err)
+
+	// when ... we burn the snippet
+	snippet, err := m.Burn(id)
+
+	// then ... its content and files should be returned
+	assert.NilError(t, err)
+	assert.Equal(t, snippet.Content, "s3cr3t")
+	assert.Equal(t, len(snippet.Files), 1)
+
+	// and ... it should be gone afterwards
+	_, err = m.Get(shortID)
+	assert.Equal(t, err, ErrNoRecord)
+
+	_, err = m.Burn(id)
+	assert.Equal(t, err, ErrNoRecord)
+
+	// and ... snippets without burn-after-reading can't be burned
+	_, err = m.Burn(1)
+	assert.Equal(t, err, ErrNoRecord)
+}
+
+func TestSnippetModelUnlockIntegration(t *testing.T) {
+	integrationTest(t)
+
+	// given ... we have a database with a passphrase protected snippet in it
+	db := newTestDB(t)
+	m := SnippetModel{DB: db}
+
+	input := SnippetInput{
+		Title:      "Contractor notes",
+		Files:      singleFile("s3cr3t"),
+		Expires:    time.Now().Add(24 * time.Hour),
+		Visibility: VisibilityUnlisted,
+		Passphrase: "open sesame",
+	}
+	shortID, err := m.Insert(input, 1)
+	assert.NilError(t, err)
+
+	snippet, err := m.Get(shortID)
+	assert.NilError(t, err)
+	id := snippet.ID
+	assert.Equal(t, snippet.Protected, true)
+
+	// when ... we unlock it with the right passphrase
+	// then ... it should succeed
+	assert.NilError(t, m.Unlock(id, "open sesame"))
+
+	// when ... we keep trying the wrong passphrase
+	for i := 0; i < maxUnlockAttempts; i++ {
+		assert.Equal(t, m.Unlock(id, "wrong"), ErrInvalidCredentials)
+	}
+
+	// then ... the snippet should be locked, even for the right passphrase
+	assert.Equal(t, m.Unlock(id, "open sesame"), ErrTooManyAttempts)
+
+	// and ... snippets without a passphrase are always unlocked
+	assert.NilError(t, m.Unlock(1, "anything"))
+}
+
+func TestSnippetModelPassphraseVersionIntegration(t *testing.T) {
+	integrationTest(t)
+
+	// given ... we have a database with a passphrase protected snippet in it
+	db := newTestDB(t)
+	m := SnippetModel{DB: db}
+
+	input := SnippetInput{Title: "Contractor notes", Files: singleFile("s3cr3t"), Visibility: VisibilityUnlisted, Passphrase: "open sesame"}
+	shortID, err := m.Insert(input, 1)
+	assert.NilError(t, err)
+
+	snippet, err := m.Get(shortID)
+	assert.NilError(t, err)
+	assert.Equal(t, snippet.PassphraseVersion, 1)
+
+	// when ... it's edited without touching the passphrase
+	input.Passphrase = ""
+	input.Title = "Contractor onboarding"
+	assert.NilError(t, m.Update(snippet.ID, input))
+
+	// then ... the passphrase version should stay the same
+	snippet, err = m.Get(shortID)
+	assert.NilError(t, err)
+	assert.Equal(t, snippet.PassphraseVersion, 1)
+
+	// when ... the passphrase is changed
+	input.Passphrase = "open barley"
+	assert.NilError(t, m.Update(snippet.ID, input))
+
+	// then ... the version should go up
+	snippet, err = m.Get(shortID)
+	assert.NilError(t, err)
+	assert.Equal(t, snippet.PassphraseVersion, 2)
+
+	// when ... the passphrase is removed
+	input.Passphrase = ""
+	input.RemovePassphrase = true
+	assert.NilError(t, m.Update(snippet.ID, input))
+
+	// then ... the version should go up again, so that adding one back later
+	// doesn't match old sessions
+	snippet, err = m.Get(shortID)
+	assert.NilError(t, err)
+	assert.Equal(t, snippet.Protected, false)
+	assert.Equal(t, snippet.PassphraseVersion, 3)
+}
+
+func TestNewShortID(t *testing.T) {
+	seen := make(map[string]bool)
+
+	for range 100 {
+		// When ... we generate a short ID
+		shortID, err := newShortID()
+		assert.NilError(t, err)
+
+		// Then ... it should be made of ShortIDLength base62 characters
+		assert.Equal(t, len(shortID), ShortIDLength)
+		for _, c := range shortID {
+			assert.Equal(t, strings.ContainsRune(shortIDAlphabet, c), true)
+		}
+
+		// And ... it shouldn't repeat
+		assert.Equal(t, seen[shortID], false)
+		seen[shortID] = true
+	}
+}
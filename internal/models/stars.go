@@ -0,0 +1,73 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+type StarModelInterface interface {
+	Star(userID, snippetID int) error
+	Unstar(userID, snippetID int) error
+	Starred(userID, snippetID int) (bool, error)
+	ByUser(userID int) ([]Snippet, error)
+	MostStarred(since time.Time, limit int) ([]Snippet, error)
+}
+
+// Define a StarModel type which wraps a sql.DB connection pool. Users star
+// snippets to bookmark them; the number of stars on each snippet is counted
+// by snippetSelect.
+type StarModel struct {
+	DB *sql.DB
+}
+
+// This will star a snippet for a user. Starring a snippet which the user has
+// already starred does nothing, thanks to the primary key on the stars table.
+func (m *StarModel) Star(userID, snippetID int) error {
+	stmt := `INSERT IGNORE INTO stars (user_id, snippet_id, created) VALUES (?, ?, UTC_TIMESTAMP())`
+
+	_, err := m.DB.Exec(stmt, userID, snippetID)
+	return err
+}
+
+// This will remove a user's star from a snippet, if they had starred it.
+func (m *StarModel) Unstar(userID, snippetID int) error {
+	stmt := `DELETE FROM stars WHERE user_id = ? AND snippet_id = ?`
+
+	_, err := m.DB.Exec(stmt, userID, snippetID)
+	return err
+}
+
+// This will return true if the user has starred the snippet.
+func (m *StarModel) Starred(userID, snippetID int) (bool, error) {
+	var starred bool
+
+	stmt := `SELECT EXISTS(SELECT true FROM stars WHERE user_id = ? AND snippet_id = ?)`
+
+	err := m.DB.QueryRow(stmt, userID, snippetID).Scan(&starred)
+	return starred, err
+}
+
+// This will return the unexpired snippets starred by a user, most recently
+// starred first. Snippets which have been made private by their owner since
+// they were starred are left out.
+func (m *StarModel) ByUser(userID int) ([]Snippet, error) {
+	stmt := snippetSelect + `
+	INNER JOIN stars ON stars.snippet_id = snippets.id
+	WHERE ` + notExpired + ` AND stars.user_id = ?
+	AND (snippets.visibility <> ? OR snippets.user_id = ?)
+	ORDER BY stars.created DESC, snippets.id DESC`
+
+	return querySnippets(m.DB, stmt, userID, VisibilityPrivate, userID)
+}
+
+// This will return up to limit public snippets which have been starred the
+// most times since the given time, most starred first.
+func (m *StarModel) MostStarred(since time.Time, limit int) ([]Snippet, error) {
+	stmt := snippetSelect + `
+	INNER JOIN (SELECT snippet_id, COUNT(*) AS recent FROM stars WHERE created >= ? GROUP BY snippet_id) AS recent_stars
+	ON recent_stars.snippet_id = snippets.id
+	WHERE ` + notExpired + ` AND snippets.visibility = ?
+	ORDER BY recent_stars.recent DESC, snippets.created DESC, snippets.id DESC LIMIT ?`
+
+	return querySnippets(m.DB, stmt, since.UTC(), VisibilityPublic, limit)
+}
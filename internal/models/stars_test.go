@@ -0,0 +1,102 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mixnblend/snippetbox/internal/assert"
+)
+
+func TestStarModelIntegration(t *testing.T) {
+	integrationTest(t)
+
+	// given ... we have a database with "An old silent pond" (ID 1) and its
+	// author Alice (ID 1) in it
+	db := newTestDB(t)
+	snippets := SnippetModel{DB: db}
+	users := UserModel{DB: db}
+	m := StarModel{DB: db}
+
+	// and ... another user, Bob (ID 2)
+	err := users.Insert("Bob", "bob@example.com", "pa$$word")
+	assert.NilError(t, err)
+
+	// and ... another public snippet (ID 2) and a private one of Alice's (ID 3)
+	_, err = snippets.Insert(SnippetInput{Title: "Two", Files: singleFile("Two"), Visibility: VisibilityPublic}, 1)
+	assert.NilError(t, err)
+	_, err = snippets.Insert(SnippetInput{Title: "Private", Files: singleFile("Private"), Visibility: VisibilityPrivate}, 1)
+	assert.NilError(t, err)
+
+	// when ... both users star the first snippet, Alice twice
+	assert.NilError(t, m.Star(1, 1))
+	assert.NilError(t, m.Star(1, 1))
+	assert.NilError(t, m.Star(2, 1))
+
+	// and ... Alice stars the other two
+	assert.NilError(t, m.Star(1, 2))
+	assert.NilError(t, m.Star(1, 3))
+
+	// then ... the stars should be counted once per user
+	snippet, err := snippets.Get("silentPond")
+	assert.NilError(t, err)
+	assert.Equal(t, snippet.Stars, 2)
+
+	starred, err := m.Starred(2, 1)
+	assert.NilError(t, err)
+	assert.Equal(t, starred, true)
+
+	starred, err = m.Starred(2, 2)
+	assert.NilError(t, err)
+	assert.Equal(t, starred, false)
+
+	// and ... all of Alice's starred snippets should be listed, newest star first
+	starredSnippets, err := m.ByUser(1)
+	assert.NilError(t, err)
+	assert.Equal(t, len(starredSnippets), 3)
+
+	// and ... the most starred public snippets should come first
+	mostStarred, err := m.MostStarred(time.Now().Add(-7*24*time.Hour), 10)
+	assert.NilError(t, err)
+	assert.Equal(t, len(mostStarred), 2)
+	assert.Equal(t, mostStarred[0].Title, "An old silent pond")
+	assert.Equal(t, mostStarred[1].Title, "Two")
+
+	// and ... stars from before the cut-off should be ignored
+	mostStarred, err = m.MostStarred(time.Now().Add(time.Hour), 10)
+	assert.NilError(t, err)
+	assert.Equal(t, len(mostStarred), 0)
+
+	// when ... Bob unstars the first snippet
+	assert.NilError(t, m.Unstar(2, 1))
+
+	// then ... it should only have Alice's star
+	snippet, err = snippets.Get("silentPond")
+	assert.NilError(t, err)
+	assert.Equal(t, snippet.Stars, 1)
+}
+
+func TestStarModelByUserIntegration(t *testing.T) {
+	integrationTest(t)
+
+	// given ... we have a database with Alice's "An old silent pond" in it
+	db := newTestDB(t)
+	snippets := SnippetModel{DB: db}
+	users := UserModel{DB: db}
+	m := StarModel{DB: db}
+
+	// and ... Bob has starred it
+	err := users.Insert("Bob", "bob@example.com", "pa$$word")
+	assert.NilError(t, err)
+	assert.NilError(t, m.Star(2, 1))
+
+	// when ... Alice makes it private
+	snippet, err := snippets.Get("silentPond")
+	assert.NilError(t, err)
+	err = snippets.Update(snippet.ID, SnippetInput{Title: snippet.Title, Files: snippet.Files, Visibility: VisibilityPrivate})
+	assert.NilError(t, err)
+
+	// then ... it should no longer be among Bob's starred snippets
+	starred, err := m.ByUser(2)
+	assert.NilError(t, err)
+	assert.Equal(t, len(starred), 0)
+}
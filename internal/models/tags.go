@@ -0,0 +1,105 @@
+package models
+
+import (
+	"database/sql"
+)
+
+// TagCount holds the name of a tag and the number of public snippets which
+// have it.
+type TagCount struct {
+	Name  string
+	Count int
+}
+
+// setTags replaces the tags of a snippet. Tags which don't exist yet are
+// created. Like insertRevision(), it must be called inside the same
+// transaction as the change to the snippet.
+func setTags(tx *sql.Tx, snippetID int, tags []string) error {
+	_, err := tx.Exec(`DELETE FROM snippet_tags WHERE snippet_id = ?`, snippetID)
+	if err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		// INSERT IGNORE skips tags which already exist, thanks to the
+		// unique constraint on their name.
+		_, err = tx.Exec(`INSERT IGNORE INTO tags (name) VALUES (?)`, tag)
+		if err != nil {
+			return err
+		}
+
+		stmt := `INSERT INTO snippet_tags (snippet_id, tag_id) SELECT ?, id FROM tags WHERE name = ?`
+
+		_, err = tx.Exec(stmt, snippetID, tag)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// This will return the names of the tags on a snippet, in alphabetical order.
+func (m *SnippetModel) tags(snippetID int) ([]string, error) {
+	stmt := `SELECT tags.name FROM tags
+	INNER JOIN snippet_tags ON snippet_tags.tag_id = tags.id
+	WHERE snippet_tags.snippet_id = ? ORDER BY tags.name`
+
+	rows, err := m.DB.Query(stmt, snippetID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var tags []string
+
+	for rows.Next() {
+		var tag string
+		err = rows.Scan(&tag)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// This will return up to limit of the tags used by the most unexpired public
+// snippets, along with how many snippets use each of them, most used first.
+func (m *SnippetModel) PopularTags(limit int) ([]TagCount, error) {
+	stmt := `SELECT tags.name, COUNT(*) FROM tags
+	INNER JOIN snippet_tags ON snippet_tags.tag_id = tags.id
+	INNER JOIN snippets ON snippets.id = snippet_tags.snippet_id
+	WHERE ` + notExpired + ` AND snippets.visibility = ?
+	GROUP BY tags.id, tags.name ORDER BY COUNT(*) DESC, tags.name LIMIT ?`
+
+	rows, err := m.DB.Query(stmt, VisibilityPublic, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var counts []TagCount
+
+	for rows.Next() {
+		var c TagCount
+		err = rows.Scan(&c.Name, &c.Count)
+		if err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
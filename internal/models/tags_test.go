@@ -0,0 +1,60 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mixnblend/snippetbox/internal/assert"
+)
+
+func TestSnippetModelTagsIntegration(t *testing.T) {
+	integrationTest(t)
+
+	// given ... we have a database with a user record in it
+	db := newTestDB(t)
+	m := SnippetModel{DB: db}
+
+	// when ... we insert some tagged snippets
+	input := SnippetInput{Title: "Query", Files: singleFile("SELECT 1"), Visibility: VisibilityPublic, Tags: []string{"sql", "go"}}
+	queryID, err := m.Insert(input, 1)
+	assert.NilError(t, err)
+
+	input = SnippetInput{Title: "Handler", Files: singleFile("func home()"), Visibility: VisibilityPublic, Tags: []string{"go"}}
+	_, err = m.Insert(input, 1)
+	assert.NilError(t, err)
+
+	input = SnippetInput{Title: "Secret", Files: singleFile("s3cr3t"), Visibility: VisibilityPrivate, Tags: []string{"go", "k8s"}}
+	_, err = m.Insert(input, 1)
+	assert.NilError(t, err)
+
+	// then ... a snippet should be returned with its tags in alphabetical order
+	snippet, err := m.Get(queryID)
+	assert.NilError(t, err)
+	assert.Equal(t, strings.Join(snippet.Tags, ","), "go,sql")
+
+	// and ... the public snippets should be listed by tag
+	list, err := m.List(SnippetFilter{Visibility: VisibilityPublic, Tag: "go"}, SnippetPage{Limit: 10})
+	assert.NilError(t, err)
+	assert.Equal(t, len(list.Snippets), 2)
+
+	list, err = m.List(SnippetFilter{Visibility: VisibilityPublic, Tag: "k8s"}, SnippetPage{Limit: 10})
+	assert.NilError(t, err)
+	assert.Equal(t, len(list.Snippets), 0)
+
+	// and ... the popular tags should only count public snippets
+	counts, err := m.PopularTags(10)
+	assert.NilError(t, err)
+	assert.Equal(t, len(counts), 2)
+	assert.Equal(t, counts[0], TagCount{Name: "go", Count: 2})
+	assert.Equal(t, counts[1], TagCount{Name: "sql", Count: 1})
+
+	// when ... we update the tags of a snippet
+	input = SnippetInput{Title: "Query", Files: singleFile("SELECT 1"), Visibility: VisibilityPublic, Tags: []string{"mysql"}}
+	err = m.Update(snippet.ID, input)
+	assert.NilError(t, err)
+
+	// then ... the old tags should be replaced
+	snippet, err = m.Get(queryID)
+	assert.NilError(t, err)
+	assert.Equal(t, strings.Join(snippet.Tags, ","), "mysql")
+}
@@ -1,22 +1,199 @@
+CREATE TABLE sessions (
+    token CHAR(43) PRIMARY KEY,
+    data BLOB NOT NULL,
+    expiry TIMESTAMP(6) NOT NULL
+);
+
+CREATE INDEX sessions_expiry_idx ON sessions (expiry);
+
+CREATE TABLE users (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    hashed_password CHAR(60) NOT NULL,
+    created DATETIME NOT NULL,
+    verified BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+ALTER TABLE users ADD CONSTRAINT users_uc_email UNIQUE (email);
+
 CREATE TABLE snippets (
     id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    short_id CHAR(10) CHARACTER SET ascii COLLATE ascii_bin NOT NULL,
     title VARCHAR(100) NOT NULL,
-    content TEXT NOT NULL,
+    content MEDIUMTEXT NOT NULL,
     created DATETIME NOT NULL,
-    expires DATETIME NOT NULL
+    expires DATETIME NULL,
+    user_id INTEGER NOT NULL,
+    visibility ENUM('public', 'unlisted', 'private') NOT NULL DEFAULT 'public',
+    burn_after_reading BOOLEAN NOT NULL DEFAULT FALSE,
+    passphrase_hash CHAR(60) NULL,
+    passphrase_version INTEGER NOT NULL DEFAULT 1,
+    unlock_failures INTEGER NOT NULL DEFAULT 0,
+    unlock_locked_until DATETIME NULL,
+    parent_id INTEGER NULL,
+    legacy BOOLEAN NOT NULL DEFAULT FALSE
 );
 
 CREATE INDEX idx_snippets_created ON snippets(created);
 
-CREATE TABLE users (
+CREATE INDEX idx_snippets_expires ON snippets(expires);
+
+CREATE FULLTEXT INDEX idx_snippets_fulltext ON snippets(title, content);
+
+ALTER TABLE snippets ADD CONSTRAINT snippets_uc_short_id UNIQUE (short_id);
+
+ALTER TABLE snippets ADD CONSTRAINT snippets_fk_user FOREIGN KEY (user_id) REFERENCES users(id);
+
+ALTER TABLE snippets ADD CONSTRAINT snippets_fk_parent FOREIGN KEY (parent_id) REFERENCES snippets(id) ON DELETE SET NULL;
+
+CREATE TABLE snippet_revisions (
     id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    snippet_id INTEGER NOT NULL,
+    version INTEGER NOT NULL,
+    title VARCHAR(100) NOT NULL,
+    content MEDIUMTEXT NOT NULL,
+    created DATETIME NOT NULL,
+    user_id INTEGER NOT NULL
+);
+
+ALTER TABLE snippet_revisions ADD CONSTRAINT snippet_revisions_uc_version UNIQUE (snippet_id, version);
+ALTER TABLE snippet_revisions ADD CONSTRAINT snippet_revisions_fk_snippet FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE;
+ALTER TABLE snippet_revisions ADD CONSTRAINT snippet_revisions_fk_user FOREIGN KEY (user_id) REFERENCES users(id);
+
+CREATE TABLE snippet_files (
+    snippet_id INTEGER NOT NULL,
+    position INTEGER NOT NULL,
     name VARCHAR(255) NOT NULL,
-    email VARCHAR(255) NOT NULL,
-    hashed_password CHAR(60) NOT NULL,
+    language VARCHAR(32) NOT NULL DEFAULT '',
+    content MEDIUMTEXT NOT NULL,
+    PRIMARY KEY (snippet_id, position)
+);
+
+ALTER TABLE snippet_files ADD CONSTRAINT snippet_files_fk_snippet FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE;
+
+CREATE TABLE tags (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    name VARCHAR(32) NOT NULL
+);
+
+ALTER TABLE tags ADD CONSTRAINT tags_uc_name UNIQUE (name);
+
+CREATE TABLE snippet_tags (
+    snippet_id INTEGER NOT NULL,
+    tag_id INTEGER NOT NULL,
+    PRIMARY KEY (snippet_id, tag_id)
+);
+
+CREATE INDEX idx_snippet_tags_tag ON snippet_tags(tag_id);
+
+ALTER TABLE snippet_tags ADD CONSTRAINT snippet_tags_fk_snippet FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE;
+
+ALTER TABLE snippet_tags ADD CONSTRAINT snippet_tags_fk_tag FOREIGN KEY (tag_id) REFERENCES tags(id);
+
+CREATE TABLE stars (
+    user_id INTEGER NOT NULL,
+    snippet_id INTEGER NOT NULL,
+    created DATETIME NOT NULL,
+    PRIMARY KEY (user_id, snippet_id)
+);
+
+CREATE INDEX idx_stars_snippet_created ON stars(snippet_id, created);
+
+ALTER TABLE stars ADD CONSTRAINT stars_fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+ALTER TABLE stars ADD CONSTRAINT stars_fk_snippet FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE;
+
+CREATE TABLE comments (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    snippet_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    parent_id INTEGER NULL,
+    content TEXT NOT NULL,
+    created DATETIME NOT NULL,
+    updated DATETIME NULL,
+    deleted BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX idx_comments_snippet_created ON comments(snippet_id, created);
+
+ALTER TABLE comments ADD CONSTRAINT comments_fk_snippet FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE;
+
+ALTER TABLE comments ADD CONSTRAINT comments_fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+ALTER TABLE comments ADD CONSTRAINT comments_fk_parent FOREIGN KEY (parent_id) REFERENCES comments(id) ON DELETE CASCADE;
+
+CREATE TABLE collections (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    short_id CHAR(10) CHARACTER SET ascii COLLATE ascii_bin NOT NULL,
+    title VARCHAR(100) NOT NULL,
+    visibility ENUM('public', 'unlisted', 'private') NOT NULL DEFAULT 'public',
+    created DATETIME NOT NULL,
+    user_id INTEGER NOT NULL
+);
+
+ALTER TABLE collections ADD CONSTRAINT collections_uc_short_id UNIQUE (short_id);
+
+ALTER TABLE collections ADD CONSTRAINT collections_fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+CREATE TABLE collection_snippets (
+    collection_id INTEGER NOT NULL,
+    snippet_id INTEGER NOT NULL,
+    position INTEGER NOT NULL,
+    PRIMARY KEY (collection_id, snippet_id)
+);
+
+CREATE INDEX idx_collection_snippets_snippet ON collection_snippets(snippet_id);
+
+ALTER TABLE collection_snippets ADD CONSTRAINT collection_snippets_fk_collection FOREIGN KEY (collection_id) REFERENCES collections(id) ON DELETE CASCADE;
+
+ALTER TABLE collection_snippets ADD CONSTRAINT collection_snippets_fk_snippet FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE;
+
+CREATE TABLE api_tokens (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    user_id INTEGER NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    token_hash CHAR(64) CHARACTER SET ascii COLLATE ascii_bin NOT NULL,
+    scopes VARCHAR(100) NOT NULL,
+    created DATETIME NOT NULL,
+    expires DATETIME NOT NULL,
+    last_used DATETIME NULL
+);
+
+ALTER TABLE api_tokens ADD CONSTRAINT api_tokens_uc_token_hash UNIQUE (token_hash);
+
+CREATE INDEX idx_api_tokens_expires ON api_tokens(expires);
+
+ALTER TABLE api_tokens ADD CONSTRAINT api_tokens_fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+CREATE TABLE webhooks (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    user_id INTEGER NOT NULL,
+    url VARCHAR(2048) NOT NULL,
+    secret VARCHAR(100) CHARACTER SET ascii COLLATE ascii_bin NOT NULL,
+    events VARCHAR(255) NOT NULL,
     created DATETIME NOT NULL
 );
 
-ALTER TABLE users ADD CONSTRAINT users_uc_email UNIQUE (email);
+ALTER TABLE webhooks ADD CONSTRAINT webhooks_fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+CREATE TABLE webhook_deliveries (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    webhook_id INTEGER NOT NULL,
+    event VARCHAR(50) NOT NULL,
+    payload TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    response_status INTEGER NOT NULL DEFAULT 0,
+    error VARCHAR(255) NOT NULL DEFAULT '',
+    created DATETIME NOT NULL,
+    last_attempt DATETIME NULL,
+    next_attempt DATETIME NULL
+);
+
+CREATE INDEX idx_webhook_deliveries_next_attempt ON webhook_deliveries(status, next_attempt);
+
+ALTER TABLE webhook_deliveries ADD CONSTRAINT webhook_deliveries_fk_webhook FOREIGN KEY (webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE;
 
 INSERT INTO users (name, email, hashed_password, created) VALUES (
     'Alice Jones',
@@ -24,3 +201,29 @@ INSERT INTO users (name, email, hashed_password, created) VALUES (
     '$2a$12$NuTjWXm3KKntReFwyBVHyuf/to.HEwTy.eS206TNfkGfr6HzGJSWG',
     '2022-01-01 09:18:24'
 );
+
+INSERT INTO snippets (short_id, title, content, created, expires, user_id, legacy) VALUES (
+    'silentPond',
+    'An old silent pond',
+    'An old silent pond...',
+    '2022-01-01 10:00:00',
+    '2099-01-01 10:00:00',
+    1,
+    TRUE
+);
+
+INSERT INTO snippet_revisions (snippet_id, version, title, content, created, user_id) VALUES (
+    1,
+    1,
+    'An old silent pond',
+    'An old silent pond...',
+    '2022-01-01 10:00:00',
+    1
+);
+
+INSERT INTO snippet_files (snippet_id, position, name, content) VALUES (
+    1,
+    0,
+    'an-old-silent-pond.txt',
+    'An old silent pond...'
+);
@@ -1,3 +1,27 @@
-DROP TABLE users;
+DROP TABLE sessions;
+
+DROP TABLE stars;
+
+DROP TABLE comments;
+
+DROP TABLE collection_snippets;
+
+DROP TABLE collections;
+
+DROP TABLE webhook_deliveries;
+
+DROP TABLE webhooks;
+
+DROP TABLE api_tokens;
+
+DROP TABLE snippet_tags;
+
+DROP TABLE tags;
+
+DROP TABLE snippet_files;
+
+DROP TABLE snippet_revisions;
 
 DROP TABLE snippets;
+
+DROP TABLE users;
@@ -66,3 +66,9 @@ func integrationTest(t *testing.T) {
 		t.Skip("skipping integration test")
 	}
 }
+
+// singleFile returns the files of a snippet which has just one file with the
+// given content.
+func singleFile(content string) []SnippetFile {
+	return []SnippetFile{{Name: "snippet.txt", Content: content}}
+}
@@ -0,0 +1,225 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+// The scopes of an API token control what it can be used for.
+const (
+	ScopeSnippetsRead  = "snippets:read"
+	ScopeSnippetsWrite = "snippets:write"
+)
+
+// Scopes lists every scope, in the order they're shown to users.
+var Scopes = []string{ScopeSnippetsRead, ScopeSnippetsWrite}
+
+// TokenPrefix starts every API token, so that tokens are easy to recognise
+// (for example by secret scanners) if they're leaked.
+const TokenPrefix = "snip_"
+
+type TokenModelInterface interface {
+	Insert(userID int, input TokenInput) (string, error)
+	ByUser(userID int) ([]Token, error)
+	Delete(id, userID int) error
+	Authenticate(plaintext string) (Token, error)
+	DeleteExpired(limit int) (int, error)
+}
+
+// A Token is a personal access token, which lets scripts and other
+// non-browser clients use the API on behalf of a user. Only a hash of the
+// token itself is stored, so it can't be shown again after it's created.
+// LastUsed is the zero time if the token has never been used.
+type Token struct {
+	ID       int
+	UserID   int
+	Name     string
+	Scopes   []string
+	Created  time.Time
+	Expires  time.Time
+	LastUsed time.Time
+}
+
+// HasScope reports whether the token was given the scope.
+func (t Token) HasScope(scope string) bool {
+	return slices.Contains(t.Scopes, scope)
+}
+
+// Expired reports whether the token has expired.
+func (t Token) Expired() bool {
+	return !t.Expires.After(time.Now())
+}
+
+// TokenInput holds the user-supplied fields used to create a token.
+type TokenInput struct {
+	Name    string
+	Scopes  []string
+	Expires time.Time
+}
+
+// Define a TokenModel type which wraps a sql.DB connection pool.
+type TokenModel struct {
+	DB *sql.DB
+}
+
+// hashToken returns the hash of a token which is stored in the database.
+// Tokens are long and random, so unlike passwords they don't need a slow,
+// salted hash like bcrypt; SHA-256 is enough, and it means a token can be
+// looked up by its hash.
+func hashToken(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
+
+// newToken returns a new random token: TokenPrefix followed by 32 random
+// bytes, base64 encoded.
+func newToken() (string, error) {
+	b := make([]byte, 32)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return TokenPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// This will create a new token for a user and return it. This is the only
+// time the token itself is available.
+func (m *TokenModel) Insert(userID int, input TokenInput) (string, error) {
+	plaintext, err := newToken()
+	if err != nil {
+		return "", err
+	}
+
+	stmt := `INSERT INTO api_tokens (user_id, name, token_hash, scopes, created, expires)
+	VALUES (?, ?, ?, ?, UTC_TIMESTAMP(), ?)`
+
+	_, err = m.DB.Exec(stmt, userID, input.Name, hashToken(plaintext), strings.Join(input.Scopes, ","), input.Expires.UTC())
+	if err != nil {
+		return "", err
+	}
+
+	return plaintext, nil
+}
+
+// tokenSelect selects the columns scanned by scanToken.
+const tokenSelect = `SELECT id, user_id, name, scopes, created, expires, last_used FROM api_tokens`
+
+// scanToken scans a row selected with tokenSelect into a Token.
+func scanToken(row rowScanner) (Token, error) {
+	var (
+		t        Token
+		scopes   string
+		lastUsed sql.NullTime
+	)
+
+	err := row.Scan(&t.ID, &t.UserID, &t.Name, &scopes, &t.Created, &t.Expires, &lastUsed)
+	if err != nil {
+		return Token{}, err
+	}
+
+	t.Scopes = strings.Split(scopes, ",")
+	t.LastUsed = lastUsed.Time
+
+	return t, nil
+}
+
+// This will return all of a user's tokens, including expired ones which
+// haven't been reaped yet, newest first.
+func (m *TokenModel) ByUser(userID int) ([]Token, error) {
+	stmt := tokenSelect + ` WHERE user_id = ? ORDER BY created DESC, id DESC`
+
+	rows, err := m.DB.Query(stmt, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var tokens []Token
+
+	for rows.Next() {
+		t, err := scanToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// This will revoke one of a user's tokens by deleting it. If the token
+// doesn't exist or belongs to someone else, ErrNoRecord is returned.
+func (m *TokenModel) Delete(id, userID int) error {
+	stmt := `DELETE FROM api_tokens WHERE id = ? AND user_id = ?`
+
+	result, err := m.DB.Exec(stmt, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+// This will find the unexpired token matching plaintext and record that it
+// has been used. If there isn't one, ErrInvalidCredentials is returned.
+func (m *TokenModel) Authenticate(plaintext string) (Token, error) {
+	stmt := tokenSelect + ` WHERE token_hash = ? AND expires > UTC_TIMESTAMP()`
+
+	t, err := scanToken(m.DB.QueryRow(stmt, hashToken(plaintext)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Token{}, ErrInvalidCredentials
+		}
+		return Token{}, err
+	}
+
+	stmt = `UPDATE api_tokens SET last_used = UTC_TIMESTAMP() WHERE id = ?`
+
+	_, err = m.DB.Exec(stmt, t.ID)
+	if err != nil {
+		return Token{}, err
+	}
+
+	return t, nil
+}
+
+// This will permanently delete up to limit expired tokens and return how
+// many were deleted, in the same way as SnippetModel.DeleteExpired().
+func (m *TokenModel) DeleteExpired(limit int) (int, error) {
+	stmt := `DELETE FROM api_tokens WHERE expires <= UTC_TIMESTAMP() LIMIT ?`
+
+	result, err := m.DB.Exec(stmt, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(rows), nil
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mixnblend/snippetbox/internal/assert"
+)
+
+func TestTokenModelIntegration(t *testing.T) {
+	integrationTest(t)
+
+	// given ... we have a database with Alice (ID 1) in it
+	db := newTestDB(t)
+	m := TokenModel{DB: db}
+
+	// when ... Alice creates a token
+	input := TokenInput{
+		Name:    "CI",
+		Scopes:  []string{ScopeSnippetsRead, ScopeSnippetsWrite},
+		Expires: time.Now().Add(24 * time.Hour),
+	}
+	plaintext, err := m.Insert(1, input)
+	assert.NilError(t, err)
+
+	// then ... the token should start with the prefix
+	assert.Equal(t, strings.HasPrefix(plaintext, TokenPrefix), true)
+
+	// and ... only its hash should be stored
+	var stored string
+	err = db.QueryRow(`SELECT token_hash FROM api_tokens`).Scan(&stored)
+	assert.NilError(t, err)
+	assert.Equal(t, stored, hashToken(plaintext))
+
+	// and ... it should be listed, but not used yet
+	tokens, err := m.ByUser(1)
+	assert.NilError(t, err)
+	assert.Equal(t, len(tokens), 1)
+	assert.Equal(t, tokens[0].Name, "CI")
+	assert.Equal(t, tokens[0].HasScope(ScopeSnippetsWrite), true)
+	assert.Equal(t, tokens[0].LastUsed.IsZero(), true)
+
+	// when ... the token is used
+	token, err := m.Authenticate(plaintext)
+	assert.NilError(t, err)
+
+	// then ... it should belong to Alice, and its use should be recorded
+	assert.Equal(t, token.UserID, 1)
+	tokens, err = m.ByUser(1)
+	assert.NilError(t, err)
+	assert.Equal(t, tokens[0].LastUsed.IsZero(), false)
+
+	// and ... a wrong token should be refused
+	_, err = m.Authenticate(plaintext + "x")
+	assert.Equal(t, err, ErrInvalidCredentials)
+
+	// when ... Alice creates a token which has already expired
+	expired, err := m.Insert(1, TokenInput{Name: "Old", Scopes: []string{ScopeSnippetsRead}, Expires: time.Now().Add(-time.Hour)})
+	assert.NilError(t, err)
+
+	// then ... it should be refused
+	_, err = m.Authenticate(expired)
+	assert.Equal(t, err, ErrInvalidCredentials)
+
+	// when ... someone else tries to revoke Alice's token
+	err = m.Delete(token.ID, 2)
+
+	// then ... it should be left alone
+	assert.Equal(t, err, ErrNoRecord)
+
+	// when ... Alice revokes it
+	err = m.Delete(token.ID, 1)
+	assert.NilError(t, err)
+
+	// then ... it can no longer be used
+	_, err = m.Authenticate(plaintext)
+	assert.Equal(t, err, ErrInvalidCredentials)
+}
+
+func TestTokenModelDeleteExpiredIntegration(t *testing.T) {
+	integrationTest(t)
+
+	// given ... we have a database with an expired and a current token in it
+	db := newTestDB(t)
+	m := TokenModel{DB: db}
+
+	_, err := db.Exec(`INSERT INTO api_tokens (user_id, name, token_hash, scopes, created, expires) VALUES
+	(1, 'expired', 'expired', 'snippets:read', UTC_TIMESTAMP(), DATE_SUB(UTC_TIMESTAMP(), INTERVAL 1 HOUR)),
+	(1, 'current', 'current', 'snippets:read', UTC_TIMESTAMP(), DATE_ADD(UTC_TIMESTAMP(), INTERVAL 1 HOUR))`)
+	assert.NilError(t, err)
+
+	// when ... we delete expired tokens
+	n, err := m.DeleteExpired(10)
+
+	// then ... only the expired token should be deleted
+	assert.NilError(t, err)
+	assert.Equal(t, n, 1)
+
+	tokens, err := m.ByUser(1)
+	assert.NilError(t, err)
+	assert.Equal(t, len(tokens), 1)
+	assert.Equal(t, tokens[0].Name, "current")
+}
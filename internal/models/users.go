@@ -15,6 +15,7 @@ type UserModelInterface interface {
 	Authenticate(email, password string) (int, error)
 	Exists(id int) (bool, error)
 	Get(id int) (User, error)
+	Verify(email string) error
 	PasswordUpdate(id int, currentPassword, newPassword string) error
 }
 
@@ -29,6 +30,10 @@ type User struct {
 	Email          string
 	HashedPassword []byte
 	Created        time.Time
+	// Verified is true once the account has been verified by an operator
+	// (see the "verify" command). Accounts which aren't verified yet have
+	// a shorter limit on how long their snippets can be kept.
+	Verified bool
 }
 
 // Define a new UserModel struct which wraps a database connection pool.
@@ -75,9 +80,9 @@ func (m *UserModel) Insert(name, email, password string) error {
 func (m *UserModel) Get(id int) (User, error) {
 	var user User
 
-	stmt := `select id, email, name, created from users WHERE id = ?`
+	stmt := `select id, email, name, created, verified from users WHERE id = ?`
 
-	err := m.DB.QueryRow(stmt, id).Scan(&user.ID, &user.Email, &user.Name, &user.Created)
+	err := m.DB.QueryRow(stmt, id).Scan(&user.ID, &user.Email, &user.Name, &user.Created, &user.Verified)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return User{}, ErrNoRecord
@@ -89,6 +94,27 @@ func (m *UserModel) Get(id int) (User, error) {
 	return user, nil
 }
 
+// We'll use the Verify method to mark the account with the given email
+// address as verified. If there's no such account, ErrNoRecord is returned.
+func (m *UserModel) Verify(email string) error {
+	var id int
+
+	stmt := `SELECT id FROM users WHERE email = ?`
+
+	err := m.DB.QueryRow(stmt, email).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoRecord
+		}
+		return err
+	}
+
+	stmt = `UPDATE users SET verified = TRUE WHERE id = ?`
+
+	_, err = m.DB.Exec(stmt, id)
+	return err
+}
+
 func (m *UserModel) Authenticate(email, password string) (int, error) {
 	var id int
 	var hashedPassword []byte
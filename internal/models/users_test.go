@@ -44,3 +44,35 @@ func TestUserModelExistsIntegration(t *testing.T) {
 		})
 	}
 }
+
+func TestUserModelVerifyIntegration(t *testing.T) {
+	integrationTest(t)
+
+	// given ... we have a database with Alice in it, whose account hasn't
+	// been verified
+	db := newTestDB(t)
+	m := UserModel{DB: db}
+
+	user, err := m.Get(1)
+	assert.NilError(t, err)
+	assert.Equal(t, user.Verified, false)
+
+	// when ... Alice's account is verified
+	err = m.Verify("alice@example.com")
+	assert.NilError(t, err)
+
+	// then ... it should stay verified
+	user, err = m.Get(1)
+	assert.NilError(t, err)
+	assert.Equal(t, user.Verified, true)
+
+	// and ... verifying it again should do no harm
+	err = m.Verify("alice@example.com")
+	assert.NilError(t, err)
+
+	// when ... we try to verify an account which doesn't exist
+	err = m.Verify("nobody@example.com")
+
+	// then ... it should be reported as missing
+	assert.Equal(t, err, ErrNoRecord)
+}
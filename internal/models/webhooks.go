@@ -0,0 +1,411 @@
+package models
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"slices"
+	"strings"
+	"time"
+)
+
+// The events a webhook can be sent, named after what happened to the
+// snippet.
+const (
+	EventSnippetCreated = "snippet.created"
+	EventSnippetUpdated = "snippet.updated"
+	EventSnippetDeleted = "snippet.deleted"
+	EventSnippetExpired = "snippet.expired"
+)
+
+// WebhookEvents lists every event, in the order they're shown to users.
+var WebhookEvents = []string{EventSnippetCreated, EventSnippetUpdated, EventSnippetDeleted, EventSnippetExpired}
+
+// The statuses of a webhook delivery. A pending delivery has either not been
+// attempted yet or is waiting to be retried; a failed one has used up all
+// of its attempts.
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookSecretPrefix starts every webhook secret, in the same way as
+// TokenPrefix.
+const WebhookSecretPrefix = "whsec_"
+
+// deliveryRetention is how long finished deliveries are kept in the log.
+const deliveryRetention = 30 * 24 * time.Hour
+
+type WebhookModelInterface interface {
+	Insert(userID int, input WebhookInput) (Webhook, error)
+	ByUser(userID int) ([]Webhook, error)
+	Delete(id, userID int) error
+	Subscribed(userID int, event string) ([]Webhook, error)
+	InsertDelivery(webhookID int, event string, payload []byte, nextAttempt time.Time) (int, error)
+	ClaimDue(limit int, lease time.Duration) ([]WebhookDelivery, error)
+	RecordAttempt(id int, attempt WebhookAttempt) error
+	Deliveries(userID int, limit int) ([]WebhookDelivery, error)
+	DeleteExpiredDeliveries(limit int) (int, error)
+}
+
+// A Webhook is a URL which is sent a JSON payload when one of its user's
+// snippets has one of the chosen events. Each payload is signed with the
+// secret, which unlike an API token has to be stored as it is, as it's
+// needed to make the signature.
+type Webhook struct {
+	ID      int
+	UserID  int
+	URL     string
+	Secret  string
+	Events  []string
+	Created time.Time
+}
+
+// HasEvent reports whether the webhook is sent the event.
+func (w Webhook) HasEvent(event string) bool {
+	return slices.Contains(w.Events, event)
+}
+
+// WebhookInput holds the user-supplied fields used to create a webhook.
+type WebhookInput struct {
+	URL    string
+	Events []string
+}
+
+// A WebhookDelivery is one payload sent (or to be sent) to a webhook, along
+// with the result of the latest attempt. ResponseStatus is 0 if no response
+// was received, in which case Error says why. The webhook's URL and secret
+// are included so that the delivery can be sent without looking the
+// webhook up again.
+type WebhookDelivery struct {
+	ID             int
+	WebhookID      int
+	URL            string
+	Secret         string
+	Event          string
+	Payload        []byte
+	Status         string
+	Attempts       int
+	ResponseStatus int
+	Error          string
+	Created        time.Time
+	LastAttempt    time.Time
+	NextAttempt    time.Time
+}
+
+// A WebhookAttempt is the result of trying to send a delivery. NextAttempt
+// is when to try again if Status is DeliveryPending.
+type WebhookAttempt struct {
+	Status         string
+	ResponseStatus int
+	Error          string
+	NextAttempt    time.Time
+}
+
+// Define a WebhookModel type which wraps a sql.DB connection pool.
+type WebhookModel struct {
+	DB *sql.DB
+}
+
+// newWebhookSecret returns a new random secret: WebhookSecretPrefix
+// followed by 32 random bytes, hex encoded.
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return WebhookSecretPrefix + hex.EncodeToString(b), nil
+}
+
+// This will create a new webhook for a user, with a new secret, and return
+// it.
+func (m *WebhookModel) Insert(userID int, input WebhookInput) (Webhook, error) {
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return Webhook{}, err
+	}
+
+	stmt := `INSERT INTO webhooks (user_id, url, secret, events, created)
+	VALUES (?, ?, ?, ?, UTC_TIMESTAMP())`
+
+	result, err := m.DB.Exec(stmt, userID, input.URL, secret, strings.Join(input.Events, ","))
+	if err != nil {
+		return Webhook{}, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return Webhook{}, err
+	}
+
+	return scanWebhook(m.DB.QueryRow(webhookSelect+` WHERE id = ?`, id))
+}
+
+// webhookSelect selects the columns scanned by scanWebhook.
+const webhookSelect = `SELECT id, user_id, url, secret, events, created FROM webhooks`
+
+// scanWebhook scans a row selected with webhookSelect into a Webhook.
+func scanWebhook(row rowScanner) (Webhook, error) {
+	var (
+		w      Webhook
+		events string
+	)
+
+	err := row.Scan(&w.ID, &w.UserID, &w.URL, &w.Secret, &events, &w.Created)
+	if err != nil {
+		return Webhook{}, err
+	}
+
+	w.Events = strings.Split(events, ",")
+
+	return w, nil
+}
+
+// This will return all of a user's webhooks, oldest first.
+func (m *WebhookModel) ByUser(userID int) ([]Webhook, error) {
+	stmt := webhookSelect + ` WHERE user_id = ? ORDER BY id`
+
+	rows, err := m.DB.Query(stmt, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var webhooks []Webhook
+
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, w)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+// This will delete one of a user's webhooks, along with its deliveries. If
+// the webhook doesn't exist or belongs to someone else, ErrNoRecord is
+// returned.
+func (m *WebhookModel) Delete(id, userID int) error {
+	stmt := `DELETE FROM webhooks WHERE id = ? AND user_id = ?`
+
+	result, err := m.DB.Exec(stmt, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+// This will return the user's webhooks which are sent the event. A user
+// only has a handful of webhooks, so they're filtered here rather than by
+// searching the comma-separated events column.
+func (m *WebhookModel) Subscribed(userID int, event string) ([]Webhook, error) {
+	webhooks, err := m.ByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.DeleteFunc(webhooks, func(w Webhook) bool {
+		return !w.HasEvent(event)
+	}), nil
+}
+
+// This will add a pending delivery of the payload to a webhook, to be
+// attempted at nextAttempt, and return its ID.
+func (m *WebhookModel) InsertDelivery(webhookID int, event string, payload []byte, nextAttempt time.Time) (int, error) {
+	stmt := `INSERT INTO webhook_deliveries (webhook_id, event, payload, status, created, next_attempt)
+	VALUES (?, ?, ?, ?, UTC_TIMESTAMP(), ?)`
+
+	result, err := m.DB.Exec(stmt, webhookID, event, string(payload), DeliveryPending, nextAttempt.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// deliverySelect selects the columns scanned by scanDelivery.
+const deliverySelect = `SELECT d.id, d.webhook_id, w.url, w.secret, d.event, d.payload, d.status,
+	d.attempts, d.response_status, d.error, d.created, d.last_attempt, d.next_attempt
+	FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id`
+
+// scanDelivery scans a row selected with deliverySelect into a
+// WebhookDelivery.
+func scanDelivery(row rowScanner) (WebhookDelivery, error) {
+	var (
+		d                        WebhookDelivery
+		payload                  string
+		lastAttempt, nextAttempt sql.NullTime
+	)
+
+	err := row.Scan(&d.ID, &d.WebhookID, &d.URL, &d.Secret, &d.Event, &payload, &d.Status,
+		&d.Attempts, &d.ResponseStatus, &d.Error, &d.Created, &lastAttempt, &nextAttempt)
+	if err != nil {
+		return WebhookDelivery{}, err
+	}
+
+	d.Payload = []byte(payload)
+	d.LastAttempt = lastAttempt.Time
+	d.NextAttempt = nextAttempt.Time
+
+	return d, nil
+}
+
+// scanDeliveries scans every row selected with deliverySelect.
+func scanDeliveries(rows *sql.Rows) ([]WebhookDelivery, error) {
+	defer rows.Close()
+
+	var deliveries []WebhookDelivery
+
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// This will return up to limit pending deliveries which are due to be
+// attempted, oldest first. Each one's next attempt is pushed back by the
+// lease, so that it isn't returned again while it's being sent; if the
+// attempt is never recorded (because the server stopped, say), it's
+// returned again once the lease runs out.
+func (m *WebhookModel) ClaimDue(limit int, lease time.Duration) ([]WebhookDelivery, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	stmt := deliverySelect + ` WHERE d.status = ? AND d.next_attempt <= UTC_TIMESTAMP()
+	ORDER BY d.next_attempt, d.id LIMIT ?`
+
+	rows, err := tx.Query(stmt, DeliveryPending, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries, err := scanDeliveries(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	leased := time.Now().Add(lease).UTC()
+
+	for i := range deliveries {
+		_, err = tx.Exec(`UPDATE webhook_deliveries SET next_attempt = ? WHERE id = ?`, leased, deliveries[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		deliveries[i].NextAttempt = leased
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// This will record the result of an attempt to send a delivery.
+func (m *WebhookModel) RecordAttempt(id int, attempt WebhookAttempt) error {
+	var nextAttempt sql.NullTime
+	if attempt.Status == DeliveryPending {
+		nextAttempt = sql.NullTime{Time: attempt.NextAttempt.UTC(), Valid: true}
+	}
+
+	stmt := `UPDATE webhook_deliveries SET status = ?, attempts = attempts + 1, response_status = ?,
+	error = ?, last_attempt = UTC_TIMESTAMP(), next_attempt = ? WHERE id = ?`
+
+	result, err := m.DB.Exec(stmt, attempt.Status, attempt.ResponseStatus, truncate(attempt.Error, 255), nextAttempt, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+// truncate shortens s to at most n characters.
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+
+	return string(runes[:n])
+}
+
+// This will return the latest deliveries to any of a user's webhooks,
+// newest first, for the delivery log on their account page.
+func (m *WebhookModel) Deliveries(userID int, limit int) ([]WebhookDelivery, error) {
+	stmt := deliverySelect + ` WHERE w.user_id = ? ORDER BY d.created DESC, d.id DESC LIMIT ?`
+
+	rows, err := m.DB.Query(stmt, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanDeliveries(rows)
+}
+
+// This will delete up to limit finished deliveries which are older than
+// deliveryRetention, and return how many were deleted, in the same way as
+// SnippetModel.DeleteExpired().
+func (m *WebhookModel) DeleteExpiredDeliveries(limit int) (int, error) {
+	stmt := `DELETE FROM webhook_deliveries WHERE status <> ? AND created <= ? LIMIT ?`
+
+	result, err := m.DB.Exec(stmt, DeliveryPending, time.Now().Add(-deliveryRetention).UTC(), limit)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(rows), nil
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mixnblend/snippetbox/internal/assert"
+)
+
+func TestWebhookModelIntegration(t *testing.T) {
+	integrationTest(t)
+
+	// given ... we have a database with Alice (ID 1) in it
+	db := newTestDB(t)
+	m := WebhookModel{DB: db}
+
+	// when ... Alice adds a webhook for new and deleted snippets
+	webhook, err := m.Insert(1, WebhookInput{
+		URL:    "https://chat.example.com/hooks/snippets",
+		Events: []string{EventSnippetCreated, EventSnippetDeleted},
+	})
+	assert.NilError(t, err)
+
+	// then ... it should be given a secret
+	assert.Equal(t, strings.HasPrefix(webhook.Secret, WebhookSecretPrefix), true)
+
+	// and ... it should only be subscribed to the events chosen
+	subscribed, err := m.Subscribed(1, EventSnippetCreated)
+	assert.NilError(t, err)
+	assert.Equal(t, len(subscribed), 1)
+	assert.Equal(t, subscribed[0].URL, "https://chat.example.com/hooks/snippets")
+
+	subscribed, err = m.Subscribed(1, EventSnippetUpdated)
+	assert.NilError(t, err)
+	assert.Equal(t, len(subscribed), 0)
+
+	// when ... a delivery is added which is due now
+	id, err := m.InsertDelivery(webhook.ID, EventSnippetCreated, []byte(`{"event":"snippet.created"}`), time.Now().Add(-time.Second))
+	assert.NilError(t, err)
+
+	// then ... it should be claimed along with the webhook's URL and secret
+	due, err := m.ClaimDue(10, time.Minute)
+	assert.NilError(t, err)
+	assert.Equal(t, len(due), 1)
+	assert.Equal(t, due[0].ID, id)
+	assert.Equal(t, due[0].Secret, webhook.Secret)
+	assert.Equal(t, string(due[0].Payload), `{"event":"snippet.created"}`)
+
+	// and ... it shouldn't be claimed again while it's being sent
+	due, err = m.ClaimDue(10, time.Minute)
+	assert.NilError(t, err)
+	assert.Equal(t, len(due), 0)
+
+	// when ... the first attempt fails and is retried straight away
+	err = m.RecordAttempt(id, WebhookAttempt{Status: DeliveryPending, ResponseStatus: 502, NextAttempt: time.Now().Add(-time.Second)})
+	assert.NilError(t, err)
+
+	due, err = m.ClaimDue(10, time.Minute)
+	assert.NilError(t, err)
+	assert.Equal(t, len(due), 1)
+	assert.Equal(t, due[0].Attempts, 1)
+
+	// and ... the second attempt succeeds
+	err = m.RecordAttempt(id, WebhookAttempt{Status: DeliverySucceeded, ResponseStatus: 200})
+	assert.NilError(t, err)
+
+	// then ... it should be in Alice's delivery log, and not due again
+	deliveries, err := m.Deliveries(1, 10)
+	assert.NilError(t, err)
+	assert.Equal(t, len(deliveries), 1)
+	assert.Equal(t, deliveries[0].Status, DeliverySucceeded)
+	assert.Equal(t, deliveries[0].Attempts, 2)
+	assert.Equal(t, deliveries[0].ResponseStatus, 200)
+	assert.Equal(t, deliveries[0].NextAttempt.IsZero(), true)
+
+	due, err = m.ClaimDue(10, time.Minute)
+	assert.NilError(t, err)
+	assert.Equal(t, len(due), 0)
+
+	// and ... recent deliveries should be kept
+	n, err := m.DeleteExpiredDeliveries(10)
+	assert.NilError(t, err)
+	assert.Equal(t, n, 0)
+
+	// when ... someone else tries to delete Alice's webhook
+	err = m.Delete(webhook.ID, 2)
+
+	// then ... it should be left alone
+	assert.Equal(t, err, ErrNoRecord)
+
+	// when ... Alice deletes it
+	err = m.Delete(webhook.ID, 1)
+	assert.NilError(t, err)
+
+	// then ... its deliveries should go with it
+	deliveries, err = m.Deliveries(1, 10)
+	assert.NilError(t, err)
+	assert.Equal(t, len(deliveries), 0)
+}
@@ -0,0 +1,133 @@
+package search
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// A Fragment is a piece of highlighted text. Templates should wrap fragments
+// with Match set in a <mark> element.
+type Fragment struct {
+	Text  string
+	Match bool
+}
+
+// Highlight splits text into fragments, marking every case-insensitive
+// occurrence of any of the terms. Where terms overlap, the longest one
+// starting earliest wins.
+func Highlight(text string, terms []string) []Fragment {
+	var fragments []Fragment
+	plain := 0
+
+	for i := 0; i < len(text); {
+		n := matchAt(text[i:], terms)
+		if n == 0 {
+			_, size := utf8.DecodeRuneInString(text[i:])
+			i += size
+			continue
+		}
+
+		if plain < i {
+			fragments = append(fragments, Fragment{Text: text[plain:i]})
+		}
+		fragments = append(fragments, Fragment{Text: text[i : i+n], Match: true})
+		i += n
+		plain = i
+	}
+
+	if plain < len(text) {
+		fragments = append(fragments, Fragment{Text: text[plain:]})
+	}
+
+	return fragments
+}
+
+// Excerpt returns the highlighted fragments of an excerpt of text around the
+// first occurrence of any of the terms. Runs of whitespace are collapsed, the
+// excerpt is at most width characters long (plus ellipses where the text has
+// been cut), and it starts at the beginning of the text if none of the terms
+// occur.
+func Excerpt(text string, terms []string, width int) []Fragment {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+
+	if len(runes) <= width {
+		return Highlight(text, terms)
+	}
+
+	// Find the first match, and centre the excerpt on it.
+	start := 0
+	if first := firstMatch(text, terms); first >= 0 {
+		start = utf8.RuneCountInString(text[:first]) - width/2
+	}
+	start = max(0, min(start, len(runes)-width))
+	end := start + width
+
+	// Avoid cutting words in half where possible.
+	for start > 0 && runes[start-1] != ' ' && start < end {
+		start++
+	}
+	for end < len(runes) && runes[end] != ' ' && end > start {
+		end--
+	}
+
+	excerpt := strings.TrimSpace(string(runes[start:end]))
+	if start > 0 {
+		excerpt = "…" + excerpt
+	}
+	if end < len(runes) {
+		excerpt += "…"
+	}
+
+	return Highlight(excerpt, terms)
+}
+
+// firstMatch returns the byte offset of the first occurrence of any of the
+// terms in text, or -1 if there isn't one.
+func firstMatch(text string, terms []string) int {
+	for i := 0; i < len(text); {
+		if matchAt(text[i:], terms) > 0 {
+			return i
+		}
+		_, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+	}
+
+	return -1
+}
+
+// matchAt returns the length in bytes of the longest term which text starts
+// with, ignoring case, or 0 if it doesn't start with any of them.
+func matchAt(text string, terms []string) int {
+	longest := 0
+
+	for _, term := range terms {
+		if term == "" {
+			continue
+		}
+		if n := prefixFold(text, term); n > longest {
+			longest = n
+		}
+	}
+
+	return longest
+}
+
+// prefixFold reports how many bytes of text match prefix under Unicode case
+// folding, or 0 if text doesn't start with prefix.
+func prefixFold(text, prefix string) int {
+	i := 0
+
+	for _, want := range prefix {
+		if i >= len(text) {
+			return 0
+		}
+		got, size := utf8.DecodeRuneInString(text[i:])
+		if !strings.EqualFold(string(got), string(want)) {
+			return 0
+		}
+		i += size
+	}
+
+	return i
+}
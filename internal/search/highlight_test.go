@@ -0,0 +1,107 @@
+package search
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mixnblend/snippetbox/internal/assert"
+)
+
+// render formats fragments with matches in square brackets.
+func render(fragments []Fragment) string {
+	var b strings.Builder
+	for _, f := range fragments {
+		if f.Match {
+			b.WriteString("[" + f.Text + "]")
+		} else {
+			b.WriteString(f.Text)
+		}
+	}
+	return b.String()
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		terms []string
+		want  string
+	}{
+		{
+			name:  "No terms",
+			text:  "An old silent pond",
+			terms: nil,
+			want:  "An old silent pond",
+		},
+		{
+			name:  "Case insensitive",
+			text:  "Pond after pond",
+			terms: []string{"pond"},
+			want:  "[Pond] after [pond]",
+		},
+		{
+			name:  "Longest match wins",
+			text:  "An old silent pond",
+			terms: []string{"silent", "silent pond"},
+			want:  "An old [silent pond]",
+		},
+		{
+			name:  "Unicode",
+			text:  "Ünïcödé frog ÜNÏCÖDÉ",
+			terms: []string{"ünïcödé"},
+			want:  "[Ünïcödé] frog [ÜNÏCÖDÉ]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When ... we highlight the text
+			// Then ... the terms should be marked
+			assert.Equal(t, render(Highlight(tt.text, tt.terms)), tt.want)
+		})
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	text := "An old silent pond...\nA frog jumps into the pond,\nsplash! Silence again."
+
+	tests := []struct {
+		name  string
+		terms []string
+		width int
+		want  string
+	}{
+		{
+			name:  "Short text",
+			terms: []string{"frog"},
+			width: 100,
+			want:  "An old silent pond... A [frog] jumps into the pond, splash! Silence again.",
+		},
+		{
+			name:  "Centred on the first match",
+			terms: []string{"frog"},
+			width: 20,
+			want:  "…pond... A [frog] jumps…",
+		},
+		{
+			name:  "No match",
+			terms: []string{"winter"},
+			width: 20,
+			want:  "An old silent…",
+		},
+		{
+			name:  "Match at the end",
+			terms: []string{"again"},
+			width: 20,
+			want:  "…Silence [again].",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When ... we take an excerpt of the text
+			// Then ... it should be cut and highlighted as expected
+			assert.Equal(t, render(Excerpt(text, tt.terms, tt.width)), tt.want)
+		})
+	}
+}
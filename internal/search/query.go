@@ -0,0 +1,147 @@
+// Package search parses the query syntax used to search snippets and
+// highlights the matching parts of a snippet for display.
+//
+// A query is a list of terms separated by whitespace. A term is a single word
+// or a "quoted phrase", and may be prefixed with - to exclude snippets which
+// contain it, or with title: to only match snippet titles. For example:
+//
+//	frog "silent pond" -winter title:haiku
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// FieldTitle restricts a term to snippet titles.
+const FieldTitle = "title"
+
+// A Term is a single part of a search query.
+type Term struct {
+	// Text is the word or phrase to search for, without any quotes.
+	Text string
+	// Phrase is set if the term was quoted, and so must appear exactly as
+	// written rather than being matched word by word.
+	Phrase bool
+	// Exclude is set if the term was prefixed with -.
+	Exclude bool
+	// Field is FieldTitle if the term was prefixed with title:, or empty if
+	// it can match anywhere.
+	Field string
+}
+
+// A Query is a parsed search query.
+type Query struct {
+	Terms []Term
+}
+
+// Parse parses a search query. It never fails: unterminated quotes run to
+// the end of the query, and prefixes without a term after them are ignored.
+func Parse(s string) Query {
+	var q Query
+
+	for len(s) > 0 {
+		s = strings.TrimLeftFunc(s, unicode.IsSpace)
+		if s == "" {
+			break
+		}
+
+		var term Term
+
+		if strings.HasPrefix(s, "-") {
+			term.Exclude = true
+			s = s[1:]
+		}
+
+		if len(s) >= len(FieldTitle)+1 && strings.EqualFold(s[:len(FieldTitle)+1], FieldTitle+":") {
+			term.Field = FieldTitle
+			s = s[len(FieldTitle)+1:]
+		}
+
+		if strings.HasPrefix(s, `"`) {
+			end := strings.IndexByte(s[1:], '"')
+			if end < 0 {
+				term.Text, s = s[1:], ""
+			} else {
+				term.Text, s = s[1:end+1], s[end+2:]
+			}
+			term.Phrase = true
+			term.Text = strings.Join(strings.Fields(term.Text), " ")
+		} else {
+			end := strings.IndexFunc(s, unicode.IsSpace)
+			if end < 0 {
+				end = len(s)
+			}
+			term.Text, s = s[:end], s[end:]
+		}
+
+		if term.Text != "" {
+			q.Terms = append(q.Terms, term)
+		}
+	}
+
+	return q
+}
+
+// Included returns the terms which snippets must match, in the order they
+// appeared in the query.
+func (q Query) Included() []Term {
+	var terms []Term
+	for _, t := range q.Terms {
+		if !t.Exclude {
+			terms = append(terms, t)
+		}
+	}
+	return terms
+}
+
+// Excluded returns the terms which snippets must not match.
+func (q Query) Excluded() []Term {
+	var terms []Term
+	for _, t := range q.Terms {
+		if t.Exclude {
+			terms = append(terms, t)
+		}
+	}
+	return terms
+}
+
+// IsEmpty reports whether the query has no terms for snippets to match. A
+// query made up only of exclusions is empty, as it would match almost
+// everything.
+func (q Query) IsEmpty() bool {
+	return len(q.Included()) == 0
+}
+
+// Highlights returns the text of the included terms, for passing to
+// Highlight() and Excerpt().
+func (q Query) Highlights() []string {
+	var highlights []string
+	for _, t := range q.Included() {
+		highlights = append(highlights, t.Text)
+	}
+	return highlights
+}
+
+// String formats the query back into the search syntax.
+func (q Query) String() string {
+	parts := make([]string, len(q.Terms))
+
+	for i, t := range q.Terms {
+		var b strings.Builder
+		if t.Exclude {
+			b.WriteString("-")
+		}
+		if t.Field != "" {
+			b.WriteString(t.Field + ":")
+		}
+		if t.Phrase {
+			b.WriteString(`"` + t.Text + `"`)
+		} else {
+			b.WriteString(t.Text)
+		}
+		parts[i] = b.String()
+	}
+
+	return strings.Join(parts, " ")
+}
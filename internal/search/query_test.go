@@ -0,0 +1,94 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/mixnblend/snippetbox/internal/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []Term
+	}{
+		{
+			name:  "Words",
+			query: "old  pond ",
+			want:  []Term{{Text: "old"}, {Text: "pond"}},
+		},
+		{
+			name:  "Phrase",
+			query: `frog "silent   pond"`,
+			want:  []Term{{Text: "frog"}, {Text: "silent pond", Phrase: true}},
+		},
+		{
+			name:  "Exclude",
+			query: `pond -winter -"wintry forest"`,
+			want: []Term{
+				{Text: "pond"},
+				{Text: "winter", Exclude: true},
+				{Text: "wintry forest", Phrase: true, Exclude: true},
+			},
+		},
+		{
+			name:  "Title",
+			query: `title:haiku Title:"old pond" -title:draft`,
+			want: []Term{
+				{Text: "haiku", Field: FieldTitle},
+				{Text: "old pond", Phrase: true, Field: FieldTitle},
+				{Text: "draft", Exclude: true, Field: FieldTitle},
+			},
+		},
+		{
+			name:  "Unterminated phrase",
+			query: `"silent pond`,
+			want:  []Term{{Text: "silent pond", Phrase: true}},
+		},
+		{
+			name:  "Prefixes without terms",
+			query: `- title: ""`,
+			want:  nil,
+		},
+		{
+			name:  "Other fields are plain words",
+			query: "lang:go",
+			want:  []Term{{Text: "lang:go"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When ... we parse the query
+			q := Parse(tt.query)
+
+			// Then ... we should get the expected terms
+			assert.Equal(t, len(q.Terms), len(tt.want))
+			for i := range min(len(q.Terms), len(tt.want)) {
+				assert.Equal(t, q.Terms[i], tt.want[i])
+			}
+		})
+	}
+}
+
+func TestQuery(t *testing.T) {
+	// Given ... we have parsed a query
+	q := Parse(`frog -winter title:"old pond"`)
+
+	// Then ... we should be able to split it into included and excluded terms
+	assert.Equal(t, len(q.Included()), 2)
+	assert.Equal(t, len(q.Excluded()), 1)
+	assert.Equal(t, q.IsEmpty(), false)
+
+	// And ... only the included terms should be highlighted
+	highlights := q.Highlights()
+	assert.Equal(t, len(highlights), 2)
+	assert.Equal(t, highlights[0], "frog")
+	assert.Equal(t, highlights[1], "old pond")
+
+	// And ... it should format back into the same query
+	assert.Equal(t, q.String(), `frog -winter title:"old pond"`)
+
+	// And ... a query with only exclusions should be empty
+	assert.Equal(t, Parse("-winter").IsEmpty(), true)
+}
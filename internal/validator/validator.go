@@ -4,6 +4,7 @@ import (
 	"regexp"
 	"slices"
 	"strings"
+	"unicode"
 	"unicode/utf8"
 )
 
@@ -14,6 +15,20 @@ import (
 // variable is more performant than re-parsing the pattern each time we need it.
 var EmailRX = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
 
+// ShortIDRX matches the random, URL-safe short IDs which identify snippets:
+// exactly 10 base62 characters.
+var ShortIDRX = regexp.MustCompile("^[0-9A-Za-z]{10}$")
+
+// TagRX matches a normalised snippet tag: up to 32 lowercase letters, digits
+// and the characters . + # - (so that tags like "c++", "c#" and "node.js" are
+// possible), starting with a letter or digit.
+var TagRX = regexp.MustCompile("^[a-z0-9][a-z0-9.+#-]{0,31}$")
+
+// FileNameRX matches a file name which is safe to use inside a zip archive:
+// one which doesn't contain slashes, backslashes or control characters, and
+// isn't made up only of dots (like "." and "..").
+var FileNameRX = regexp.MustCompile(`^[^/\\\x00-\x1f\x7f]*[^/\\\x00-\x1f\x7f.][^/\\\x00-\x1f\x7f]*$`)
+
 // MinChars() returns true if a value contains at least n characters.
 func MinChars(value string, n int) bool {
 	return utf8.RuneCountInString(value) >= n
@@ -80,3 +95,39 @@ func NotBlank(value string) bool {
 func PermittedValue[T comparable](value T, permittedValues ...T) bool {
 	return slices.Contains(permittedValues, value)
 }
+
+// MaxItems() returns true if a slice contains no more than n items.
+func MaxItems[T any](values []T, n int) bool {
+	return len(values) <= n
+}
+
+// AllMatch() returns true if every value matches a provided compiled regular
+// expression pattern.
+func AllMatch(values []string, rx *regexp.Regexp) bool {
+	for _, value := range values {
+		if !rx.MatchString(value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// NormalizeTags() splits a comma or whitespace separated list of tags, such as
+// "Go, SQL k8s", into lowercase tags with any duplicates removed. The tags
+// are returned in the order they were first given.
+func NormalizeTags(value string) []string {
+	fields := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+
+	var tags []string
+	for _, field := range fields {
+		tag := strings.ToLower(field)
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags
+}
@@ -0,0 +1,98 @@
+package validator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mixnblend/snippetbox/internal/assert"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "Empty", value: "  ", want: ""},
+		{name: "Commas", value: "go,sql", want: "go|sql"},
+		{name: "Whitespace", value: " go  sql\tk8s ", want: "go|sql|k8s"},
+		{name: "Mixed", value: "go, sql,,k8s", want: "go|sql|k8s"},
+		{name: "Lowercase", value: "Go, SQL", want: "go|sql"},
+		{name: "Duplicates", value: "go, Go, sql, GO", want: "go|sql"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When ... we normalize the tags
+			tags := NormalizeTags(tt.value)
+
+			// Then ... they should be split, lowercased and deduplicated
+			assert.Equal(t, strings.Join(tags, "|"), tt.want)
+		})
+	}
+}
+
+func TestTagRX(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want bool
+	}{
+		{tag: "go", want: true},
+		{tag: "k8s", want: true},
+		{tag: "c++", want: true},
+		{tag: "c#", want: true},
+		{tag: "node.js", want: true},
+		{tag: "x-ray", want: true},
+		{tag: strings.Repeat("a", 32), want: true},
+		{tag: strings.Repeat("a", 33), want: false},
+		{tag: "Go", want: false},
+		{tag: ".hidden", want: false},
+		{tag: "-flag", want: false},
+		{tag: "a/b", want: false},
+		{tag: "snake_case", want: false},
+		{tag: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			// When ... we check the tag
+			// Then ... it should only match if it's a valid tag
+			assert.Equal(t, Matches(tt.tag, TagRX), tt.want)
+		})
+	}
+}
+
+func TestMaxItemsAndAllMatch(t *testing.T) {
+	tags := []string{"go", "sql", "k8s"}
+
+	assert.Equal(t, MaxItems(tags, 3), true)
+	assert.Equal(t, MaxItems(tags, 2), false)
+	assert.Equal(t, AllMatch(tags, TagRX), true)
+	assert.Equal(t, AllMatch(append(tags, "Bad Tag"), TagRX), false)
+}
+
+func TestFileNameRX(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: "main.go", want: true},
+		{name: "Dockerfile", want: true},
+		{name: ".env", want: true},
+		{name: "my notes.md", want: true},
+		{name: "..", want: false},
+		{name: ".", want: false},
+		{name: "../etc/passwd", want: false},
+		{name: `dir\file.txt`, want: false},
+		{name: "line\nbreak", want: false},
+		{name: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When ... we check the file name
+			// Then ... it should only match if it's safe to use in a zip
+			assert.Equal(t, Matches(tt.name, FileNameRX), tt.want)
+		})
+	}
+}
@@ -5,4 +5,94 @@ for (var i = 0; i < navLinks.length; i++) {
 		link.classList.add("live");
 		break;
 	}
-}
\ No newline at end of file
+}
+
+// Replace the expiry time of a snippet with a live countdown. The exact time
+// is kept in the title so that it can still be seen by hovering over it.
+var countdowns = document.querySelectorAll("time[data-countdown]");
+
+function formatRemaining(ms) {
+	var seconds = Math.floor(ms / 1000);
+	var parts = [];
+	var units = [["d", 86400], ["h", 3600], ["m", 60], ["s", 1]];
+	for (var i = 0; i < units.length; i++) {
+		var n = Math.floor(seconds / units[i][1]);
+		seconds -= n * units[i][1];
+		if (n > 0 || parts.length > 0) {
+			parts.push(n + units[i][0]);
+		}
+	}
+	return parts.slice(0, 3).join(" ");
+}
+
+function updateCountdowns() {
+	for (var i = 0; i < countdowns.length; i++) {
+		var el = countdowns[i];
+		var remaining = Date.parse(el.getAttribute("datetime")) - Date.now();
+		if (remaining > 0) {
+			el.textContent = "Expires in " + formatRemaining(remaining);
+		} else {
+			el.textContent = "Expired";
+		}
+	}
+}
+
+for (var i = 0; i < countdowns.length; i++) {
+	countdowns[i].title = countdowns[i].textContent;
+}
+
+if (countdowns.length > 0) {
+	updateCountdowns();
+	setInterval(updateCountdowns, 1000);
+}
+
+// Highlight the lines picked out by a fragment such as #L10 or #L10-L20.
+// Lines in the second and later files of a snippet have IDs like F2L10.
+// Single lines are also highlighted by CSS using :target, but ranges need
+// this. Shift-clicking a line number extends the selection into a range.
+var lineRangeRX = /^#((?:F\d+)?L)(\d+)(?:-(?:F\d+)?L(\d+))?$/;
+
+function selectLines() {
+	var selected = document.querySelectorAll(".chroma .line.selected");
+	for (var i = 0; i < selected.length; i++) {
+		selected[i].classList.remove("selected");
+	}
+
+	var match = lineRangeRX.exec(window.location.hash);
+	if (!match) {
+		return;
+	}
+	var prefix = match[1];
+	var from = parseInt(match[2], 10);
+	var to = match[3] ? parseInt(match[3], 10) : from;
+	if (to < from) {
+		var tmp = from;
+		from = to;
+		to = tmp;
+	}
+
+	for (var n = from; n <= to; n++) {
+		var ln = document.getElementById(prefix + n);
+		if (ln) {
+			ln.parentNode.classList.add("selected");
+		}
+	}
+
+	var first = document.getElementById(prefix + from);
+	if (first && match[3]) {
+		first.scrollIntoView();
+	}
+}
+
+document.addEventListener("click", function(e) {
+	var link = e.target.closest ? e.target.closest(".chroma .ln a") : null;
+	var current = lineRangeRX.exec(window.location.hash);
+	if (!link || !e.shiftKey || !current) {
+		return;
+	}
+	e.preventDefault();
+	window.location.hash = "#" + current[1] + current[2] + "-" + link.getAttribute("href").substring(1);
+});
+
+window.addEventListener("hashchange", selectLines);
+selectLines();